@@ -15,7 +15,7 @@ func main() {
 	if len(os.Args) < 4 {
 		fmt.Println("Usage: go run create_admin.go <email> <password> <name> [role]")
 		fmt.Println("Example: go run create_admin.go admin@example.com password123 Admin User")
-		fmt.Println("Role can be: admin, super_admin (default: admin)")
+		fmt.Println("Role can be any role with admin.access, e.g. admin or super_admin (default: admin)")
 		os.Exit(1)
 	}
 
@@ -27,12 +27,6 @@ func main() {
 		role = os.Args[4]
 	}
 
-	// Validate role
-	if role != "admin" && role != "super_admin" {
-		fmt.Printf("Invalid role: %s. Must be 'admin' or 'super_admin'\n", role)
-		os.Exit(1)
-	}
-
 	// Load configuration
 	cfg := config.Load()
 
@@ -50,10 +44,24 @@ func main() {
 	}
 
 	// Auto-migrate schema
-	err = gormDB.AutoMigrate(&models.User{})
+	err = gormDB.AutoMigrate(&models.User{}, &models.Role{})
 	if err != nil {
 		log.Fatalf("Failed to migrate schema: %v", err)
 	}
+	if err := db.SeedRoles(gormDB); err != nil {
+		log.Fatalf("Failed to seed roles: %v", err)
+	}
+
+	// Validate role
+	var adminRole models.Role
+	if err := gormDB.Where("name = ?", role).First(&adminRole).Error; err != nil {
+		fmt.Printf("Invalid role: %s. Role does not exist\n", role)
+		os.Exit(1)
+	}
+	if !adminRole.HasPermission(models.PermAdminAccess) {
+		fmt.Printf("Invalid role: %s. Role does not grant %s\n", role, models.PermAdminAccess)
+		os.Exit(1)
+	}
 
 	// Check if user already exists
 	var existingUser models.User
@@ -89,4 +97,5 @@ func main() {
 	fmt.Printf("Role: %s\n", role)
 	fmt.Printf("ID: %d\n", adminUser.ID)
 	fmt.Printf("\nYou can now login with these credentials.\n")
+	fmt.Printf("Enable two-factor authentication for this account from the profile page after the first login.\n")
 }
@@ -0,0 +1,59 @@
+// Command fake_oidc runs the stand-in OpenID Connect provider from
+// internal/oidc/oidctest so single sign-on can be tried locally.
+//
+//	go run ./cmd/fake_oidc -addr :9099 -users alice@acme.test,bob@acme.test
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/frontinsight/backend/internal/config"
+	"github.com/frontinsight/backend/internal/oidc/oidctest"
+)
+
+func main() {
+	addr := flag.String("addr", ":9099", "listen address")
+	issuer := flag.String("issuer", "http://localhost:9099", "issuer URL the provider is reachable at")
+	clientID := flag.String("client-id", "front-insight", "OAuth client ID")
+	clientSecret := flag.String("client-secret", "front-insight-secret", "OAuth client secret")
+	users := flag.String("users", "alice@acme.test", "comma-separated emails that can sign in")
+	flag.Parse()
+
+	provider, err := oidctest.NewProvider(*issuer, *clientID, *clientSecret)
+	if err != nil {
+		log.Fatalf("Failed to create provider: %v", err)
+	}
+
+	domains := map[string]bool{}
+	for _, email := range strings.Split(*users, ",") {
+		email = strings.TrimSpace(email)
+		if email == "" {
+			continue
+		}
+		name := strings.SplitN(email, "@", 2)[0]
+		provider.AddUser(oidctest.User{Email: email, Name: name, EmailVerified: true})
+		if at := strings.LastIndex(email, "@"); at >= 0 {
+			domains[strings.ToLower(email[at+1:])] = true
+		}
+	}
+
+	providerConfig := config.OIDCProvider{
+		Name:         "fake",
+		Issuer:       *issuer,
+		ClientID:     *clientID,
+		ClientSecret: *clientSecret,
+	}
+	for domain := range domains {
+		providerConfig.Domains = append(providerConfig.Domains, domain)
+	}
+	example, _ := json.Marshal([]config.OIDCProvider{providerConfig})
+
+	fmt.Printf("Stand-in OIDC provider listening on %s (issuer %s)\n", *addr, *issuer)
+	fmt.Printf("Start the server with:\n  OIDC_PROVIDERS='%s'\n", example)
+	log.Fatal(http.ListenAndServe(*addr, provider.Handler()))
+}
@@ -0,0 +1,39 @@
+// Command fake_ql2 runs the stand-in QL2 submit API from internal/ql2/ql2test
+// so searches can be submitted locally, and reports started jobs as finished
+// to the backend's job status webhook.
+//
+//	go run ./cmd/fake_ql2 -addr :9098 -webhook http://localhost:5001/webhooks/ql2-job-status
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/frontinsight/backend/internal/config"
+	"github.com/frontinsight/backend/internal/ql2/ql2test"
+)
+
+func main() {
+	cfg := config.Load()
+	addr := flag.String("addr", ":9098", "listen address")
+	username := flag.String("username", cfg.QL2Username, "accepted QL2 username")
+	password := flag.String("password", cfg.QL2Password, "accepted QL2 password")
+	webhook := flag.String("webhook", "", "job status webhook of the backend; empty to never finish jobs")
+	webhookKey := flag.String("webhook-key", cfg.QL2WebhookAPIKey, "API key sent to the webhook")
+	completeAfter := flag.Duration("complete-after", 10*time.Second, "how long started jobs take")
+	finalStatus := flag.Int("final-status", 3, "status reported for finished jobs: 3 Completed, 4 Error occured, 5 Aborted")
+	flag.Parse()
+
+	server := ql2test.New(*username, *password)
+	server.WebhookURL = *webhook
+	server.WebhookAPIKey = *webhookKey
+	server.CompleteAfter = *completeAfter
+	server.FinalStatus = *finalStatus
+
+	fmt.Printf("Stand-in QL2 listening on %s\n", *addr)
+	fmt.Printf("Start the server with:\n  QL2_BASE_URL=http://localhost%s\n", *addr)
+	log.Fatal(http.ListenAndServe(*addr, server.Handler()))
+}
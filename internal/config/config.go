@@ -1,8 +1,10 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -16,6 +18,22 @@ type AppConfig struct {
 	SMTPUser string
 	SMTPPass string
 
+	// How email is delivered: smtp, log (print to stdout) or file (write .eml
+	// files to MailDir)
+	MailDriver string
+	MailDir    string
+	MailFrom   string
+
+	// Public URL of the web frontend, used to build links in emails
+	FrontendURL string
+
+	// Users are notified when a charge takes their wallet below this balance
+	LowBalanceThreshold float64
+
+	// Let webhook endpoints use plain http and private addresses. Only for
+	// local development: it lets customers reach our internal network.
+	WebhookAllowInsecure bool
+
 	RedisURL string
 
 	RunDBHost string
@@ -35,6 +53,8 @@ type AppConfig struct {
 	SFTPUser string
 	SFTPPass string
 
+	// QL2 submit API. QL2BaseURL can point at cmd/fake_ql2 for local development.
+	QL2BaseURL  string
 	QL2Username string
 	QL2Password string
 	QL2StartJob string
@@ -45,7 +65,23 @@ type AppConfig struct {
 	QL2WebhookAPIKey string
 
 	JWTSecret string
-	JWTExpiry time.Duration
+	// Token signing algorithm: RS256 or EdDSA with rotating keys published at
+	// /.well-known/jwks.json, or HS256 with JWTSecret alone
+	JWTAlgorithm string
+	// How long a signing key is used before it is replaced
+	JWTKeyRotation time.Duration
+	// Lifetime of stateless access tokens and of opaque refresh tokens
+	AccessTokenExpiry  time.Duration
+	RefreshTokenExpiry time.Duration
+
+	// OpenID Connect single sign-on providers, chosen by the user's email domain
+	OIDCProviders []OIDCProvider
+	// Callback URL registered with every OIDC provider
+	OIDCRedirectURL string
+
+	// Reverse proxies (IPs or CIDR ranges) whose X-Forwarded-For header is
+	// trusted. Empty means clients connect directly.
+	TrustedProxies []string
 
 	// Development settings
 	DevMode bool
@@ -57,6 +93,19 @@ type AppConfig struct {
 	ApplicationName string
 }
 
+// OIDCProvider configures single sign-on with one identity provider for the
+// email domains it lists. OIDC_PROVIDERS holds a JSON array of these, e.g.
+//
+//	[{"name":"acme","issuer":"https://login.acme.com","client_id":"...","client_secret":"...","domains":["acme.com"]}]
+type OIDCProvider struct {
+	Name         string   `json:"name"`
+	Issuer       string   `json:"issuer"`
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret"`
+	Domains      []string `json:"domains"`
+	Scopes       []string `json:"scopes"` // defaults to openid, email, profile
+}
+
 func Load() AppConfig {
 	cfg := AppConfig{}
 	cfg.Port = getenv("PORT", "5001")
@@ -66,6 +115,18 @@ func Load() AppConfig {
 	cfg.SMTPPort = getenvInt("SMTP_PORT", 25)
 	cfg.SMTPUser = getenv("SMTP_USER", "hariom_yadav@ql2.com")
 	cfg.SMTPPass = getenv("SMTP_PASS", "ql2_smtp_pass")
+	cfg.MailDriver = getenv("MAIL_DRIVER", "smtp")
+	switch cfg.MailDriver {
+	case "smtp", "log", "file":
+	default:
+		fmt.Printf("ERROR: Unsupported MAIL_DRIVER %q, using smtp\n", cfg.MailDriver)
+		cfg.MailDriver = "smtp"
+	}
+	cfg.MailDir = getenv("MAIL_DIR", "mail")
+	cfg.MailFrom = getenv("MAIL_FROM", cfg.SMTPUser)
+
+	cfg.FrontendURL = strings.TrimRight(getenv("FRONTEND_URL", "http://localhost:3000"), "/")
+	cfg.LowBalanceThreshold = float64(getenvInt("LOW_BALANCE_THRESHOLD", 100))
 
 	cfg.RedisURL = getenv("REDIS_URL", "redis://localhost:6379/0")
 
@@ -86,6 +147,7 @@ func Load() AppConfig {
 	cfg.SFTPUser = getenv("SFTP_USER", "y_dream")
 	cfg.SFTPPass = getenv("SFTP_PASS", "y!dre@ml$0707")
 
+	cfg.QL2BaseURL = strings.TrimRight(getenv("QL2_BASE_URL", "https://client.ql2.com"), "/")
 	cfg.QL2Username = getenv("QL2_USERNAME", "y_dream")
 	cfg.QL2Password = getenv("QL2_PASSWORD", "Ql2india@009")
 	cfg.QL2StartJob = getenv("QL2_START_JOB", "y")
@@ -94,9 +156,33 @@ func Load() AppConfig {
 	cfg.QL2Password_1 = getenv("QL2_PASSWORD_1", "Hariom@2524")
 
 	cfg.QL2WebhookAPIKey = getenv("QL2_WEBHOOK_API_KEY", "ql2-webhook-api-key-change-in-production")
+	cfg.WebhookAllowInsecure = getenv("WEBHOOK_ALLOW_INSECURE", "false") == "true"
 
 	cfg.JWTSecret = getenv("JWT_SECRET", "your-super-secret-jwt-key-change-this-in-production")
-	cfg.JWTExpiry = time.Duration(getenvInt("JWT_EXPIRY_HOURS", 24)) * time.Hour
+	cfg.JWTAlgorithm = getenv("JWT_ALGORITHM", "RS256")
+	switch cfg.JWTAlgorithm {
+	case "RS256", "EdDSA", "HS256":
+	default:
+		fmt.Printf("ERROR: Unsupported JWT_ALGORITHM %q, using RS256\n", cfg.JWTAlgorithm)
+		cfg.JWTAlgorithm = "RS256"
+	}
+	cfg.JWTKeyRotation = time.Duration(getenvInt("JWT_KEY_ROTATION_DAYS", 30)) * 24 * time.Hour
+	cfg.AccessTokenExpiry = time.Duration(getenvInt("ACCESS_TOKEN_EXPIRY_MINUTES", 15)) * time.Minute
+	cfg.RefreshTokenExpiry = time.Duration(getenvInt("REFRESH_TOKEN_EXPIRY_DAYS", 30)) * 24 * time.Hour
+
+	if raw := os.Getenv("OIDC_PROVIDERS"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &cfg.OIDCProviders); err != nil {
+			fmt.Printf("ERROR: Invalid OIDC_PROVIDERS, single sign-on disabled: %v\n", err)
+			cfg.OIDCProviders = nil
+		}
+	}
+	cfg.OIDCRedirectURL = getenv("OIDC_REDIRECT_URL", "http://localhost:5001/auth/oidc/callback")
+
+	for _, p := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			cfg.TrustedProxies = append(cfg.TrustedProxies, p)
+		}
+	}
 
 	cfg.DevMode = getenv("DEV_MODE", "true") == "false"
 
@@ -0,0 +1,30 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/frontinsight/backend/internal/models"
+)
+
+// SeedRoles creates missing built-in roles. Roles edited by an admin are
+// kept, except that super_admin is always given every permission.
+func SeedRoles(gdb *gorm.DB) error {
+	for _, role := range models.DefaultRoles() {
+		var existing models.Role
+		err := gdb.Where("name = ?", role.Name).Attrs(role).FirstOrCreate(&existing).Error
+		if err != nil {
+			return err
+		}
+		if role.Name == models.RoleSuperAdmin {
+			if err := gdb.Model(&existing).Updates(map[string]any{
+				"permissions": role.Permissions,
+				"updated_at":  time.Now().UTC(),
+			}).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
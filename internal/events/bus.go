@@ -0,0 +1,111 @@
+// Package events is an in-process publish/subscribe bus for live updates to
+// users: search status transitions, schedule runs and wallet balances. The
+// SSE endpoint subscribes a browser tab to its user's events.
+//
+// Events only reach subscribers connected to the instance that published
+// them. Clients treat them as hints and reload state from the API when they
+// reconnect.
+package events
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Event types
+const (
+	SearchStatus  = "search.status"  // a search moved to a new status
+	ScheduleRun   = "schedule.run"   // a scheduled run started or finished
+	WalletBalance = "wallet.balance" // a wallet balance or frozen amount changed
+)
+
+// subscriberBuffer is how many events a slow subscriber may fall behind
+// before it is dropped
+const subscriberBuffer = 64
+
+// Event is one update for a user
+type Event struct {
+	ID   uint64    `json:"id"`
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	Data any       `json:"data"`
+}
+
+// Subscription receives the events of one user until it is closed
+type Subscription struct {
+	C <-chan Event
+
+	ch     chan Event
+	bus    *Bus
+	user   string
+	closed bool // guarded by bus.mu
+}
+
+// Close stops the subscription. C is closed once no more events are sent.
+func (sub *Subscription) Close() {
+	sub.bus.remove(sub)
+}
+
+// Bus delivers published events to the subscriptions of the same user.
+// Users are keyed by email address, the owner key of searches and wallets.
+type Bus struct {
+	mu     sync.Mutex
+	subs   map[string]map[*Subscription]struct{}
+	nextID atomic.Uint64
+}
+
+// NewBus creates an empty bus
+func NewBus() *Bus {
+	return &Bus{subs: make(map[string]map[*Subscription]struct{})}
+}
+
+// Subscribe starts receiving the user's events
+func (b *Bus) Subscribe(user string) *Subscription {
+	ch := make(chan Event, subscriberBuffer)
+	sub := &Subscription{C: ch, ch: ch, bus: b, user: user}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subs[user] == nil {
+		b.subs[user] = make(map[*Subscription]struct{})
+	}
+	b.subs[user][sub] = struct{}{}
+	return sub
+}
+
+// Publish sends an event to every subscription of the user without
+// blocking. A subscriber whose buffer is full is dropped; its client
+// reconnects and reloads. A nil bus discards the event.
+func (b *Bus) Publish(user, eventType string, data any) {
+	if b == nil || user == "" {
+		return
+	}
+	event := Event{ID: b.nextID.Add(1), Type: eventType, Time: time.Now().UTC(), Data: data}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for sub := range b.subs[user] {
+		select {
+		case sub.ch <- event:
+		default:
+			b.removeLocked(sub)
+		}
+	}
+}
+
+func (b *Bus) remove(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.removeLocked(sub)
+}
+
+func (b *Bus) removeLocked(sub *Subscription) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	close(sub.ch)
+	delete(b.subs[sub.user], sub)
+	if len(b.subs[sub.user]) == 0 {
+		delete(b.subs, sub.user)
+	}
+}
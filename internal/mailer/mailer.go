@@ -0,0 +1,51 @@
+// Package mailer renders and delivers the emails the product sends.
+//
+// Every email goes through one path: handlers render a template and add the
+// message to the email_outbox table with an Outbox, and the outbox worker
+// hands it to a Mailer, retrying with backoff while delivery fails. A Mailer
+// is the transport: SMTP in production, or a log or file sink in development.
+package mailer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/frontinsight/backend/internal/config"
+)
+
+// Message is one rendered email
+type Message struct {
+	To      string
+	Subject string
+	HTML    string
+	Text    string // plain-text alternative, may be empty
+}
+
+// Mailer delivers a message
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New returns the mailer selected by MAIL_DRIVER: smtp, log or file
+func New(cfg config.AppConfig) Mailer {
+	switch cfg.MailDriver {
+	case "log":
+		return LogMailer{}
+	case "file":
+		return FileMailer{Dir: cfg.MailDir, From: cfg.MailFrom}
+	default:
+		return &SMTPMailer{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			User:     cfg.SMTPUser,
+			Pass:     cfg.SMTPPass,
+			From:     cfg.MailFrom,
+			Insecure: cfg.DevMode,
+		}
+	}
+}
+
+// describe is a short description of a message for logs and errors
+func describe(msg Message) string {
+	return fmt.Sprintf("%q to %s", msg.Subject, msg.To)
+}
@@ -0,0 +1,125 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/frontinsight/backend/internal/models"
+	"github.com/frontinsight/backend/internal/outbox"
+)
+
+const (
+	// outboxSendTimeout bounds one delivery attempt
+	outboxSendTimeout = 30 * time.Second
+	// outboxMaxAttempts spreads retries over roughly an hour before giving up
+	outboxMaxAttempts = 8
+)
+
+var outboxConfig = outbox.Config{
+	Name:             "queued emails",
+	PendingStatus:    models.EmailStatusPending,
+	FinishedStatuses: []string{models.EmailStatusSent, models.EmailStatusFailed},
+	PollInterval:     15 * time.Second,
+	BatchSize:        20,
+	Lease:            2 * time.Minute,
+	Retention:        30 * 24 * time.Hour,
+}
+
+// Outbox queues emails in the email_outbox table and delivers them in the
+// background with an outbox.Worker
+type Outbox struct {
+	*outbox.Worker[models.EmailOutbox]
+	db        *gorm.DB
+	mailer    Mailer
+	templates *Templates
+}
+
+// NewOutbox creates the outbox; Start runs its worker
+func NewOutbox(db *gorm.DB, mailer Mailer, templates *Templates) *Outbox {
+	o := &Outbox{
+		db:        db,
+		mailer:    mailer,
+		templates: templates,
+	}
+	o.Worker = outbox.New(db, outboxConfig, o.deliver)
+	return o
+}
+
+// Enqueue renders the template and queues the email. Pass a transaction as
+// db to queue the email only if the transaction commits.
+func (o *Outbox) Enqueue(db *gorm.DB, to, template string, data any) error {
+	msg, err := o.templates.Render(to, template, data)
+	if err != nil {
+		return err
+	}
+	row := models.EmailOutbox{
+		Recipient:     msg.To,
+		Template:      template,
+		Subject:       msg.Subject,
+		HTMLBody:      msg.HTML,
+		TextBody:      msg.Text,
+		Status:        models.EmailStatusPending,
+		NextAttemptAt: time.Now().UTC(),
+	}
+	if err := db.Create(&row).Error; err != nil {
+		return fmt.Errorf("queue email: %w", err)
+	}
+
+	// Send right away instead of waiting for the next poll
+	o.Wake()
+	return nil
+}
+
+// deliver sends one claimed email and records the outcome
+func (o *Outbox) deliver(row *models.EmailOutbox) {
+	ctx, cancel := context.WithTimeout(context.Background(), outboxSendTimeout)
+	defer cancel()
+	msg := Message{To: row.Recipient, Subject: row.Subject, HTML: row.HTMLBody, Text: row.TextBody}
+	sendErr := o.mailer.Send(ctx, msg)
+
+	now := time.Now().UTC()
+	updates := map[string]any{}
+	switch {
+	case sendErr == nil:
+		updates["status"] = models.EmailStatusSent
+		updates["sent_at"] = now
+		updates["last_error"] = nil
+		updates["html_body"] = ""
+		updates["text_body"] = ""
+	case row.Attempts >= outboxMaxAttempts:
+		fmt.Printf("ERROR: Giving up on email %d %s after %d attempts: %v\n", row.ID, describe(msg), row.Attempts, sendErr)
+		updates["status"] = models.EmailStatusFailed
+		updates["last_error"] = sendErr.Error()
+		updates["html_body"] = ""
+		updates["text_body"] = ""
+	default:
+		fmt.Printf("ERROR: Failed to send email %d %s (attempt %d): %v\n", row.ID, describe(msg), row.Attempts, sendErr)
+		updates["next_attempt_at"] = now.Add(retryBackoff(row.Attempts))
+		updates["last_error"] = sendErr.Error()
+	}
+	if err := o.db.Model(&models.EmailOutbox{}).Where("id = ?", row.ID).Updates(updates).Error; err != nil {
+		fmt.Printf("ERROR: Failed to record delivery of email %d: %v\n", row.ID, err)
+	}
+}
+
+// Cleanup clears the bodies of finished emails that still hold them, then
+// removes finished emails past the retention period. Bodies carry reset links
+// and sign-in codes; deliver clears them when an email finishes, and this
+// catches rows where recording that failed. updated_at is left alone so the
+// retention period still counts from when the email finished.
+func (o *Outbox) Cleanup() {
+	if err := o.db.Model(&models.EmailOutbox{}).
+		Where("status IN ? AND (html_body <> '' OR text_body <> '')", outboxConfig.FinishedStatuses).
+		UpdateColumns(map[string]any{"html_body": "", "text_body": ""}).Error; err != nil {
+		fmt.Printf("ERROR: Failed to clear bodies of finished emails: %v\n", err)
+	}
+	o.Worker.Cleanup()
+}
+
+// retryBackoff doubles the wait after each failed attempt: 30s, 1m, 2m, ... up to an hour
+func retryBackoff(attempts int) time.Duration {
+	return outbox.Backoff(attempts, 30*time.Second, time.Hour)
+}
@@ -0,0 +1,105 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/frontinsight/backend/internal/models"
+)
+
+type mailerFunc func(context.Context, Message) error
+
+func (f mailerFunc) Send(ctx context.Context, msg Message) error {
+	return f(ctx, msg)
+}
+
+// sqlRecorder collects the statements gorm logs
+type sqlRecorder struct {
+	mu  sync.Mutex
+	sql []string
+}
+
+func (r *sqlRecorder) Printf(format string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sql = append(r.sql, fmt.Sprintf(format, args...))
+}
+
+func (r *sqlRecorder) all() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.sql...)
+}
+
+// dryRunOutbox builds an outbox whose statements are recorded instead of run
+func dryRunOutbox(t *testing.T, send mailerFunc) (*Outbox, *sqlRecorder) {
+	t.Helper()
+	rec := &sqlRecorder{}
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=127.0.0.1 port=1 sslmode=disable"}), &gorm.Config{
+		DryRun:                 true,
+		SkipDefaultTransaction: true,
+		DisableAutomaticPing:   true,
+		Logger:                 logger.New(rec, logger.Config{LogLevel: logger.Info}),
+	})
+	if err != nil {
+		t.Fatalf("open dry-run database: %v", err)
+	}
+	return NewOutbox(db, send, nil), rec
+}
+
+func TestDeliverClearsBodiesOfFinishedEmails(t *testing.T) {
+	tests := []struct {
+		name       string
+		attempts   int
+		sendErr    error
+		wantStatus string
+		wantClear  bool
+	}{
+		{"sent", 1, nil, models.EmailStatusSent, true},
+		{"retried", 1, errors.New("connection refused"), "", false},
+		{"given up", outboxMaxAttempts, errors.New("connection refused"), models.EmailStatusFailed, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, rec := dryRunOutbox(t, func(context.Context, Message) error { return tt.sendErr })
+			o.deliver(&models.EmailOutbox{ID: 7, Recipient: "ana@example.com", Subject: "Reset your password", Attempts: tt.attempts})
+
+			sql := strings.Join(rec.all(), "\n")
+			if cleared := strings.Contains(sql, `"html_body"=''`) && strings.Contains(sql, `"text_body"=''`); cleared != tt.wantClear {
+				t.Errorf("bodies cleared = %v, want %v:\n\t%s", cleared, tt.wantClear, sql)
+			}
+			if tt.wantStatus != "" && !strings.Contains(sql, `"status"='`+tt.wantStatus+`'`) {
+				t.Errorf("update\n\t%s\nwant status %s", sql, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestCleanupClearsBodiesBeforeRemovingRows(t *testing.T) {
+	o, rec := dryRunOutbox(t, nil)
+	o.Cleanup()
+
+	sql := rec.all()
+	if len(sql) != 2 {
+		t.Fatalf("ran %d statements, want 2:\n\t%s", len(sql), strings.Join(sql, "\n\t"))
+	}
+	for i, want := range []string{
+		`UPDATE "email_outbox" SET "html_body"='',"text_body"='' WHERE`,
+		`DELETE FROM "email_outbox" WHERE status IN ('sent','failed') AND updated_at < `,
+	} {
+		if !strings.Contains(sql[i], want) {
+			t.Errorf("statement %d\n\t%s\nwant it to contain\n\t%s", i+1, sql[i], want)
+		}
+	}
+	if want := `WHERE status IN ('sent','failed') AND (html_body <> '' OR text_body <> '')`; !strings.Contains(sql[0], want) {
+		t.Errorf("clear statement\n\t%s\nwant it to contain\n\t%s", sql[0], want)
+	}
+}
@@ -0,0 +1,50 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LogMailer prints messages to standard output instead of sending them, for
+// development without an SMTP server
+type LogMailer struct{}
+
+// Send prints the message with its plain-text body
+func (LogMailer) Send(_ context.Context, msg Message) error {
+	body := msg.Text
+	if body == "" {
+		body = msg.HTML
+	}
+	fmt.Printf("MAIL: %s\n%s\n", describe(msg), body)
+	return nil
+}
+
+// FileMailer writes every message as an .eml file into Dir, where it can be
+// opened with a mail client
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+// Send writes the message to a new file
+func (m FileMailer) Send(_ context.Context, msg Message) error {
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create mail directory: %w", err)
+	}
+	data, err := buildMIME(m.From, msg)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(m.Dir, time.Now().UTC().Format("20060102T150405")+"-*.eml")
+	if err != nil {
+		return fmt.Errorf("failed to create mail file: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(data); err != nil {
+		return fmt.Errorf("failed to write mail file %s: %w", filepath.Base(f.Name()), err)
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// smtpDialTimeout bounds connecting to the SMTP server when the context has no deadline
+const smtpDialTimeout = 10 * time.Second
+
+// SMTPMailer sends mail through an SMTP server. The port picks the connection:
+// 465 is implicit TLS, 587 requires STARTTLS, and 25 is plain SMTP without
+// authentication.
+type SMTPMailer struct {
+	Host     string
+	Port     int
+	User     string
+	Pass     string
+	From     string
+	Insecure bool // skip certificate verification, for development servers
+}
+
+// Send delivers the message in one SMTP session
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	addr := net.JoinHostPort(m.Host, fmt.Sprintf("%d", m.Port))
+	useTLS := m.Port == 465
+	useSTARTTLS := m.Port == 587
+	usePlainSMTP := m.Port == 25
+	tlsConfig := &tls.Config{ServerName: m.Host, InsecureSkipVerify: m.Insecure}
+
+	dialer := &net.Dialer{Timeout: smtpDialTimeout}
+	var conn net.Conn
+	var err error
+	if useTLS {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, m.Host)
+	if err != nil {
+		return fmt.Errorf("failed to create SMTP client: %w", err)
+	}
+	defer client.Close()
+
+	if useSTARTTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("STARTTLS not supported on port 587 (required for authentication)")
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+
+	// Port 25 relays typically accept mail without authentication
+	if !usePlainSMTP && m.User != "" && m.Pass != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.User, m.Pass, m.Host)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(m.From); err != nil {
+		return fmt.Errorf("failed to set sender: %w", err)
+	}
+	if err := client.Rcpt(msg.To); err != nil {
+		return fmt.Errorf("failed to set recipient: %w", err)
+	}
+
+	data, err := buildMIME(m.From, msg)
+	if err != nil {
+		return err
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to open data writer: %w", err)
+	}
+	if _, err := writer.Write(data); err != nil {
+		writer.Close()
+		return fmt.Errorf("failed to write email data: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to close data writer: %w", err)
+	}
+
+	if err := client.Quit(); err != nil {
+		return fmt.Errorf("failed to quit SMTP session: %w", err)
+	}
+	return nil
+}
+
+// buildMIME renders the message as RFC 5322 text: multipart/alternative when
+// there is a plain-text part, otherwise a single HTML part
+func buildMIME(from string, msg Message) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	header := func(key, value string) {
+		fmt.Fprintf(buf, "%s: %s\r\n", key, value)
+	}
+	header("MIME-Version", "1.0")
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", messageID(from))
+	header("Subject", mime.QEncoding.Encode("UTF-8", msg.Subject))
+	header("From", from)
+	header("To", msg.To)
+
+	if msg.Text == "" {
+		header("Content-Type", `text/html; charset="UTF-8"`)
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		if err := writeQuotedPrintable(buf, msg.HTML); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	mw := multipart.NewWriter(buf)
+	header("Content-Type", `multipart/alternative; boundary="`+mw.Boundary()+`"`)
+	buf.WriteString("\r\n")
+	parts := []struct{ contentType, body string }{
+		{`text/plain; charset="UTF-8"`, msg.Text},
+		{`text/html; charset="UTF-8"`, msg.HTML},
+	}
+	for _, p := range parts {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(w, p.body); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeQuotedPrintable(w interface{ Write([]byte) (int, error) }, body string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// messageID returns a unique Message-ID in the sender's domain
+func messageID(from string) string {
+	domain := "localhost"
+	if at := strings.LastIndex(from, "@"); at >= 0 && at < len(from)-1 {
+		domain = from[at+1:]
+	}
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(b), domain)
+}
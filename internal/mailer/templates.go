@@ -0,0 +1,83 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"strings"
+	texttemplate "text/template"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// Templates renders the emails in templates/. Each email is a pair of files:
+// <name>.txt holds the subject in a "subject" block followed by the plain-text
+// body, and <name>.html defines the "content" block that layout.html wraps.
+// HTML bodies are escaped by html/template, so data can be passed in as is.
+type Templates struct {
+	html map[string]*htmltemplate.Template
+	text map[string]*texttemplate.Template
+}
+
+// LoadTemplates parses every embedded email template
+func LoadTemplates() (*Templates, error) {
+	layout, err := htmltemplate.ParseFS(templateFS, "templates/layout.html")
+	if err != nil {
+		return nil, err
+	}
+	names, err := fs.Glob(templateFS, "templates/*.txt")
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Templates{
+		html: make(map[string]*htmltemplate.Template, len(names)),
+		text: make(map[string]*texttemplate.Template, len(names)),
+	}
+	for _, file := range names {
+		name := strings.TrimSuffix(path.Base(file), ".txt")
+		text, err := texttemplate.New(name).ParseFS(templateFS, file)
+		if err != nil {
+			return nil, err
+		}
+		if text.Lookup("subject") == nil {
+			return nil, fmt.Errorf("email template %s has no subject", name)
+		}
+		html, err := htmltemplate.Must(layout.Clone()).ParseFS(templateFS, "templates/"+name+".html")
+		if err != nil {
+			return nil, err
+		}
+		t.text[name] = text
+		t.html[name] = html
+	}
+	return t, nil
+}
+
+// Render builds the message for the named template
+func (t *Templates) Render(to, name string, data any) (Message, error) {
+	text, ok := t.text[name]
+	if !ok {
+		return Message{}, fmt.Errorf("unknown email template %q", name)
+	}
+
+	var subject, body, html bytes.Buffer
+	if err := text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, fmt.Errorf("render %s subject: %w", name, err)
+	}
+	if err := text.ExecuteTemplate(&body, name+".txt", data); err != nil {
+		return Message{}, fmt.Errorf("render %s text: %w", name, err)
+	}
+	if err := t.html[name].ExecuteTemplate(&html, "layout", data); err != nil {
+		return Message{}, fmt.Errorf("render %s html: %w", name, err)
+	}
+	return Message{
+		To:      to,
+		Subject: strings.TrimSpace(subject.String()),
+		HTML:    html.String(),
+		Text:    strings.TrimSpace(body.String()) + "\n",
+	}, nil
+}
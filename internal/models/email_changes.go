@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// EmailChange is a pending change of a user's email address. It takes effect
+// once the code sent to the new address is confirmed; only the code's
+// SHA-256 hash is stored.
+type EmailChange struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	NewEmail  string    `gorm:"not null" json:"new_email"`
+	CodeHash  string    `gorm:"size:64;not null" json:"-"`
+	Attempts  int       `gorm:"not null;default:0" json:"-"` // wrong codes entered
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	ExpiresAt time.Time `gorm:"not null" json:"expires_at"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+)
+
+// Email outbox statuses
+const (
+	EmailStatusPending = "pending" // waiting for its first or next attempt
+	EmailStatusSent    = "sent"
+	EmailStatusFailed  = "failed" // gave up after the last retry
+)
+
+// EmailOutbox is an email waiting to be delivered, or the record of one that
+// was. Handlers only insert rows; a background worker sends them and retries
+// with backoff. Bodies are cleared once sent or given up on because they may
+// carry one-time codes and links.
+type EmailOutbox struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	Recipient     string     `gorm:"not null;index" json:"recipient"`
+	Template      string     `gorm:"size:50;not null" json:"template"`
+	Subject       string     `gorm:"not null" json:"subject"`
+	HTMLBody      string     `gorm:"type:text" json:"-"`
+	TextBody      string     `gorm:"type:text" json:"-"`
+	Status        string     `gorm:"size:20;not null;default:pending;index" json:"status"`
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt time.Time  `gorm:"not null;index" json:"next_attempt_at"`
+	LastError     *string    `gorm:"type:text" json:"last_error"`
+	SentAt        *time.Time `json:"sent_at"`
+	CreatedAt     time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+func (EmailOutbox) TableName() string {
+	return "email_outbox"
+}
@@ -4,12 +4,22 @@ import (
 	"time"
 )
 
+// Attempt types recorded in the login_attempts table
+const (
+	AttemptTypeLogin                = "login"
+	AttemptTypePasswordResetRequest = "password_reset_request"
+	AttemptTypePasswordResetConfirm = "password_reset_confirm"
+	AttemptTypeTwoFactor            = "two_factor"
+	AttemptTypeEmailChange          = "email_change"
+)
+
 type LoginAttempt struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	Email     string    `gorm:"not null;index" json:"email"`
-	IPAddress string    `gorm:"not null;index" json:"ip_address"`
-	Success   bool      `gorm:"not null" json:"success"`
-	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Email       string    `gorm:"not null;index" json:"email"`
+	IPAddress   string    `gorm:"not null;index" json:"ip_address"`
+	AttemptType string    `gorm:"not null;default:'login';index" json:"attempt_type"`
+	Success     bool      `gorm:"not null" json:"success"`
+	CreatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
 }
 
 // These functions will be moved to the server package
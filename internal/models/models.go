@@ -12,7 +12,7 @@ type User struct {
 	Name         string     `gorm:"not null" json:"name"`
 	Password     string     `gorm:"not null" json:"-"`
 	IsVerified   bool       `gorm:"default:false" json:"is_verified"`
-	Role         string     `gorm:"default:'user'" json:"role"` // user, admin, super_admin
+	Role         string     `gorm:"default:'user'" json:"role"` // name of a Role: user, admin, super_admin or a custom role
 	City         *string    `json:"city"`
 	State        *string    `json:"state"`
 	Country      *string    `json:"country"`
@@ -22,7 +22,13 @@ type User struct {
 	BusinessType *string    `gorm:"column:business_type" json:"business_type"`
 	Company      *string    `json:"company"`
 	LastLoginAt  *time.Time `gorm:"column:last_login_at" json:"last_login_at"`
-	SessionToken *string    `gorm:"column:session_token" json:"-"`
+	TOTPSecret   *string    `gorm:"column:totp_secret" json:"-"`
+	TOTPEnabled  bool       `gorm:"column:totp_enabled;default:false" json:"totp_enabled"`
+	TOTPLastStep int64      `gorm:"column:totp_last_step;default:0" json:"-"` // last accepted TOTP time step, blocks code replay
+	FailedLoginCount int    `gorm:"column:failed_login_count;default:0;not null" json:"failed_login_count"` // wrong passwords since the last successful login
+	LockedUntil  *time.Time `gorm:"column:locked_until" json:"locked_until"`                                 // login is refused until then
+	DeletionScheduledAt *time.Time `gorm:"column:deletion_scheduled_at;index" json:"deletion_scheduled_at"` // the account is anonymized after this
+	AnonymizedAt *time.Time `gorm:"column:anonymized_at" json:"anonymized_at"`                              // personal data was removed
 	Balance      float64    `gorm:"type:decimal(10,2);default:0.00;not null" json:"balance"`
 	FrozenAmount float64    `gorm:"type:decimal(10,2);default:0.00;not null;column:frozen_amount" json:"frozen_amount"`
 	CreatedAt    time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
@@ -37,6 +43,141 @@ type EmailVerification struct {
 	IsUsed    bool      `gorm:"default:false" json:"is_used"`
 }
 
+// PasswordReset stores a single-use password reset token. Only the SHA-256
+// hash of the token is persisted; the raw token is emailed to the user.
+type PasswordReset struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Email     string    `gorm:"not null;index" json:"email"`
+	TokenHash string    `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	IPAddress string    `gorm:"column:ip_address" json:"ip_address"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	ExpiresAt time.Time `gorm:"not null" json:"expires_at"`
+	IsUsed    bool      `gorm:"default:false" json:"is_used"`
+}
+
+// Session is one signed-in device. A session is created on login and kept
+// alive by rotating its refresh tokens; revoking it signs that device out.
+type Session struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"not null;index" json:"user_id"`
+	Device     string     `json:"device"` // e.g. "Chrome on Windows", derived from the user agent
+	IPAddress  string     `gorm:"column:ip_address" json:"ip_address"`
+	UserAgent  string     `gorm:"column:user_agent" json:"user_agent"`
+	CreatedAt  time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	LastSeenAt time.Time  `gorm:"column:last_seen_at;not null" json:"last_seen_at"`
+	ExpiresAt  time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt  *time.Time `gorm:"column:revoked_at" json:"revoked_at"`
+}
+
+// RefreshToken is an opaque, single-use refresh token belonging to a session.
+// Presenting an already used token revokes the whole session.
+type RefreshToken struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	SessionID uint       `gorm:"not null;index" json:"session_id"`
+	TokenHash string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `gorm:"column:used_at" json:"used_at"`
+	RevokedAt *time.Time `gorm:"column:revoked_at" json:"revoked_at"`
+	CreatedAt time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// RecoveryCode is a single-use two-factor recovery code. Only the SHA-256
+// hash is stored; the codes are shown to the user once when generated.
+type RecoveryCode struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	CodeHash  string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	UsedAt    *time.Time `gorm:"column:used_at" json:"used_at"`
+	CreatedAt time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// OIDCLoginState holds the state, nonce and PKCE verifier of a single sign-on
+// login between the redirect to the identity provider and its callback
+type OIDCLoginState struct {
+	ID           uint   `gorm:"primaryKey" json:"id"`
+	StateHash    string `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	Provider     string `gorm:"not null" json:"provider"`
+	Nonce        string `gorm:"not null" json:"-"`
+	CodeVerifier string `gorm:"not null" json:"-"`
+	// LinkUserID is the signed-in user who asked to link the identity to
+	// their account; nil for a sign-in
+	LinkUserID *uint     `gorm:"index" json:"link_user_id,omitempty"`
+	CreatedAt  time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	ExpiresAt  time.Time `gorm:"not null" json:"expires_at"`
+}
+
+// OIDCIdentity links a user to an account at a single sign-on provider
+type OIDCIdentity struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	UserID      uint       `gorm:"not null;index" json:"user_id"`
+	Provider    string     `gorm:"not null;uniqueIndex:idx_oidc_provider_subject" json:"provider"`
+	Subject     string     `gorm:"not null;uniqueIndex:idx_oidc_provider_subject" json:"subject"`
+	Email       string     `gorm:"not null" json:"email"`
+	CreatedAt   time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	LastLoginAt *time.Time `gorm:"column:last_login_at" json:"last_login_at"`
+}
+
+// API key scopes
+const (
+	APIKeyScopeRead    = "read"    // list and download collections, searches and reports
+	APIKeyScopeSubmit  = "submit"  // create, change and submit collections and schedules
+	APIKeyScopeBilling = "billing" // wallet balance and payments
+)
+
+// APIKey is a user-managed credential for scripts. The raw key is shown once
+// on creation; only its SHA-256 hash and a short display prefix are stored.
+type APIKey struct {
+	ID         uint           `gorm:"primaryKey" json:"id"`
+	UserID     uint           `gorm:"not null;index" json:"user_id"`
+	Name       string         `gorm:"not null" json:"name"`
+	Prefix     string         `gorm:"size:16;not null;index" json:"prefix"`
+	KeyHash    string         `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	Scopes     pq.StringArray `gorm:"type:text[];not null" json:"scopes"`
+	ExpiresAt  *time.Time     `gorm:"column:expires_at" json:"expires_at"`
+	LastUsedAt *time.Time     `gorm:"column:last_used_at" json:"last_used_at"`
+	LastUsedIP *string        `gorm:"column:last_used_ip" json:"last_used_ip"`
+	RevokedAt  *time.Time     `gorm:"column:revoked_at" json:"revoked_at"`
+	CreatedAt  time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// HasScope reports whether the key grants the scope
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Organization roles
+const (
+	OrgRoleOwner   = "owner"   // everything a manager can do, plus managing members
+	OrgRoleManager = "manager" // runs searches and schedules and tops up the wallet
+	OrgRoleViewer  = "viewer"  // read-only access to collections, searches and the wallet
+)
+
+// Organization groups users that share a wallet, collections, searches and schedules
+type Organization struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	Name         string    `gorm:"not null" json:"name"`
+	Balance      float64   `gorm:"type:decimal(10,2);default:0.00;not null" json:"balance"`
+	FrozenAmount float64   `gorm:"type:decimal(10,2);default:0.00;not null;column:frozen_amount" json:"frozen_amount"`
+	CreatedBy    uint      `gorm:"not null" json:"created_by"`
+	CreatedAt    time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt    time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// OrganizationMember is a user's membership and role in an organization
+type OrganizationMember struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	OrganizationID uint      `gorm:"not null;uniqueIndex:idx_org_member" json:"organization_id"`
+	UserID         uint      `gorm:"not null;uniqueIndex:idx_org_member;index" json:"user_id"`
+	Role           string    `gorm:"not null;default:'viewer'" json:"role"` // owner, manager, viewer
+	CreatedAt      time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
 type Search struct {
 	ID            uint         `gorm:"primaryKey" json:"id"`
 	UserID        string       `gorm:"not null;index" json:"user_id"`
@@ -50,6 +191,7 @@ type Search struct {
 	ScheduledAt   *time.Time   `gorm:"column:scheduled_at" json:"scheduled_at"`
 	Amount        float64      `gorm:"type:decimal(10,2);default:0.00;not null" json:"amount"`
 	FrozenAmount  float64      `gorm:"type:decimal(10,2);default:0.00;not null;column:frozen_amount" json:"frozen_amount"`
+	OrganizationID *uint       `gorm:"column:organization_id;index" json:"organization_id"` // set when charged to an organization wallet
 	CreatedAt     time.Time    `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
 	Items         []SearchItem `gorm:"foreignKey:SearchID" json:"search_items,omitempty"`
 }
@@ -61,6 +203,7 @@ type Collection struct {
 	Description     *string          `json:"description"`
 	Status          string           `gorm:"default:'saved'" json:"status"`
 	LastRunAt       *time.Time       `gorm:"column:last_run_at" json:"last_run_at"`
+	OrganizationID  *uint            `gorm:"column:organization_id;index" json:"organization_id"` // shared with the organization's members when set
 	CreatedAt       time.Time        `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
 	UpdatedAt       time.Time        `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
 	CollectionItems []CollectionItem `gorm:"foreignKey:CollectionID" json:"collection_items,omitempty"`
@@ -105,6 +248,7 @@ type Transaction struct {
 	ID            uint      `gorm:"primaryKey" json:"id"`
 	UserID        string    `gorm:"not null;index" json:"user_id"`
 	SearchID      *uint     `json:"search_id"`
+	OrganizationID *uint    `gorm:"column:organization_id;index" json:"organization_id"` // organization wallet the transaction applies to
 	TxnType       string    `gorm:"not null;index" json:"txn_type"` // debit, credit, refund, freeze, unfreeze
 	Amount        float64   `gorm:"type:decimal(10,2);not null" json:"amount"`
 	BalanceBefore float64   `gorm:"type:decimal(10,2);not null" json:"balance_before"`
@@ -117,7 +261,7 @@ type Transaction struct {
 	UpdatedAt     time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
 
 	// Foreign key relationships
-	User   User    `gorm:"foreignKey:UserID;references:Email" json:"user,omitempty"`
+	User   User    `gorm:"foreignKey:UserID;references:Email;constraint:OnUpdate:CASCADE" json:"user,omitempty"`
 	Search *Search `gorm:"foreignKey:SearchID" json:"search,omitempty"`
 }
 
@@ -175,6 +319,7 @@ type Schedule struct {
 	UpdatedAt    time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
 	CollectionID *uint      `json:"collection_id"` // Reference to collection if scheduled from collection
 	SearchID     *uint      `json:"search_id"`     // Reference to search if scheduled from search
+	OrganizationID *uint    `gorm:"column:organization_id;index" json:"organization_id"` // Inherited from the collection or search
 }
 
 type ScheduleRun struct {
@@ -249,6 +394,14 @@ type AdminActivity struct {
 	CreatedAt  time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
 }
 
+// SystemSetting is a runtime setting changed by admins with settings.write
+type SystemSetting struct {
+	Key       string    `gorm:"primaryKey;size:100" json:"key"`
+	Value     string    `gorm:"not null" json:"value"`
+	UpdatedBy uint      `json:"updated_by"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
 type SystemStats struct {
 	ID               uint      `gorm:"primaryKey" json:"id"`
 	TotalUsers       int       `json:"total_users"`
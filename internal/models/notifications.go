@@ -0,0 +1,50 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Notification events. Each can be delivered in the app, by email, or both,
+// as the user's NotificationPreference for the event says.
+const (
+	NotificationSearchCompleted = "search.completed"
+	NotificationSearchFailed    = "search.failed"
+	NotificationSearchAborted   = "search.aborted"
+	NotificationScheduleFailed  = "schedule.failed"
+	NotificationLowBalance      = "wallet.low_balance"
+	NotificationRefundIssued    = "wallet.refund"
+)
+
+// NotificationEvents lists every event users can set preferences for
+var NotificationEvents = []string{
+	NotificationSearchCompleted,
+	NotificationSearchFailed,
+	NotificationSearchAborted,
+	NotificationScheduleFailed,
+	NotificationLowBalance,
+	NotificationRefundIssued,
+}
+
+// Notification is an in-app message for a user
+type Notification struct {
+	ID        uint            `gorm:"primaryKey" json:"id"`
+	UserID    uint            `gorm:"not null;index:idx_notifications_user_read,priority:1" json:"user_id"`
+	Event     string          `gorm:"size:50;not null" json:"event"`
+	Title     string          `gorm:"not null" json:"title"`
+	Body      string          `gorm:"type:text" json:"body"`
+	Link      *string         `json:"link"` // page in the web app
+	Data      json.RawMessage `gorm:"type:jsonb;not null;default:'{}'" json:"data"`
+	ReadAt    *time.Time      `gorm:"index:idx_notifications_user_read,priority:2" json:"read_at"`
+	CreatedAt time.Time       `gorm:"default:CURRENT_TIMESTAMP;index" json:"created_at"`
+}
+
+// NotificationPreference is a user's choice of channels for one event. Without
+// a row, in-app notifications are on and email is off.
+type NotificationPreference struct {
+	UserID    uint      `gorm:"primaryKey;autoIncrement:false" json:"-"`
+	Event     string    `gorm:"primaryKey;size:50" json:"event"`
+	InApp     bool      `gorm:"not null" json:"in_app"`
+	Email     bool      `gorm:"not null" json:"email"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
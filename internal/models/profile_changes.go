@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// ProfileChange records one edit of a user's profile field, by the user or
+// by an admin
+type ProfileChange struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	Field     string    `gorm:"size:50;not null" json:"field"`
+	OldValue  *string   `json:"old_value"`
+	NewValue  *string   `json:"new_value"`
+	ChangedBy uint      `gorm:"not null" json:"changed_by"` // user ID of whoever made the change
+	IPAddress string    `gorm:"column:ip_address" json:"ip_address"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP;index" json:"created_at"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+)
+
+// QL2 submission statuses
+const (
+	QL2SubmissionPending   = "pending" // waiting for its first or next attempt
+	QL2SubmissionSubmitted = "submitted"
+	QL2SubmissionFailed    = "failed" // gave up; the search was failed and refunded
+)
+
+// QL2Submission is a search's job waiting to be submitted to QL2, or the
+// record of one that was. It is created in the same transaction as the search
+// and the freeze of its amount, and submitted by a background worker, so a
+// QL2 outage cannot leave a search with money frozen and no job behind it.
+type QL2Submission struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	SearchID      uint       `gorm:"not null;uniqueIndex" json:"search_id"`
+	JobName       string     `gorm:"not null" json:"job_name"`
+	CSV           string     `gorm:"column:csv;type:text;not null" json:"-"`
+	Start         bool       `gorm:"not null" json:"start"`
+	Schedule      string     `gorm:"not null;default:''" json:"schedule"`
+	Status        string     `gorm:"size:20;not null;default:pending;index" json:"status"`
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt time.Time  `gorm:"not null;index" json:"next_attempt_at"`
+	LastError     *string    `gorm:"type:text" json:"last_error"`
+	SubmittedAt   *time.Time `json:"submitted_at"`
+	CreatedAt     time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+func (QL2Submission) TableName() string {
+	return "ql2_submissions"
+}
@@ -0,0 +1,99 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Permissions checked by the admin API. Roles group them; User.Role names a role.
+const (
+	PermAdminAccess      = "admin.access"      // use the admin API at all
+	PermUsersRead        = "users.read"        // list and view users
+	PermUsersWrite       = "users.write"       // edit users and revoke their sessions
+	PermUsersRoles       = "users.roles"       // change a user's role
+	PermUsersImpersonate = "users.impersonate" // act as a customer for support
+	PermSearchesRead     = "searches.read"     // view every user's searches and collections
+	PermSchedulesManage  = "schedules.manage"  // view every user's schedules
+	PermActivitiesRead   = "activities.read"   // read the admin audit log
+	PermBillingAdjust    = "billing.adjust"    // adjust wallet balances
+	PermSettingsRead     = "settings.read"     // view system settings
+	PermSettingsWrite    = "settings.write"    // change system settings
+	PermRolesManage      = "roles.manage"      // create, edit and delete roles
+)
+
+// AllPermissions lists every known permission
+var AllPermissions = []string{
+	PermAdminAccess,
+	PermUsersRead,
+	PermUsersWrite,
+	PermUsersRoles,
+	PermUsersImpersonate,
+	PermSearchesRead,
+	PermSchedulesManage,
+	PermActivitiesRead,
+	PermBillingAdjust,
+	PermSettingsRead,
+	PermSettingsWrite,
+	PermRolesManage,
+}
+
+// Built-in role names
+const (
+	RoleUser       = "user"
+	RoleAdmin      = "admin"
+	RoleSuperAdmin = "super_admin"
+)
+
+// Role is a named set of permissions
+type Role struct {
+	Name        string         `gorm:"primaryKey;size:50" json:"name"`
+	Description string         `json:"description"`
+	Permissions pq.StringArray `gorm:"type:text[];not null" json:"permissions"`
+	System      bool           `gorm:"not null;default:false" json:"system"` // built-in roles cannot be deleted
+	CreatedAt   time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt   time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// HasPermission reports whether the role grants the permission
+func (r *Role) HasPermission(permission string) bool {
+	for _, p := range r.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultRoles are created on start-up when missing. super_admin always
+// holds every permission.
+func DefaultRoles() []Role {
+	return []Role{
+		{
+			Name:        RoleUser,
+			Description: "Customer account without admin access",
+			Permissions: pq.StringArray{},
+			System:      true,
+		},
+		{
+			Name:        RoleAdmin,
+			Description: "Support staff: manage users and view searches, schedules and the audit log",
+			Permissions: pq.StringArray{
+				PermAdminAccess,
+				PermUsersRead,
+				PermUsersWrite,
+				PermSearchesRead,
+				PermSchedulesManage,
+				PermActivitiesRead,
+				PermSettingsRead,
+			},
+			System: true,
+		},
+		{
+			Name:        RoleSuperAdmin,
+			Description: "Full access, including roles and system settings",
+			Permissions: pq.StringArray(AllPermissions),
+			System:      true,
+		},
+	}
+}
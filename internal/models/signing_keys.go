@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// SigningKey is an asymmetric key for signing access and MFA tokens. The
+// newest unretired key signs; retired keys keep verifying until ExpiresAt,
+// after the last token they signed has expired.
+type SigningKey struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	KID        string     `gorm:"column:kid;size:64;not null;uniqueIndex" json:"kid"`
+	Algorithm  string     `gorm:"size:16;not null" json:"algorithm"` // RS256 or EdDSA
+	PrivateKey string     `gorm:"type:text;not null" json:"-"`       // PKCS #8 PEM
+	PublicKey  string     `gorm:"type:text;not null" json:"public_key"`
+	CreatedAt  time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	RetiredAt  *time.Time `json:"retired_at"`              // stopped signing
+	ExpiresAt  *time.Time `gorm:"index" json:"expires_at"` // stops verifying
+}
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Webhook events customers can subscribe their endpoints to. They share the
+// names of the matching notification events.
+const (
+	WebhookSearchCompleted = NotificationSearchCompleted
+	WebhookSearchFailed    = NotificationSearchFailed
+	WebhookSearchAborted   = NotificationSearchAborted
+	WebhookScheduleFailed  = NotificationScheduleFailed
+	// WebhookPing is sent by the test endpoint to every endpoint regardless
+	// of its event filter
+	WebhookPing = "ping"
+)
+
+// WebhookEvents lists the events an endpoint can subscribe to
+var WebhookEvents = []string{
+	WebhookSearchCompleted,
+	WebhookSearchFailed,
+	WebhookSearchAborted,
+	WebhookScheduleFailed,
+}
+
+// Webhook delivery statuses
+const (
+	WebhookStatusPending   = "pending" // waiting for its first or next attempt
+	WebhookStatusDelivered = "delivered"
+	WebhookStatusFailed    = "failed" // gave up after the last retry
+)
+
+// WebhookEndpoint is a customer URL that receives signed event payloads. It
+// belongs to the user who registered it, or to an organization when
+// OrganizationID is set; UserID is then the member who registered it.
+type WebhookEndpoint struct {
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	UserID         uint           `gorm:"not null;index" json:"user_id"`
+	OrganizationID *uint          `gorm:"column:organization_id;index" json:"organization_id"`
+	URL            string         `gorm:"not null" json:"url"`
+	Description    string         `gorm:"not null;default:''" json:"description"`
+	Secret         string         `gorm:"not null" json:"-"` // signs payloads; shown once when created or rotated
+	Events         pq.StringArray `gorm:"type:text[];not null" json:"events"`
+	Active         bool           `gorm:"not null" json:"active"`
+	CreatedAt      time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+}
+
+// Subscribes reports whether the endpoint wants the event
+func (e *WebhookEndpoint) Subscribes(event string) bool {
+	if event == WebhookPing {
+		return true
+	}
+	for _, ev := range e.Events {
+		if ev == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is one event sent, or waiting to be sent, to an endpoint,
+// with the outcome of its latest attempt. A redelivery is a new row with the
+// same EventID and payload, so receivers can deduplicate on the event ID.
+type WebhookDelivery struct {
+	ID             uint            `gorm:"primaryKey" json:"id"`
+	EndpointID     uint            `gorm:"not null;index" json:"endpoint_id"`
+	EventID        string          `gorm:"size:40;not null;index" json:"event_id"`
+	Event          string          `gorm:"size:50;not null" json:"event"`
+	Payload        json.RawMessage `gorm:"type:jsonb;not null" json:"payload"`
+	Status         string          `gorm:"size:20;not null;default:pending;index" json:"status"`
+	Attempts       int             `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt  time.Time       `gorm:"not null;index" json:"next_attempt_at"`
+	ResponseStatus *int            `json:"response_status"`
+	ResponseBody   *string         `gorm:"type:text" json:"response_body"` // first few KB
+	DurationMs     *int64          `json:"duration_ms"`
+	LastError      *string         `gorm:"type:text" json:"last_error"`
+	DeliveredAt    *time.Time      `json:"delivered_at"`
+	CreatedAt      time.Time       `gorm:"default:CURRENT_TIMESTAMP;index" json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
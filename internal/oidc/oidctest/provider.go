@@ -0,0 +1,301 @@
+// Package oidctest is a minimal stand-in OpenID Connect provider for local
+// development and end-to-end checks of single sign-on. It supports discovery,
+// the authorization code flow with PKCE (S256), RS256 ID tokens and JWKS.
+// Sign-in is automatic: the user is picked by the login_hint parameter or
+// entered in a bare HTML form.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/frontinsight/backend/internal/oidc"
+)
+
+// User is an account known to the stand-in provider
+type User struct {
+	Subject       string
+	Email         string
+	Name          string
+	EmailVerified bool
+}
+
+type authCode struct {
+	user          User
+	redirectURI   string
+	nonce         string
+	codeChallenge string
+	expiresAt     time.Time
+}
+
+// Provider is the stand-in identity provider. Issuer must be set to the URL
+// the provider is served at before it handles requests.
+type Provider struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// TokenTTL is the ID token lifetime (default 5 minutes)
+	TokenTTL time.Duration
+	// EditClaims, when set, changes ID token claims before they are signed,
+	// to check that clients reject bad tokens
+	EditClaims func(claims *oidc.IDTokenClaims)
+
+	key *rsa.PrivateKey
+	kid string
+
+	mu    sync.Mutex
+	users map[string]User // by lower-cased email
+	codes map[string]authCode
+}
+
+// NewProvider creates a provider with a fresh RSA signing key
+func NewProvider(issuer, clientID, clientSecret string) (*Provider, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	kid, err := oidc.RandomString(8)
+	if err != nil {
+		return nil, err
+	}
+	return &Provider{
+		Issuer:       strings.TrimRight(issuer, "/"),
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		TokenTTL:     5 * time.Minute,
+		key:          key,
+		kid:          kid,
+		users:        make(map[string]User),
+		codes:        make(map[string]authCode),
+	}, nil
+}
+
+// NewServer starts a provider on a local httptest server. Call Close on the
+// returned server when done.
+func NewServer(clientID, clientSecret string) (*Provider, *httptest.Server, error) {
+	p, err := NewProvider("", clientID, clientSecret)
+	if err != nil {
+		return nil, nil, err
+	}
+	srv := httptest.NewServer(p.Handler())
+	p.Issuer = srv.URL
+	return p, srv, nil
+}
+
+// AddUser registers a user that can sign in. A missing subject is derived
+// from the email.
+func (p *Provider) AddUser(u User) {
+	if u.Subject == "" {
+		sum := sha256.Sum256([]byte(strings.ToLower(u.Email)))
+		u.Subject = base64.RawURLEncoding.EncodeToString(sum[:12])
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.users[strings.ToLower(u.Email)] = u
+}
+
+// Handler serves the provider endpoints
+func (p *Provider) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.handleDiscovery)
+	mux.HandleFunc("/jwks", p.handleJWKS)
+	mux.HandleFunc("/authorize", p.handleAuthorize)
+	mux.HandleFunc("/token", p.handleToken)
+	return mux
+}
+
+func (p *Provider) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                p.Issuer,
+		"authorization_endpoint":                p.Issuer + "/authorize",
+		"token_endpoint":                        p.Issuer + "/token",
+		"jwks_uri":                              p.Issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"scopes_supported":                      []string{"openid", "email", "profile"},
+	})
+}
+
+func (p *Provider) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, oidc.JSONWebKeySet{Keys: []oidc.JSONWebKey{oidc.RSAPublicJWK(p.kid, &p.key.PublicKey)}})
+}
+
+func (p *Provider) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	q := r.Form
+	if q.Get("client_id") != p.ClientID || q.Get("response_type") != "code" || q.Get("redirect_uri") == "" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+	if q.Get("code_challenge") == "" || q.Get("code_challenge_method") != "S256" {
+		http.Error(w, "PKCE with S256 is required", http.StatusBadRequest)
+		return
+	}
+
+	email := strings.ToLower(strings.TrimSpace(q.Get("email")))
+	if email == "" {
+		email = strings.ToLower(strings.TrimSpace(q.Get("login_hint")))
+	}
+	p.mu.Lock()
+	user, ok := p.users[email]
+	p.mu.Unlock()
+	if !ok {
+		p.renderLoginForm(w, q, email)
+		return
+	}
+
+	code, err := oidc.RandomString(16)
+	if err != nil {
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	p.mu.Lock()
+	p.codes[code] = authCode{
+		user:          user,
+		redirectURI:   q.Get("redirect_uri"),
+		nonce:         q.Get("nonce"),
+		codeChallenge: q.Get("code_challenge"),
+		expiresAt:     time.Now().Add(time.Minute),
+	}
+	p.mu.Unlock()
+
+	redirect, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	params := redirect.Query()
+	params.Set("code", code)
+	params.Set("state", q.Get("state"))
+	redirect.RawQuery = params.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (p *Provider) renderLoginForm(w http.ResponseWriter, q url.Values, email string) {
+	var fields strings.Builder
+	for name, values := range q {
+		if name == "email" {
+			continue
+		}
+		for _, v := range values {
+			fmt.Fprintf(&fields, `<input type="hidden" name="%s" value="%s">`, html.EscapeString(name), html.EscapeString(v))
+		}
+	}
+	message := ""
+	if email != "" {
+		message = fmt.Sprintf("<p>Unknown user %s.</p>", html.EscapeString(email))
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, `<html><body><h2>Stand-in OIDC provider</h2>%s<form method="post" action="/authorize">%s<input name="email" placeholder="email"><button type="submit">Sign in</button></form></body></html>`, message, fields.String())
+}
+
+func (p *Provider) handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, "invalid_request", "malformed form")
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if ok {
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != p.ClientID || (p.ClientSecret != "" && subtle.ConstantTimeCompare([]byte(clientSecret), []byte(p.ClientSecret)) != 1) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="token"`)
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError(w, "unsupported_grant_type", "only authorization_code is supported")
+		return
+	}
+
+	code := r.PostForm.Get("code")
+	p.mu.Lock()
+	grant, found := p.codes[code]
+	delete(p.codes, code) // codes are single use
+	p.mu.Unlock()
+	if !found || time.Now().After(grant.expiresAt) {
+		tokenError(w, "invalid_grant", "unknown or expired code")
+		return
+	}
+	if r.PostForm.Get("redirect_uri") != grant.redirectURI {
+		tokenError(w, "invalid_grant", "redirect_uri mismatch")
+		return
+	}
+	if oidc.CodeChallengeS256(r.PostForm.Get("code_verifier")) != grant.codeChallenge {
+		tokenError(w, "invalid_grant", "PKCE verification failed")
+		return
+	}
+
+	idToken, err := p.signIDToken(grant)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	accessToken, _ := oidc.RandomString(24)
+	writeJSON(w, http.StatusOK, oidc.TokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		IDToken:     idToken,
+		ExpiresIn:   int(p.TokenTTL.Seconds()),
+	})
+}
+
+func (p *Provider) signIDToken(grant authCode) (string, error) {
+	now := time.Now()
+	claims := oidc.IDTokenClaims{
+		Email:         grant.user.Email,
+		EmailVerified: grant.user.EmailVerified,
+		Name:          grant.user.Name,
+		Nonce:         grant.nonce,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    p.Issuer,
+			Subject:   grant.user.Subject,
+			Audience:  jwt.ClaimStrings{p.ClientID},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(p.TokenTTL)),
+		},
+	}
+	if p.EditClaims != nil {
+		p.EditClaims(&claims)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = p.kid
+	return token.SignedString(p.key)
+}
+
+func tokenError(w http.ResponseWriter, code, description string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code, "error_description": description})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
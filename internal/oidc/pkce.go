@@ -0,0 +1,28 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// RandomString returns a URL-safe random string of n random bytes, used for
+// state, nonce and PKCE code verifiers
+func RandomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// NewCodeVerifier returns a PKCE code verifier (RFC 7636, 43 characters)
+func NewCodeVerifier() (string, error) {
+	return RandomString(32)
+}
+
+// CodeChallengeS256 derives the S256 code challenge sent with the authorization request
+func CodeChallengeS256(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,134 @@
+// Package outbox runs the background workers behind the tables that queue
+// work to be done after a request: emails, QL2 submissions and webhook
+// deliveries. Handlers insert rows in the same transaction as the change
+// they belong to; a Worker claims the due rows, hands each to a delivery
+// function and retries failures with backoff.
+//
+// Several instances can run the same worker: rows are claimed with SKIP
+// LOCKED and leased, so each row is attempted by one of them at a time.
+package outbox
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Config describes an outbox table and how its worker paces itself. The
+// table needs the status, attempts, next_attempt_at and updated_at columns.
+type Config struct {
+	// Name describes the rows in log messages, e.g. "queued emails"
+	Name string
+	// PendingStatus marks rows waiting for their first or next attempt
+	PendingStatus string
+	// FinishedStatuses mark rows that are no longer attempted; Cleanup
+	// removes them after Retention
+	FinishedStatuses []string
+
+	PollInterval time.Duration
+	BatchSize    int
+	// Lease is how long a claimed row is hidden from other workers. It must
+	// cover one delivery, or the row may be attempted twice.
+	Lease     time.Duration
+	Retention time.Duration
+}
+
+// Worker claims due rows of the table of T and delivers them. deliver is
+// called with the attempt already counted in the row's Attempts and must
+// record the outcome: a finished status, or a new next_attempt_at for a
+// retry. A row left as it is becomes due again when its lease expires.
+type Worker[T any] struct {
+	db      *gorm.DB
+	cfg     Config
+	deliver func(row *T)
+	wake    chan struct{}
+
+	// claim is claimDue; tests replace it to run without a database
+	claim func() ([]T, error)
+}
+
+// New creates a worker; Start runs it
+func New[T any](db *gorm.DB, cfg Config, deliver func(row *T)) *Worker[T] {
+	w := &Worker[T]{
+		db:      db,
+		cfg:     cfg,
+		deliver: deliver,
+		wake:    make(chan struct{}, 1),
+	}
+	w.claim = w.claimDue
+	return w
+}
+
+// Wake processes due rows right away instead of waiting for the next poll.
+// Call it after the transaction that queued them commits.
+func (w *Worker[T]) Wake() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start processes due rows until the process exits
+func (w *Worker[T]) Start() {
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		w.ProcessDue()
+		select {
+		case <-ticker.C:
+		case <-w.wake:
+		}
+	}
+}
+
+// ProcessDue delivers every row that is due, one batch at a time
+func (w *Worker[T]) ProcessDue() {
+	for {
+		batch, err := w.claim()
+		if err != nil {
+			fmt.Printf("ERROR: Failed to claim %s: %v\n", w.cfg.Name, err)
+			return
+		}
+		for i := range batch {
+			w.deliver(&batch[i])
+		}
+		if len(batch) < w.cfg.BatchSize {
+			return
+		}
+	}
+}
+
+// claimDue locks a batch of due rows, counts the attempt and leases them so
+// other workers skip them while they are being delivered
+func (w *Worker[T]) claimDue() ([]T, error) {
+	now := time.Now().UTC()
+	due := w.db.Model(new(T)).Select("id").
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = ? AND next_attempt_at <= ?", w.cfg.PendingStatus, now).
+		Order("id").Limit(w.cfg.BatchSize)
+	var batch []T
+	err := w.db.Model(&batch).Clauses(clause.Returning{}).Where("id IN (?)", due).Updates(map[string]any{
+		"attempts":        gorm.Expr("attempts + 1"),
+		"next_attempt_at": now.Add(w.cfg.Lease),
+	}).Error
+	return batch, err
+}
+
+// Cleanup removes finished rows past the retention period
+func (w *Worker[T]) Cleanup() {
+	cutoff := time.Now().UTC().Add(-w.cfg.Retention)
+	_ = w.db.Where("status IN ? AND updated_at < ?", w.cfg.FinishedStatuses, cutoff).
+		Delete(new(T)).Error
+}
+
+// Backoff is the wait after a row's attempts-th failed attempt: first after
+// the first failure, doubled for each next one, up to limit
+func Backoff(attempts int, first, limit time.Duration) time.Duration {
+	wait := first
+	for i := 1; i < attempts && wait < limit; i++ {
+		wait *= 2
+	}
+	return min(wait, limit)
+}
@@ -0,0 +1,235 @@
+package outbox
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testRow is a minimal outbox table
+type testRow struct {
+	ID            uint `gorm:"primaryKey"`
+	Status        string
+	Attempts      int
+	NextAttemptAt time.Time
+	UpdatedAt     time.Time
+}
+
+func (testRow) TableName() string {
+	return "outbox_test_rows"
+}
+
+var testConfig = Config{
+	Name:             "test rows",
+	PendingStatus:    "pending",
+	FinishedStatuses: []string{"done", "failed"},
+	PollInterval:     time.Hour,
+	BatchSize:        3,
+	Lease:            time.Minute,
+	Retention:        24 * time.Hour,
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, time.Minute},
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{6, 32 * time.Minute},
+		{7, time.Hour},
+		{100, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := Backoff(tt.attempts, time.Minute, time.Hour); got != tt.want {
+			t.Errorf("Backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestProcessDueDeliversUntilShortBatch(t *testing.T) {
+	var delivered []uint
+	w := New(nil, testConfig, func(row *testRow) { delivered = append(delivered, row.ID) })
+	batches := [][]testRow{{{ID: 1}, {ID: 2}, {ID: 3}}, {{ID: 4}, {ID: 5}, {ID: 6}}, {{ID: 7}}, {{ID: 8}}}
+	claims := 0
+	w.claim = func() ([]testRow, error) {
+		claims++
+		return batches[claims-1], nil
+	}
+
+	w.ProcessDue()
+	if claims != 3 {
+		t.Errorf("claimed %d batches, want 3: a short batch means nothing else is due", claims)
+	}
+	if want := []uint{1, 2, 3, 4, 5, 6, 7}; fmt.Sprint(delivered) != fmt.Sprint(want) {
+		t.Errorf("delivered %v, want %v", delivered, want)
+	}
+}
+
+func TestProcessDueStopsOnClaimError(t *testing.T) {
+	w := New(nil, testConfig, func(row *testRow) { t.Errorf("delivered row %d after a failed claim", row.ID) })
+	claims := 0
+	w.claim = func() ([]testRow, error) {
+		claims++
+		return nil, errors.New("connection refused")
+	}
+
+	w.ProcessDue()
+	if claims != 1 {
+		t.Errorf("claimed %d times, want 1", claims)
+	}
+}
+
+func TestWakeDoesNotBlock(t *testing.T) {
+	w := New(nil, testConfig, func(*testRow) {})
+	done := make(chan struct{})
+	go func() {
+		// Wakes before the worker runs collapse into one
+		for range 5 {
+			w.Wake()
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Wake blocked without a running worker")
+	}
+	if len(w.wake) != 1 {
+		t.Errorf("pending wakes = %d, want 1", len(w.wake))
+	}
+}
+
+// sqlRecorder collects the statements gorm logs
+type sqlRecorder struct {
+	mu  sync.Mutex
+	sql []string
+}
+
+func (r *sqlRecorder) Printf(format string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sql = append(r.sql, fmt.Sprintf(format, args...))
+}
+
+func (r *sqlRecorder) last() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.sql) == 0 {
+		return ""
+	}
+	return r.sql[len(r.sql)-1]
+}
+
+// dryRunDB builds statements for Postgres without connecting to one
+func dryRunDB(t *testing.T) (*gorm.DB, *sqlRecorder) {
+	t.Helper()
+	rec := &sqlRecorder{}
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=127.0.0.1 port=1 sslmode=disable"}), &gorm.Config{
+		DryRun:                 true,
+		SkipDefaultTransaction: true,
+		DisableAutomaticPing:   true,
+		Logger:                 logger.New(rec, logger.Config{LogLevel: logger.Info}),
+	})
+	if err != nil {
+		t.Fatalf("open dry-run database: %v", err)
+	}
+	return db, rec
+}
+
+func TestClaimLocksAndLeasesInOneStatement(t *testing.T) {
+	db, rec := dryRunDB(t)
+	w := New(db, testConfig, func(*testRow) {})
+	if _, err := w.claimDue(); err != nil {
+		t.Fatalf("claimDue: %v", err)
+	}
+	sql := rec.last()
+	for _, want := range []string{
+		`UPDATE "outbox_test_rows" SET "attempts"=attempts + 1,"next_attempt_at"=`,
+		`WHERE id IN (SELECT "id" FROM "outbox_test_rows" WHERE status = 'pending' AND next_attempt_at <= `,
+		`ORDER BY id LIMIT 3 FOR UPDATE SKIP LOCKED) RETURNING *`,
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("claim statement\n\t%s\nwant it to contain\n\t%s", sql, want)
+		}
+	}
+}
+
+func TestCleanupRemovesOnlyFinishedRows(t *testing.T) {
+	db, rec := dryRunDB(t)
+	New(db, testConfig, func(*testRow) {}).Cleanup()
+	sql := rec.last()
+	if want := `DELETE FROM "outbox_test_rows" WHERE status IN ('done','failed') AND updated_at < `; !strings.Contains(sql, want) {
+		t.Errorf("cleanup statement\n\t%s\nwant it to contain\n\t%s", sql, want)
+	}
+}
+
+// TestConcurrentWorkersDeliverOnce runs against the Postgres database in
+// TEST_DATABASE_URL, which it writes a scratch table to
+func TestConcurrentWorkersDeliverOnce(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	if err := db.Migrator().DropTable(&testRow{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&testRow{}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Migrator().DropTable(&testRow{}) })
+
+	const rows = 50
+	due := time.Now().UTC().Add(-time.Second)
+	for range rows {
+		if err := db.Create(&testRow{Status: "pending", NextAttemptAt: due}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var mu sync.Mutex
+	deliveries := map[uint]int{}
+	deliver := func(row *testRow) {
+		mu.Lock()
+		deliveries[row.ID]++
+		mu.Unlock()
+		if row.Attempts != 1 {
+			t.Errorf("row %d claimed with %d attempts, want 1", row.ID, row.Attempts)
+		}
+		if err := db.Model(&testRow{}).Where("id = ?", row.ID).Update("status", "done").Error; err != nil {
+			t.Error(err)
+		}
+	}
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			New(db, testConfig, deliver).ProcessDue()
+		}()
+	}
+	wg.Wait()
+
+	if len(deliveries) != rows {
+		t.Errorf("delivered %d rows, want %d", len(deliveries), rows)
+	}
+	for id, n := range deliveries {
+		if n != 1 {
+			t.Errorf("row %d delivered %d times", id, n)
+		}
+	}
+}
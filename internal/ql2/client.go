@@ -0,0 +1,234 @@
+// Package ql2 submits hotel rate shopping jobs to QL2. A job is a CSV body
+// with one row per site, location, stay and point of sale; QL2 creates or
+// replaces the job by name and reports its progress back to the
+// /webhooks/ql2-job-status endpoint.
+//
+// The submit API takes the account credentials in the query string, so they
+// are only sent over https; plain http is allowed for loopback hosts such as
+// cmd/fake_ql2. They never appear in errors returned by this package.
+package ql2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is the production QL2 endpoint
+const DefaultBaseURL = "https://client.ql2.com"
+
+const (
+	// attemptTimeout bounds one submit request
+	attemptTimeout = 30 * time.Second
+	// maxErrorMessage is how much of an error response is kept
+	maxErrorMessage = 500
+)
+
+// Submission is a job to create or replace at QL2
+type Submission struct {
+	JobName string
+	// CSV is the job body, one row per search
+	CSV string
+	// Start runs the job right away; otherwise QL2 only stores it
+	Start bool
+	// Schedule is a QL2 schedule expression; empty for a one-off job
+	Schedule string
+}
+
+// Client submits jobs to QL2
+type Client interface {
+	Submit(ctx context.Context, sub Submission) error
+}
+
+// APIError is a response from QL2 that rejected a submission
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("HTTP %d", e.StatusCode)
+	}
+	return fmt.Sprintf("HTTP %d: %s", e.StatusCode, e.Message)
+}
+
+// Temporary reports whether the same submission may succeed if retried
+func (e *APIError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// HTTPClient is the Client for the QL2 HTTP API. Submissions that fail with
+// a network error, 429 or 5xx are retried with backoff; that is safe because
+// QL2 creates or replaces jobs by name.
+type HTTPClient struct {
+	baseURL  string
+	username string
+	password string
+	http     *http.Client
+
+	// MaxAttempts is how many times a submission is tried (default 3)
+	MaxAttempts int
+	// Backoff is the wait before the first retry, doubled for each next one
+	// (default 1s)
+	Backoff time.Duration
+}
+
+// CheckBaseURL reports whether submissions to baseURL would keep the
+// credentials off the network in clear text: it must use https, or http to a
+// loopback host.
+func CheckBaseURL(baseURL string) error {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("ql2: invalid base URL %q", baseURL)
+	}
+	if u.Scheme == "https" {
+		return nil
+	}
+	host := u.Hostname()
+	if ip := net.ParseIP(host); u.Scheme == "http" && (host == "localhost" || ip != nil && ip.IsLoopback()) {
+		return nil
+	}
+	return fmt.Errorf("ql2: base URL %s must use https", u.Redacted())
+}
+
+// NewHTTPClient creates a client for the QL2 API at baseURL. Submissions
+// fail without being sent when baseURL does not pass CheckBaseURL.
+func NewHTTPClient(baseURL, username, password string) *HTTPClient {
+	return &HTTPClient{
+		baseURL:     strings.TrimRight(baseURL, "/"),
+		username:    username,
+		password:    password,
+		http:        &http.Client{Timeout: attemptTimeout},
+		MaxAttempts: 3,
+		Backoff:     time.Second,
+	}
+}
+
+// Submit sends the job, retrying transient failures until ctx is done
+func (c *HTTPClient) Submit(ctx context.Context, sub Submission) error {
+	if sub.JobName == "" || sub.CSV == "" {
+		return errors.New("ql2: submission needs a job name and at least one row")
+	}
+	if err := CheckBaseURL(c.baseURL); err != nil {
+		return err
+	}
+	var err error
+	for attempt := 1; ; attempt++ {
+		var retryAfter time.Duration
+		retryAfter, err = c.submitOnce(ctx, sub)
+		if err == nil || attempt >= c.MaxAttempts || ctx.Err() != nil || !temporary(err) {
+			break
+		}
+		wait := c.Backoff << (attempt - 1)
+		wait += rand.N(wait/2 + 1) // jitter, so retries from many submissions spread out
+		if retryAfter > wait {
+			wait = min(retryAfter, time.Minute)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("ql2: submit job %s: %w (last error: %v)", sub.JobName, ctx.Err(), err)
+		case <-time.After(wait):
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("ql2: submit job %s: %w", sub.JobName, err)
+	}
+	return nil
+}
+
+// submitOnce makes one attempt. It returns how long QL2 asked us to wait
+// when it sent Retry-After.
+func (c *HTTPClient) submitOnce(ctx context.Context, sub Submission) (time.Duration, error) {
+	q := url.Values{}
+	q.Set("username", c.username)
+	q.Set("password", c.password)
+	q.Set("app", "hotel")
+	q.Set("createorreplacejob", sub.JobName)
+	q.Set("startjob", "n")
+	if sub.Start {
+		q.Set("startjob", "y")
+	}
+	q.Set("priority", "high")
+	if sub.Schedule != "" {
+		q.Set("setschedule", sub.Schedule)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/submit?"+q.Encode(), strings.NewReader(sub.CSV))
+	if err != nil {
+		return 0, redact(err)
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, redact(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusAccepted {
+		return 0, nil
+	}
+
+	var retryAfter time.Duration
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+		retryAfter = time.Duration(secs) * time.Second
+	}
+	msg := errorMessage(body)
+	if c.password != "" {
+		msg = strings.ReplaceAll(msg, c.password, "[redacted]")
+	}
+	return retryAfter, &APIError{StatusCode: resp.StatusCode, Message: msg}
+}
+
+// errorMessage extracts the reason from an error response: the error or
+// message field of a JSON body, or the text itself
+func errorMessage(body []byte) string {
+	var parsed struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+	}
+	msg := strings.TrimSpace(string(body))
+	if json.Unmarshal(body, &parsed) == nil {
+		switch {
+		case parsed.Error != "":
+			msg = parsed.Error
+		case parsed.Message != "":
+			msg = parsed.Message
+		}
+	}
+	msg = strings.Join(strings.Fields(msg), " ")
+	if len(msg) > maxErrorMessage {
+		msg = msg[:maxErrorMessage] + "..."
+	}
+	return msg
+}
+
+// redact drops the request URL, which holds the credentials, from transport
+// errors
+func redact(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return &url.Error{Op: urlErr.Op, URL: "[redacted]", Err: urlErr.Err}
+	}
+	return err
+}
+
+// temporary reports whether err is worth retrying: rate limits, server
+// errors and network failures, including a timed out attempt
+func temporary(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Temporary()
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,208 @@
+package ql2
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/frontinsight/backend/internal/ql2/ql2test"
+)
+
+const (
+	testUsername = "tester"
+	testPassword = "s3cret-pass"
+)
+
+var testSubmission = Submission{JobName: "job-1", CSV: "EXP,Paris,,France\n", Start: true}
+
+// newTestClient starts the stand-in QL2 API behind a request counter and
+// returns a client for it that retries without waiting
+func newTestClient(t *testing.T) (*HTTPClient, *ql2test.Server, *atomic.Int32) {
+	t.Helper()
+	fake := ql2test.New(testUsername, testPassword)
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		fake.Handler().ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	client := NewHTTPClient(srv.URL, testUsername, testPassword)
+	client.Backoff = time.Millisecond
+	return client, fake, &requests
+}
+
+func TestSubmitRetriesTemporaryFailures(t *testing.T) {
+	for _, status := range []int{http.StatusInternalServerError, http.StatusServiceUnavailable, http.StatusTooManyRequests} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			client, fake, requests := newTestClient(t)
+			fake.FailNext(2, status, "try again")
+
+			if err := client.Submit(context.Background(), testSubmission); err != nil {
+				t.Fatalf("Submit: %v", err)
+			}
+			if got := requests.Load(); got != 3 {
+				t.Errorf("requests = %d, want 3", got)
+			}
+			if jobs := fake.Jobs(); len(jobs) != 1 || jobs[0].Name != "job-1" || !jobs[0].Start {
+				t.Errorf("jobs = %+v, want job-1 started", jobs)
+			}
+		})
+	}
+}
+
+func TestSubmitStopsAfterMaxAttempts(t *testing.T) {
+	client, fake, requests := newTestClient(t)
+	fake.FailNext(5, http.StatusBadGateway, "upstream down")
+
+	err := client.Submit(context.Background(), testSubmission)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadGateway {
+		t.Fatalf("Submit error = %v, want HTTP 502", err)
+	}
+	if got := requests.Load(); got != 3 {
+		t.Errorf("requests = %d, want 3", got)
+	}
+}
+
+func TestSubmitDoesNotRetryRejectedJobs(t *testing.T) {
+	for _, status := range []int{http.StatusBadRequest, http.StatusUnauthorized} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			client, fake, requests := newTestClient(t)
+			fake.FailNext(1, status, "no")
+
+			err := client.Submit(context.Background(), testSubmission)
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != status || apiErr.Message != "no" {
+				t.Fatalf("Submit error = %v, want HTTP %d: no", err, status)
+			}
+			if got := requests.Load(); got != 1 {
+				t.Errorf("requests = %d, want 1", got)
+			}
+			if jobs := fake.Jobs(); len(jobs) != 0 {
+				t.Errorf("jobs = %+v, want none", jobs)
+			}
+		})
+	}
+}
+
+func TestSubmitHonoursRetryAfter(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+	client := NewHTTPClient(srv.URL, testUsername, testPassword)
+	client.Backoff = time.Millisecond
+
+	start := time.Now()
+	if err := client.Submit(context.Background(), testSubmission); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %v, want at least the 1s Retry-After", elapsed)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("requests = %d, want 2", got)
+	}
+}
+
+func TestSubmitStopsWhenContextIsDone(t *testing.T) {
+	client, fake, requests := newTestClient(t)
+	client.Backoff = time.Hour
+	fake.FailNext(1, http.StatusServiceUnavailable, "busy")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := client.Submit(ctx, testSubmission)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Submit error = %v, want deadline exceeded", err)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("requests = %d, want 1", got)
+	}
+}
+
+func TestSubmitRedactsCredentials(t *testing.T) {
+	t.Run("error response", func(t *testing.T) {
+		client, fake, _ := newTestClient(t)
+		fake.FailNext(1, http.StatusBadRequest, "bad request for password="+testPassword)
+
+		err := client.Submit(context.Background(), testSubmission)
+		if err == nil || strings.Contains(err.Error(), testPassword) {
+			t.Fatalf("Submit error = %v, want an error without the password", err)
+		}
+		if !strings.Contains(err.Error(), "[redacted]") {
+			t.Errorf("Submit error = %v, want the password replaced", err)
+		}
+	})
+
+	t.Run("transport error", func(t *testing.T) {
+		srv := httptest.NewServer(http.NotFoundHandler())
+		srv.Close() // nothing listens any more, so every attempt fails to connect
+		client := NewHTTPClient(srv.URL, testUsername, testPassword)
+		client.Backoff = time.Millisecond
+
+		err := client.Submit(context.Background(), testSubmission)
+		if err == nil {
+			t.Fatal("Submit succeeded against a closed server")
+		}
+		if strings.Contains(err.Error(), testPassword) || strings.Contains(err.Error(), testUsername) {
+			t.Errorf("Submit error = %v, want no credentials", err)
+		}
+	})
+}
+
+func TestSubmitRefusesPlainHTTP(t *testing.T) {
+	var requests atomic.Int32
+	client := NewHTTPClient("http://client.ql2.com", testUsername, testPassword)
+	client.http.Transport = roundTripFunc(func(*http.Request) (*http.Response, error) {
+		requests.Add(1)
+		return nil, errors.New("unexpected request")
+	})
+
+	if err := client.Submit(context.Background(), testSubmission); err == nil {
+		t.Fatal("Submit over plain http succeeded")
+	}
+	if got := requests.Load(); got != 0 {
+		t.Errorf("requests = %d, want none", got)
+	}
+}
+
+func TestCheckBaseURL(t *testing.T) {
+	tests := []struct {
+		url string
+		ok  bool
+	}{
+		{DefaultBaseURL, true},
+		{"https://ql2.example.com:8443", true},
+		{"http://localhost:5002", true},
+		{"http://127.0.0.1:5002", true},
+		{"http://[::1]:5002", true},
+		{"http://client.ql2.com", false},
+		{"http://10.0.0.5", false},
+		{"ftp://client.ql2.com", false},
+		{"client.ql2.com", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if err := CheckBaseURL(tt.url); (err == nil) != tt.ok {
+			t.Errorf("CheckBaseURL(%q) = %v, want ok %v", tt.url, err, tt.ok)
+		}
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
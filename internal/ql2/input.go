@@ -0,0 +1,230 @@
+package ql2
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/frontinsight/backend/internal/models"
+)
+
+// InputColumns is the number of fields in a job row.
+//
+// A QL2 hotel job is a CSV body without a header, one row per site,
+// location, stay and point of sale. Every row has these fields:
+//
+//	 1  site script code, e.g. EXP for Expedia (see SiteCode)
+//	 2  city
+//	 3  state or region; empty unless the location has three parts
+//	 4  country
+//	 5  check-in date, YYYYMMDD
+//	 6  check-out date, YYYYMMDD
+//	 7  (empty)
+//	 8  (empty)
+//	 9  (empty)
+//	10  (empty)
+//	11  fixed "15"
+//	12  star rating, as entered by the user
+//	13  fixed "25"
+//	14  fixed "A"
+//	15  custom tag, "userId=<user>&jobname=<collection>&" with both values
+//	    query-escaped; QL2 copies it into the results
+//	16  fixed "1"
+//	17  (empty)
+//	18  (empty)
+//	19  fixed "0"
+//	20  adults
+//	21  (empty)
+//	22  (empty)
+//	23  (empty)
+//	24  point of sale; empty for the site's default
+//	25  (empty)
+//	26  (empty)
+//	27  (empty)
+//
+// The fixed values are the ones the integration has always sent. Fields are
+// quoted as RFC 4180 requires, so commas, quotes and line breaks in user
+// input cannot shift columns. Rows end in a line feed.
+const InputColumns = 27
+
+// siteCodes maps site names to QL2 script codes
+var siteCodes = map[string]string{
+	"EXPEDIA":      "EXP",
+	"PRICELINE":    "PL",
+	"MARRIOTT":     "MC",
+	"CHOICEHOTELS": "CH",
+	"BESTWESTERN":  "BW",
+	"REDROOF":      "RR",
+	"ACCORHOTELS":  "RT",
+}
+
+// SiteCode returns the QL2 script code of a site. Names without a known
+// code are passed through, as they may already be codes.
+func SiteCode(name string) string {
+	if code, ok := siteCodes[strings.ToUpper(strings.TrimSpace(name))]; ok {
+		return code
+	}
+	return strings.TrimSpace(name)
+}
+
+// InputRow is one row of a job
+type InputRow struct {
+	SiteCode string
+	City     string
+	State    string
+	Country  string
+	// CheckIn and CheckOut are YYYYMMDD
+	CheckIn  string
+	CheckOut string
+	Stars    string
+	Adults   int
+	POS      string
+	// UserID and JobLabel go into the custom tag that comes back with the
+	// results; JobLabel is the collection name
+	UserID   string
+	JobLabel string
+}
+
+// Validate checks the fields QL2 needs to run the row
+func (r InputRow) Validate() error {
+	switch {
+	case r.SiteCode == "":
+		return errors.New("site is required")
+	case r.City == "":
+		return errors.New("city is required")
+	case r.Country == "":
+		return errors.New("country is required")
+	case r.Adults < 1:
+		return errors.New("at least one adult is required")
+	}
+	checkIn, err := time.Parse("20060102", r.CheckIn)
+	if err != nil {
+		return fmt.Errorf("invalid check-in date %q", r.CheckIn)
+	}
+	checkOut, err := time.Parse("20060102", r.CheckOut)
+	if err != nil {
+		return fmt.Errorf("invalid check-out date %q", r.CheckOut)
+	}
+	if !checkOut.After(checkIn) {
+		return errors.New("check-out must be after check-in")
+	}
+	return nil
+}
+
+// Fields returns the row's InputColumns fields, unquoted
+func (r InputRow) Fields() []string {
+	tag := "userId=" + url.QueryEscape(r.UserID) + "&jobname=" + url.QueryEscape(r.JobLabel) + "&"
+	return []string{
+		r.SiteCode, r.City, r.State, r.Country, r.CheckIn, r.CheckOut,
+		"", "", "", "",
+		"15", r.Stars, "25", "A", tag, "1",
+		"", "",
+		"0", strconv.Itoa(r.Adults),
+		"", "", "",
+		r.POS,
+		"", "", "",
+	}
+}
+
+// SkippedJob is a job that produced no rows
+type SkippedJob struct {
+	// Index is the job's position in the submitted list
+	Index  int            `json:"index"`
+	Job    models.JobData `json:"job"`
+	Reason string         `json:"reason"`
+}
+
+// BuildRows expands jobs into rows: one per point of sale, or a single row
+// for the site's default when none is given. Jobs whose location, dates or
+// site cannot make a valid row are returned as skipped instead.
+func BuildRows(jobs []models.JobData, userID, jobLabel string) ([]InputRow, []SkippedJob) {
+	rows := make([]InputRow, 0, len(jobs))
+	var skipped []SkippedJob
+	for i, j := range jobs {
+		// Locations are "City, Country" or "City, State, Country"
+		parts := strings.Split(j.Location, ",")
+		for k := range parts {
+			parts[k] = strings.TrimSpace(parts[k])
+		}
+		if len(parts) < 2 {
+			skipped = append(skipped, SkippedJob{Index: i, Job: j, Reason: "location must be \"City, Country\" or \"City, State, Country\""})
+			continue
+		}
+		base := InputRow{
+			SiteCode: SiteCode(j.Website.Name),
+			City:     parts[0],
+			Country:  parts[len(parts)-1],
+			CheckIn:  strings.ReplaceAll(j.CheckInDate, "-", ""),
+			CheckOut: strings.ReplaceAll(j.CheckOutDate, "-", ""),
+			Stars:    j.StarRating,
+			Adults:   j.Adults,
+			UserID:   userID,
+			JobLabel: jobLabel,
+		}
+		if len(parts) == 3 {
+			base.State = parts[1]
+		}
+		if err := base.Validate(); err != nil {
+			skipped = append(skipped, SkippedJob{Index: i, Job: j, Reason: err.Error()})
+			continue
+		}
+		poses := j.Website.POS
+		if len(poses) == 0 {
+			poses = []string{""}
+		}
+		for _, pos := range poses {
+			row := base
+			row.POS = strings.TrimSpace(pos)
+			rows = append(rows, row)
+		}
+	}
+	return rows, skipped
+}
+
+// JobsError refuses a submission with jobs that cannot make a valid row.
+// Every job is charged, so such submissions are not sent without them.
+type JobsError struct {
+	Skipped []SkippedJob
+}
+
+func (e *JobsError) Error() string {
+	return "ql2: jobs cannot be sent: " + DescribeSkipped(e.Skipped)
+}
+
+// DescribeSkipped lists skipped jobs and their reasons, numbered from 1 as
+// the user entered them: "job 2: city is required; job 5: ..."
+func DescribeSkipped(skipped []SkippedJob) string {
+	reasons := make([]string, len(skipped))
+	for i, sk := range skipped {
+		reasons[i] = fmt.Sprintf("job %d: %s", sk.Index+1, sk.Reason)
+	}
+	return strings.Join(reasons, "; ")
+}
+
+// EncodeInput writes rows as a job body. It fails on the first invalid row,
+// so a broken row never reaches QL2.
+func EncodeInput(rows []InputRow) (string, error) {
+	if len(rows) == 0 {
+		return "", errors.New("ql2: job has no rows")
+	}
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	for i, r := range rows {
+		if err := r.Validate(); err != nil {
+			return "", fmt.Errorf("ql2: row %d: %w", i+1, err)
+		}
+		if err := w.Write(r.Fields()); err != nil {
+			return "", fmt.Errorf("ql2: row %d: %w", i+1, err)
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return "", fmt.Errorf("ql2: encode job: %w", err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,145 @@
+package ql2
+
+import (
+	"encoding/csv"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/frontinsight/backend/internal/models"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func job(site, location, checkIn, checkOut string, pos ...string) models.JobData {
+	return models.JobData{
+		Website:      models.WebsiteData{Name: site, POS: pos},
+		Location:     location,
+		CheckInDate:  checkIn,
+		CheckOutDate: checkOut,
+		Adults:       2,
+		StarRating:   "4",
+	}
+}
+
+// TestEncodeInputGolden checks job bodies byte for byte against
+// testdata/<name>.golden. Run with -update to rewrite them after an
+// intended format change.
+func TestEncodeInputGolden(t *testing.T) {
+	tests := []struct {
+		name     string
+		jobs     []models.JobData
+		userID   string
+		jobLabel string
+	}{
+		{
+			name: "basic",
+			jobs: []models.JobData{
+				job("Expedia", "Paris, France", "2025-10-01", "2025-10-03"),
+				job("marriott", "Austin, TX, USA", "2025-11-20", "2025-11-21", "US", " GB "),
+				job("HRS", "Berlin, Germany", "2025-12-01", "2025-12-05"),
+			},
+			userID:   "ana@example.com",
+			jobLabel: "October run",
+		},
+		{
+			name: "special_characters",
+			jobs: []models.JobData{
+				job("PRICELINE", `Washington, "D.C.", USA`, "2025-10-01", "2025-10-02", "US,CA"),
+				job("Expedia", "Saint-Jean-de-Luz\nBiarritz, France", "2025-10-01", "2025-10-02"),
+			},
+			userID:   "o'brien+ops@example.com",
+			jobLabel: `Q4 "Coast" & Co, v2`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, skipped := BuildRows(tt.jobs, tt.userID, tt.jobLabel)
+			if len(skipped) > 0 {
+				t.Fatalf("skipped jobs: %s", DescribeSkipped(skipped))
+			}
+			got, err := EncodeInput(rows)
+			if err != nil {
+				t.Fatalf("EncodeInput: %v", err)
+			}
+
+			path := filepath.Join("testdata", tt.name+".golden")
+			if *update {
+				if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("read golden file (run with -update to create it): %v", err)
+			}
+			if got != string(want) {
+				t.Errorf("job body differs from %s\ngot:\n%s\nwant:\n%s", path, got, want)
+			}
+
+			// However the fields are quoted, every row must read back as
+			// InputColumns fields equal to the ones built
+			records, err := csv.NewReader(strings.NewReader(got)).ReadAll()
+			if err != nil {
+				t.Fatalf("job body is not valid CSV: %v", err)
+			}
+			if len(records) != len(rows) {
+				t.Fatalf("read %d rows, want %d", len(records), len(rows))
+			}
+			for i, rec := range records {
+				if len(rec) != InputColumns {
+					t.Errorf("row %d has %d fields, want %d", i+1, len(rec), InputColumns)
+				}
+				if want := rows[i].Fields(); strings.Join(rec, "\x00") != strings.Join(want, "\x00") {
+					t.Errorf("row %d reads back as %q, want %q", i+1, rec, want)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildRowsSkipsInvalidJobs(t *testing.T) {
+	noAdults := job("Expedia", "Paris, France", "2025-10-01", "2025-10-02")
+	noAdults.Adults = 0
+	jobs := []models.JobData{
+		job("Expedia", "Paris, France", "2025-10-01", "2025-10-02"),
+		job("Expedia", "Paris", "2025-10-01", "2025-10-02"),
+		job("Expedia", "Paris, France", "2025-10-01", "2025-10-01"),
+		job("Expedia", "Paris, France", "tomorrow", "2025-10-02"),
+		job("", "Paris, France", "2025-10-01", "2025-10-02"),
+		job("Expedia", ", France", "2025-10-01", "2025-10-02"),
+		noAdults,
+	}
+	rows, skipped := BuildRows(jobs, "ana@example.com", "run")
+	if len(rows) != 1 {
+		t.Errorf("built %d rows, want 1", len(rows))
+	}
+	want := `job 2: location must be "City, Country" or "City, State, Country"; ` +
+		`job 3: check-out must be after check-in; ` +
+		`job 4: invalid check-in date "tomorrow"; ` +
+		`job 5: site is required; ` +
+		`job 6: city is required; ` +
+		`job 7: at least one adult is required`
+	if got := DescribeSkipped(skipped); got != want {
+		t.Errorf("skipped:\n\t%s\nwant:\n\t%s", got, want)
+	}
+
+	err := error(&JobsError{Skipped: skipped})
+	var jobsErr *JobsError
+	if !errors.As(err, &jobsErr) || !strings.HasSuffix(err.Error(), want) {
+		t.Errorf("JobsError = %v, want it to list the skipped jobs", err)
+	}
+}
+
+func TestEncodeInputRejectsInvalidRows(t *testing.T) {
+	if _, err := EncodeInput(nil); err == nil {
+		t.Error("EncodeInput accepted a job without rows")
+	}
+	rows := []InputRow{{SiteCode: "EXP", City: "Paris", Country: "France", CheckIn: "20251001", CheckOut: "20250930", Adults: 1}}
+	if _, err := EncodeInput(rows); err == nil || !strings.Contains(err.Error(), "row 1") {
+		t.Errorf("EncodeInput error = %v, want one naming row 1", err)
+	}
+}
@@ -0,0 +1,134 @@
+package ql2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/frontinsight/backend/internal/models"
+	"github.com/frontinsight/backend/internal/outbox"
+)
+
+const (
+	// outboxSubmitTimeout bounds one delivery, including the client's retries
+	outboxSubmitTimeout = 2 * time.Minute
+	// outboxMaxAttempts spreads retries over about an hour and a half before
+	// the search is failed
+	outboxMaxAttempts = 8
+)
+
+var outboxConfig = outbox.Config{
+	Name:             "queued QL2 submissions",
+	PendingStatus:    models.QL2SubmissionPending,
+	FinishedStatuses: []string{models.QL2SubmissionSubmitted, models.QL2SubmissionFailed},
+	PollInterval:     15 * time.Second,
+	BatchSize:        10,
+	// The lease covers the client's own retries
+	Lease:     5 * time.Minute,
+	Retention: 30 * 24 * time.Hour,
+}
+
+// Outbox queues QL2 submissions in the ql2_submissions table and submits them
+// in the background with an outbox.Worker. Submitting a job twice is
+// harmless, as QL2 replaces jobs by name.
+type Outbox struct {
+	*outbox.Worker[models.QL2Submission]
+	db     *gorm.DB
+	client Client
+
+	// OnGiveUp is called after a submission is marked failed, to fail its
+	// search and release the frozen money
+	OnGiveUp func(submission models.QL2Submission, err error)
+}
+
+// NewOutbox creates the outbox; Start runs its worker
+func NewOutbox(db *gorm.DB, client Client) *Outbox {
+	o := &Outbox{
+		db:     db,
+		client: client,
+	}
+	o.Worker = outbox.New(db, outboxConfig, o.deliver)
+	return o
+}
+
+// Enqueue queues the job of a search. Pass the transaction that creates the
+// search and freezes its amount as db, so all three commit or none does,
+// and call Wake once it has committed.
+func (o *Outbox) Enqueue(db *gorm.DB, searchID uint, sub Submission) error {
+	row := models.QL2Submission{
+		SearchID:      searchID,
+		JobName:       sub.JobName,
+		CSV:           sub.CSV,
+		Start:         sub.Start,
+		Schedule:      sub.Schedule,
+		Status:        models.QL2SubmissionPending,
+		NextAttemptAt: time.Now().UTC(),
+	}
+	if err := db.Create(&row).Error; err != nil {
+		return fmt.Errorf("queue ql2 submission: %w", err)
+	}
+	return nil
+}
+
+// deliver submits one claimed job and records the outcome
+func (o *Outbox) deliver(row *models.QL2Submission) {
+	ctx, cancel := context.WithTimeout(context.Background(), outboxSubmitTimeout)
+	defer cancel()
+	submitErr := o.client.Submit(ctx, Submission{
+		JobName:  row.JobName,
+		CSV:      row.CSV,
+		Start:    row.Start,
+		Schedule: row.Schedule,
+	})
+
+	now := time.Now().UTC()
+	updates := map[string]any{}
+	giveUp := false
+	switch {
+	case submitErr == nil:
+		updates["status"] = models.QL2SubmissionSubmitted
+		updates["submitted_at"] = now
+		updates["last_error"] = nil
+	case row.Attempts >= outboxMaxAttempts || rejected(submitErr):
+		fmt.Printf("ERROR: Giving up on QL2 submission of search %d after %d attempts: %v\n", row.SearchID, row.Attempts, submitErr)
+		updates["status"] = models.QL2SubmissionFailed
+		updates["last_error"] = submitErr.Error()
+		giveUp = true
+	default:
+		fmt.Printf("ERROR: Failed to submit search %d to QL2 (attempt %d): %v\n", row.SearchID, row.Attempts, submitErr)
+		updates["next_attempt_at"] = now.Add(outboxBackoff(row.Attempts))
+		updates["last_error"] = submitErr.Error()
+	}
+	if err := o.db.Model(&models.QL2Submission{}).Where("id = ?", row.ID).Updates(updates).Error; err != nil {
+		// The lease expires and the job is submitted again, which QL2 tolerates
+		fmt.Printf("ERROR: Failed to record QL2 submission of search %d: %v\n", row.SearchID, err)
+		return
+	}
+	if giveUp && o.OnGiveUp != nil {
+		o.OnGiveUp(*row, submitErr)
+	}
+}
+
+// rejected reports whether QL2 refused the job itself, so sending it again
+// cannot succeed. Authentication errors are retried: they are fixed in our
+// configuration, not in the job.
+func rejected(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.StatusCode {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity:
+		return true
+	}
+	return false
+}
+
+// outboxBackoff doubles the wait after each failed attempt: 1m, 2m, 4m, ... up to 30 minutes
+func outboxBackoff(attempts int) time.Duration {
+	return outbox.Backoff(attempts, time.Minute, 30*time.Minute)
+}
@@ -0,0 +1,199 @@
+// Package ql2test is a stand-in for the QL2 submit API, for local
+// development and end-to-end checks of job submission. It checks the
+// credentials and parameters like QL2 does, records every job it accepts,
+// can be told to fail, and can report jobs as finished to the backend's
+// /webhooks/ql2-job-status endpoint.
+package ql2test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Job is a submission the stand-in accepted
+type Job struct {
+	Name       string
+	CSV        string
+	Rows       int
+	Start      bool
+	Schedule   string
+	Priority   string
+	RunID      int64
+	ReceivedAt time.Time
+}
+
+type failure struct {
+	status  int
+	message string
+}
+
+// Server is the stand-in QL2 API
+type Server struct {
+	Username string
+	Password string
+
+	// WebhookURL, when set, receives a job status update for every job that
+	// is started, CompleteAfter after it was submitted
+	WebhookURL    string
+	WebhookAPIKey string
+	CompleteAfter time.Duration
+	// FinalStatus is the status reported: 3 Completed (default), 4 Error
+	// occured or 5 Aborted
+	FinalStatus int
+
+	mu        sync.Mutex
+	jobs      []Job
+	failures  []failure
+	nextRunID int64
+}
+
+// New creates a stand-in that accepts the given credentials
+func New(username, password string) *Server {
+	return &Server{
+		Username:    username,
+		Password:    password,
+		FinalStatus: 3,
+		nextRunID:   1000,
+	}
+}
+
+// NewServer starts a stand-in on a local httptest server. Call Close on the
+// returned server when done.
+func NewServer(username, password string) (*Server, *httptest.Server) {
+	s := New(username, password)
+	return s, httptest.NewServer(s.Handler())
+}
+
+// FailNext makes the next n submissions fail with the HTTP status
+func (s *Server) FailNext(n, status int, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for range n {
+		s.failures = append(s.failures, failure{status: status, message: message})
+	}
+}
+
+// Jobs returns the accepted submissions, oldest first
+func (s *Server) Jobs() []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Job(nil), s.jobs...)
+}
+
+// Handler serves the submit API
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /submit", s.handleSubmit)
+	return mux
+}
+
+func (s *Server) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("username") != s.Username || q.Get("password") != s.Password {
+		writeError(w, http.StatusUnauthorized, "invalid username or password")
+		return
+	}
+	if q.Get("app") != "hotel" {
+		writeError(w, http.StatusBadRequest, "unknown app")
+		return
+	}
+	name := q.Get("createorreplacejob")
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "createorreplacejob is required")
+		return
+	}
+	startFlag := q.Get("startjob")
+	if startFlag != "y" && startFlag != "n" {
+		writeError(w, http.StatusBadRequest, "startjob must be y or n")
+		return
+	}
+	buf := &bytes.Buffer{}
+	if _, err := buf.ReadFrom(r.Body); err != nil {
+		writeError(w, http.StatusBadRequest, "could not read job")
+		return
+	}
+	body := strings.TrimRight(buf.String(), "\r\n")
+	if body == "" {
+		writeError(w, http.StatusBadRequest, "job has no rows")
+		return
+	}
+
+	s.mu.Lock()
+	if len(s.failures) > 0 {
+		f := s.failures[0]
+		s.failures = s.failures[1:]
+		s.mu.Unlock()
+		writeError(w, f.status, f.message)
+		return
+	}
+	s.nextRunID++
+	job := Job{
+		Name:       name,
+		CSV:        buf.String(),
+		Rows:       strings.Count(body, "\n") + 1,
+		Start:      startFlag == "y",
+		Schedule:   q.Get("setschedule"),
+		Priority:   q.Get("priority"),
+		RunID:      s.nextRunID,
+		ReceivedAt: time.Now().UTC(),
+	}
+	// Replace a job of the same name, as QL2 does
+	replaced := false
+	for i := range s.jobs {
+		if s.jobs[i].Name == name {
+			s.jobs[i] = job
+			replaced = true
+		}
+	}
+	if !replaced {
+		s.jobs = append(s.jobs, job)
+	}
+	s.mu.Unlock()
+
+	if job.Start && s.WebhookURL != "" {
+		go s.complete(job)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"job": job.Name, "rows": job.Rows, "run_id": job.RunID})
+}
+
+// complete reports a started job as finished to the backend
+func (s *Server) complete(job Job) {
+	time.Sleep(s.CompleteAfter)
+	payload, _ := json.Marshal(map[string]any{
+		"job_name": job.Name,
+		"status":   s.FinalStatus,
+		"run_id":   job.RunID,
+	})
+	req, err := http.NewRequest(http.MethodPost, s.WebhookURL, bytes.NewReader(payload))
+	if err != nil {
+		fmt.Printf("ERROR: Failed to report job %s: %v\n", job.Name, err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", s.WebhookAPIKey)
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+	if err != nil {
+		fmt.Printf("ERROR: Failed to report job %s: %v\n", job.Name, err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		fmt.Printf("ERROR: Reporting job %s returned HTTP %d\n", job.Name, resp.StatusCode)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -1,47 +1,66 @@
 package server
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
-	"strings"
 
 	"github.com/frontinsight/backend/internal/models"
 	"github.com/labstack/echo/v4"
 )
 
-// AdminMiddleware checks if the user has admin privileges
+// AdminMiddleware checks if the user's role grants access to the admin API
 func (s *Server) AdminMiddleware() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			user := c.Get("user").(*models.User)
+			user, err := s.currentUser(c)
+			if err != nil {
+				return unauthenticated(c)
+			}
+
+			// An impersonation token acts as a customer, never as an admin
+			if impersonatorID(c) != 0 {
+				return c.JSON(http.StatusForbidden, map[string]any{
+					"success": false,
+					"message": "Admin access is not available while impersonating a user",
+				})
+			}
 
-			// Check if user has admin role
-			if user.Role != "admin" && user.Role != "super_admin" {
+			// Check if user's role grants admin access
+			if !s.hasPermission(c, models.PermAdminAccess) {
 				return c.JSON(http.StatusForbidden, map[string]any{
 					"success": false,
 					"message": "Admin access required",
 				})
 			}
 
+			// Admins with settings.write can require every admin account to use two-factor authentication
+			if !user.TOTPEnabled && s.boolSetting(settingRequireAdmin2FA) {
+				return c.JSON(http.StatusForbidden, map[string]any{
+					"success":             false,
+					"message":             "Two-factor authentication is required for admin accounts. Enable it from your profile.",
+					"two_factor_required": true,
+				})
+			}
+
 			return next(c)
 		}
 	}
 }
 
-// SuperAdminMiddleware checks if the user has super admin privileges
-func (s *Server) SuperAdminMiddleware() echo.MiddlewareFunc {
+// RequirePermission limits a route to users whose role grants the permission
+func (s *Server) RequirePermission(permission string) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			user := c.Get("user").(*models.User)
-
-			// Check if user has super admin role
-			if user.Role != "super_admin" {
+			if _, err := s.currentUser(c); err != nil {
+				return unauthenticated(c)
+			}
+			if !s.hasPermission(c, permission) {
 				return c.JSON(http.StatusForbidden, map[string]any{
 					"success": false,
-					"message": "Super admin access required",
+					"message": "Missing permission: " + permission,
 				})
 			}
-
 			return next(c)
 		}
 	}
@@ -49,18 +68,17 @@ func (s *Server) SuperAdminMiddleware() echo.MiddlewareFunc {
 
 // logAdminActivity logs admin activities for audit trail
 func (s *Server) logAdminActivity(adminID uint, action, resource string, resourceID *uint, details string, c echo.Context) {
-	ipAddress := c.Request().Header.Get("X-Forwarded-For")
-	if ipAddress == "" {
-		ipAddress = c.RealIP()
-	}
-
+	ipAddress := s.getClientIP(c)
 	userAgent := c.Request().Header.Get("User-Agent")
 
-	// Ensure details is valid JSON
+	// The details column is jsonb: JSON objects are stored as they are, plain
+	// text as a JSON string
 	var detailsPtr *string
-	if details == "" {
-		detailsPtr = nil
-	} else {
+	if details != "" {
+		if !json.Valid([]byte(details)) {
+			encoded, _ := json.Marshal(details)
+			details = string(encoded)
+		}
 		detailsPtr = &details
 	}
 
@@ -82,22 +100,3 @@ func (s *Server) logAdminActivity(adminID uint, action, resource string, resourc
 		}
 	}()
 }
-
-// getClientIP extracts the client IP address from the request
-func (s *Server) getClientIP(c echo.Context) string {
-	ip := c.Request().Header.Get("X-Forwarded-For")
-	if ip == "" {
-		ip = c.Request().Header.Get("X-Real-IP")
-	}
-	if ip == "" {
-		ip = c.RealIP()
-	}
-
-	// Handle comma-separated IPs (from proxies)
-	if strings.Contains(ip, ",") {
-		ips := strings.Split(ip, ",")
-		ip = strings.TrimSpace(ips[0])
-	}
-
-	return ip
-}
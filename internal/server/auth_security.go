@@ -1,31 +1,187 @@
 package server
 
 import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
+	"github.com/labstack/echo/v4"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
 	"github.com/frontinsight/backend/internal/models"
 )
 
-// GetFailedAttemptsCount returns the number of failed login attempts for an email/IP in the last hour
-func (s *Server) GetFailedAttemptsCount(email, ipAddress string) int64 {
-	var count int64
-	oneHourAgo := time.Now().Add(-1 * time.Hour)
-	s.DB.Model(&models.LoginAttempt{}).
-		Where("email = ? AND ip_address = ? AND success = false AND created_at > ?", email, ipAddress, oneHourAgo).
-		Count(&count)
-	return count
+// Login throttling. An account may fail accountFreeFailures times in a row,
+// then it is locked for accountLockBase, doubling with every further failure
+// up to accountLockMax. An IP may fail ipFreeFailures times within
+// ipFailureWindow, then has to wait ipDelayBase after its last failure,
+// doubling the same way up to ipDelayMax.
+const (
+	accountFreeFailures = 5
+	accountLockBase     = time.Minute
+	accountLockMax      = 24 * time.Hour
+	ipFreeFailures      = 10
+	ipFailureWindow     = time.Hour
+	ipDelayBase         = time.Second
+	ipDelayMax          = 15 * time.Minute
+)
+
+// backoff returns base doubled once for every failure past the free ones,
+// capped at max, or zero while failures are still free
+func backoff(failures, free int, base, max time.Duration) time.Duration {
+	if failures < free {
+		return 0
+	}
+	d := base
+	for i := free; i < failures && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
+
+// loginIPRetryAfter returns how long an IP has to wait before its next login attempt
+func (s *Server) loginIPRetryAfter(ipAddress string) time.Duration {
+	failures := s.CountFailedAttemptsByIP(models.AttemptTypeLogin, ipAddress, ipFailureWindow)
+	delay := backoff(int(failures), ipFreeFailures, ipDelayBase, ipDelayMax)
+	if delay == 0 {
+		return 0
+	}
+	var last models.LoginAttempt
+	if err := s.DB.Where("attempt_type = ? AND ip_address = ? AND success = false", models.AttemptTypeLogin, ipAddress).
+		Order("created_at DESC").First(&last).Error; err != nil {
+		return 0
+	}
+	if wait := time.Until(last.CreatedAt.Add(delay)); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// dummyPasswordHash is compared against when a login names no account, so it
+// costs the same as checking a real password
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("not-a-password"), 12)
+	return hash
+})
+
+// accountRetryAfter returns how long a locked account stays locked
+func accountRetryAfter(user *models.User) time.Duration {
+	if user.LockedUntil == nil {
+		return 0
+	}
+	if wait := time.Until(*user.LockedUntil); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// recordAccountFailure counts a wrong password against the account and locks
+// it once it has failed too often, emailing the owner
+func (s *Server) recordAccountFailure(user *models.User, ipAddress string) {
+	err := s.DB.Model(user).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "failed_login_count"}}}).
+		Update("failed_login_count", gorm.Expr("failed_login_count + 1")).Error
+	if err != nil {
+		fmt.Printf("ERROR: Failed to count failed login for %s: %v\n", user.Email, err)
+		return
+	}
+	lock := backoff(user.FailedLoginCount, accountFreeFailures, accountLockBase, accountLockMax)
+	if lock == 0 {
+		return
+	}
+	until := time.Now().UTC().Add(lock)
+	if err := s.DB.Model(user).Update("locked_until", until).Error; err != nil {
+		fmt.Printf("ERROR: Failed to lock account %s: %v\n", user.Email, err)
+		return
+	}
+	go func(email string, failures int) {
+		if err := s.sendAccountLockedEmail(email, failures, until, ipAddress); err != nil {
+			fmt.Printf("ERROR: Failed to send account locked email to %s: %v\n", email, err)
+		}
+	}(user.Email, user.FailedLoginCount)
+}
+
+// unlockAccount clears an account's lock and failure count
+func (s *Server) unlockAccount(userID uint) error {
+	return s.DB.Model(&models.User{}).Where("id = ?", userID).
+		Updates(map[string]any{"failed_login_count": 0, "locked_until": nil}).Error
+}
+
+// tooManyLoginAttempts writes the 429 response for a throttled login
+func tooManyLoginAttempts(c echo.Context, wait time.Duration) error {
+	c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	return c.JSON(http.StatusTooManyRequests, simpleResponse{Success: false, Message: "Too many failed login attempts. Please try again later."})
+}
+
+func (s *Server) sendAccountLockedEmail(email string, failures int, until time.Time, ipAddress string) error {
+	return s.sendEmail(email, "account_locked", map[string]any{
+		"Failures":  failures,
+		"IPAddress": ipAddress,
+		"Until":     until.Format("2006-01-02 15:04"),
+		"Link":      s.Cfg.FrontendURL + "/forgot-password",
+	})
 }
 
 // RecordLoginAttempt records a login attempt
 func (s *Server) RecordLoginAttempt(email, ipAddress string, success bool) {
+	s.RecordAttempt(models.AttemptTypeLogin, email, ipAddress, success)
+}
+
+// RecordAttempt records an attempt of the given type (login, password reset, ...)
+func (s *Server) RecordAttempt(attemptType, email, ipAddress string, success bool) {
 	attempt := models.LoginAttempt{
-		Email:     email,
-		IPAddress: ipAddress,
-		Success:   success,
+		Email:       email,
+		IPAddress:   ipAddress,
+		AttemptType: attemptType,
+		Success:     success,
 	}
 	s.DB.Create(&attempt)
 }
 
+// CountAttemptsByEmail returns the number of attempts of the given type for an email within the window
+func (s *Server) CountAttemptsByEmail(attemptType, email string, window time.Duration) int64 {
+	var count int64
+	s.DB.Model(&models.LoginAttempt{}).
+		Where("attempt_type = ? AND email = ? AND created_at > ?", attemptType, email, time.Now().Add(-window)).
+		Count(&count)
+	return count
+}
+
+// CountAttemptsByIP returns the number of attempts of the given type from an IP within the window
+func (s *Server) CountAttemptsByIP(attemptType, ipAddress string, window time.Duration) int64 {
+	var count int64
+	s.DB.Model(&models.LoginAttempt{}).
+		Where("attempt_type = ? AND ip_address = ? AND created_at > ?", attemptType, ipAddress, time.Now().Add(-window)).
+		Count(&count)
+	return count
+}
+
+// CountFailedAttemptsByIP returns the number of failed attempts of the given type from an IP within the window
+func (s *Server) CountFailedAttemptsByIP(attemptType, ipAddress string, window time.Duration) int64 {
+	var count int64
+	s.DB.Model(&models.LoginAttempt{}).
+		Where("attempt_type = ? AND ip_address = ? AND success = false AND created_at > ?", attemptType, ipAddress, time.Now().Add(-window)).
+		Count(&count)
+	return count
+}
+
+// CountFailedAttemptsByEmail returns the number of failed attempts of the given type for an email within the window
+func (s *Server) CountFailedAttemptsByEmail(attemptType, email string, window time.Duration) int64 {
+	var count int64
+	s.DB.Model(&models.LoginAttempt{}).
+		Where("attempt_type = ? AND email = ? AND success = false AND created_at > ?", attemptType, email, time.Now().Add(-window)).
+		Count(&count)
+	return count
+}
+
 // CleanupOldAttempts removes login attempts older than 24 hours
 func (s *Server) CleanupOldAttempts() {
 	oneDayAgo := time.Now().Add(-24 * time.Hour)
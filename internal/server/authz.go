@@ -0,0 +1,117 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/frontinsight/backend/internal/models"
+)
+
+// Ownership checks for collections and searches. A record belongs to the
+// user who created it, or to its organization when OrganizationID is set.
+// Records the caller cannot see are reported as not found, so IDs of other
+// customers' data cannot be probed.
+
+// access is what the caller wants to do with a record
+type access int
+
+const (
+	accessRead  access = iota // any organization member
+	accessWrite               // organization owners and managers
+)
+
+var (
+	errResourceNotFound = errors.New("resource not found")
+	errResourceReadOnly = errors.New("read-only access")
+)
+
+// authorizeOwner checks the caller against a record's owner and organization
+func (s *Server) authorizeOwner(c echo.Context, ownerEmail string, orgID *uint, want access) error {
+	if orgID == nil {
+		if ownerEmail == "" || ownerEmail != currentUserEmail(c) {
+			return errResourceNotFound
+		}
+		return nil
+	}
+	member, err := s.orgMembership(currentUserID(c), *orgID, models.OrgRoleViewer)
+	if err != nil {
+		return errResourceNotFound
+	}
+	if want == accessWrite && orgRoleRank[member.Role] < orgRoleRank[models.OrgRoleManager] {
+		return errResourceReadOnly
+	}
+	return nil
+}
+
+// ownedCollection loads a collection the caller may access
+func (s *Server) ownedCollection(c echo.Context, id any, want access) (*models.Collection, error) {
+	var col models.Collection
+	if err := s.DB.First(&col, id).Error; err != nil || col.ID == 0 {
+		return nil, errResourceNotFound
+	}
+	if err := s.authorizeOwner(c, col.UserID, col.OrganizationID, want); err != nil {
+		return nil, err
+	}
+	return &col, nil
+}
+
+// ownedCollectionItem loads a collection item and its collection
+func (s *Server) ownedCollectionItem(c echo.Context, id any, want access) (*models.CollectionItem, *models.Collection, error) {
+	var item models.CollectionItem
+	if err := s.DB.First(&item, id).Error; err != nil || item.ID == 0 {
+		return nil, nil, errResourceNotFound
+	}
+	col, err := s.ownedCollection(c, item.CollectionID, want)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &item, col, nil
+}
+
+// ownedSearch loads a search the caller may access
+func (s *Server) ownedSearch(c echo.Context, id any, want access) (*models.Search, error) {
+	var search models.Search
+	if err := s.DB.First(&search, id).Error; err != nil || search.ID == 0 {
+		return nil, errResourceNotFound
+	}
+	if err := s.authorizeOwner(c, search.UserID, search.OrganizationID, want); err != nil {
+		return nil, err
+	}
+	return &search, nil
+}
+
+// ownedSearchWhere loads the latest search matching the condition that the caller may access
+func (s *Server) ownedSearchWhere(c echo.Context, want access, query string, args ...any) (*models.Search, error) {
+	var search models.Search
+	if err := s.DB.Where(query, args...).Order("id DESC").First(&search).Error; err != nil {
+		return nil, errResourceNotFound
+	}
+	if err := s.authorizeOwner(c, search.UserID, search.OrganizationID, want); err != nil {
+		return nil, err
+	}
+	return &search, nil
+}
+
+// ownedSearchItem loads a search item and its search
+func (s *Server) ownedSearchItem(c echo.Context, id any, want access) (*models.SearchItem, *models.Search, error) {
+	var item models.SearchItem
+	if err := s.DB.First(&item, id).Error; err != nil || item.ID == 0 {
+		return nil, nil, errResourceNotFound
+	}
+	search, err := s.ownedSearch(c, item.SearchID, want)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &item, search, nil
+}
+
+// accessError writes the response for an ownership error: 404 for records the
+// caller cannot see and 403 for organization members without write access
+func accessError(c echo.Context, err error, notFoundMessage string) error {
+	if errors.Is(err, errResourceReadOnly) {
+		return c.JSON(http.StatusForbidden, map[string]any{"success": false, "message": "Your organization role does not allow changes"})
+	}
+	return c.JSON(http.StatusNotFound, map[string]any{"success": false, "message": notFoundMessage})
+}
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// newIPExtractor decides how c.RealIP() finds the client address. Forwarding
+// headers are only honoured when the request comes through one of the trusted
+// proxies; otherwise anyone could pick the IP that rate limits are keyed on.
+func newIPExtractor(trustedProxies []string) echo.IPExtractor {
+	options := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	trusted := 0
+	for _, p := range trustedProxies {
+		if !strings.Contains(p, "/") {
+			if ip := net.ParseIP(p); ip != nil && ip.To4() != nil {
+				p += "/32"
+			} else {
+				p += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(p)
+		if err != nil {
+			fmt.Printf("ERROR: Ignoring invalid trusted proxy %q: %v\n", p, err)
+			continue
+		}
+		options = append(options, echo.TrustIPRange(ipNet))
+		trusted++
+	}
+	if trusted == 0 {
+		return echo.ExtractIPDirect()
+	}
+	return echo.ExtractIPFromXFFHeader(options...)
+}
+
+// getClientIP returns the client IP address resolved by the server's IP extractor
+func (s *Server) getClientIP(c echo.Context) string {
+	return c.RealIP()
+}
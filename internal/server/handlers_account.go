@@ -0,0 +1,300 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+
+	"github.com/frontinsight/backend/internal/models"
+	"github.com/frontinsight/backend/internal/utils"
+)
+
+// accountDeletionGrace is how long a user can change their mind before the
+// account is anonymized
+const accountDeletionGrace = 30 * 24 * time.Hour
+
+var errSoleOrgOwner = errors.New("user is the only owner of an organization")
+
+// ExportAccountData godoc
+// @Summary Download my data
+// @Description Download a zip archive with the authenticated user's profile and its change history, collections, searches, schedules, transactions and login attempts as JSON files
+// @Tags Authentication
+// @Produce application/zip
+// @Security BearerAuth
+// @Success 200 {file} file "Zip archive"
+// @Failure 401 {object} simpleResponse
+// @Failure 500 {object} simpleResponse
+// @Router /auth/account/export [get]
+func (s *Server) ExportAccountData(c echo.Context) error {
+	user, err := s.currentUser(c)
+	if err != nil {
+		return unauthenticated(c)
+	}
+
+	var collections []models.Collection
+	var searches []models.Search
+	var schedules []models.Schedule
+	var transactions []models.Transaction
+	var paymentOrders []models.PaymentOrder
+	var attempts []models.LoginAttempt
+	var sessions []models.Session
+	var profileChanges []models.ProfileChange
+	var notifications []models.Notification
+	var notificationPrefs []models.NotificationPreference
+	var webhookEndpoints []models.WebhookEndpoint
+	queries := []error{
+		s.DB.Preload("CollectionItems").Where("user_id = ?", user.Email).Order("id").Find(&collections).Error,
+		s.DB.Preload("Items").Where("user_id = ?", user.Email).Order("id").Find(&searches).Error,
+		s.DB.Where("user_id = ?", user.Email).Order("id").Find(&schedules).Error,
+		s.DB.Where("user_id = ?", user.Email).Order("id").Find(&transactions).Error,
+		s.DB.Where("user_id = ?", user.Email).Order("id").Find(&paymentOrders).Error,
+		s.DB.Where("email = ?", user.Email).Order("id").Find(&attempts).Error,
+		s.DB.Where("user_id = ?", user.ID).Order("id").Find(&sessions).Error,
+		s.DB.Where("user_id = ?", user.ID).Order("id").Find(&profileChanges).Error,
+		s.DB.Where("user_id = ?", user.ID).Order("id").Find(&notifications).Error,
+		s.DB.Where("user_id = ?", user.ID).Order("event").Find(&notificationPrefs).Error,
+		s.DB.Where("user_id = ? AND organization_id IS NULL", user.ID).Order("id").Find(&webhookEndpoints).Error,
+	}
+	for _, err := range queries {
+		if err != nil {
+			fmt.Printf("ERROR: Failed to export data of user %d: %v\n", user.ID, err)
+			return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to export your data."})
+		}
+	}
+
+	files := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", user},
+		{"collections.json", collections},
+		{"searches.json", searches},
+		{"schedules.json", schedules},
+		{"transactions.json", transactions},
+		{"payment_orders.json", paymentOrders},
+		{"login_attempts.json", attempts},
+		{"sessions.json", sessions},
+		{"profile_changes.json", profileChanges},
+		{"notifications.json", notifications},
+		{"notification_preferences.json", notificationPrefs},
+		{"webhook_endpoints.json", webhookEndpoints},
+	}
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	for _, f := range files {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to export your data."})
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.data); err != nil {
+			return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to export your data."})
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to export your data."})
+	}
+
+	filename := fmt.Sprintf("front-insight-data-%s.zip", time.Now().UTC().Format("20060102"))
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	return c.Blob(http.StatusOK, "application/zip", buf.Bytes())
+}
+
+type accountDeletionRequest struct {
+	Password string `json:"password" example:"password123" binding:"required"`
+}
+
+// RequestAccountDeletion godoc
+// @Summary Delete my account
+// @Description Schedule the account for deletion after a 30 day grace period. Until then the request can be cancelled. On deletion personal data is removed; wallet transactions are kept, without personal data, for accounting.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body accountDeletionRequest true "Current password"
+// @Success 200 {object} map[string]interface{} "Deletion scheduled"
+// @Failure 400 {object} simpleResponse
+// @Failure 401 {object} simpleResponse
+// @Router /auth/account/deletion [post]
+func (s *Server) RequestAccountDeletion(c echo.Context) error {
+	var req accountDeletionRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Invalid payload"})
+	}
+	user, err := s.currentUser(c)
+	if err != nil {
+		return unauthenticated(c)
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(utils.SanitizeString(req.Password))); err != nil {
+		return c.JSON(http.StatusUnauthorized, simpleResponse{Success: false, Message: "Password is incorrect."})
+	}
+	if user.DeletionScheduledAt != nil {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Your account is already scheduled for deletion."})
+	}
+	if err := s.checkNotSoleOrgOwner(s.DB, user.ID); err != nil {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Add another owner to your organizations, or remove them, before deleting your account."})
+	}
+
+	scheduledAt := time.Now().UTC().Add(accountDeletionGrace)
+	if err := s.DB.Model(user).Update("deletion_scheduled_at", scheduledAt).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to schedule account deletion."})
+	}
+
+	go func(email string) {
+		if err := s.sendAccountDeletionEmail(email, scheduledAt); err != nil {
+			fmt.Printf("ERROR: Failed to send account deletion email to %s: %v\n", email, err)
+		}
+	}(user.Email)
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"success":               true,
+		"message":               "Your account will be deleted. You can cancel until then.",
+		"deletion_scheduled_at": scheduledAt,
+	})
+}
+
+// CancelAccountDeletion godoc
+// @Summary Cancel account deletion
+// @Description Keep the account that was scheduled for deletion
+// @Tags Authentication
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} simpleResponse
+// @Failure 400 {object} simpleResponse
+// @Router /auth/account/deletion [delete]
+func (s *Server) CancelAccountDeletion(c echo.Context) error {
+	user, err := s.currentUser(c)
+	if err != nil {
+		return unauthenticated(c)
+	}
+	if user.DeletionScheduledAt == nil {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Your account is not scheduled for deletion."})
+	}
+	if err := s.DB.Model(user).Update("deletion_scheduled_at", nil).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to cancel account deletion."})
+	}
+	return c.JSON(http.StatusOK, simpleResponse{Success: true, Message: "Account deletion cancelled."})
+}
+
+// checkNotSoleOrgOwner fails when the user is the last owner of an organization
+func (s *Server) checkNotSoleOrgOwner(tx *gorm.DB, userID uint) error {
+	var owned []models.OrganizationMember
+	if err := tx.Where("user_id = ? AND role = ?", userID, models.OrgRoleOwner).Find(&owned).Error; err != nil {
+		return err
+	}
+	for _, m := range owned {
+		n, err := countOwners(tx, m.OrganizationID)
+		if err != nil {
+			return err
+		}
+		if n <= 1 {
+			return errSoleOrgOwner
+		}
+	}
+	return nil
+}
+
+// purgeDeletedAccounts anonymizes accounts whose deletion grace period is over
+func (s *Server) purgeDeletedAccounts() {
+	var users []models.User
+	if err := s.DB.Where("deletion_scheduled_at < ? AND anonymized_at IS NULL", time.Now().UTC()).Find(&users).Error; err != nil {
+		fmt.Printf("ERROR: Failed to load accounts due for deletion: %v\n", err)
+		return
+	}
+	for i := range users {
+		if err := s.anonymizeUser(&users[i]); err != nil {
+			fmt.Printf("ERROR: Failed to delete account %d: %v\n", users[i].ID, err)
+		}
+	}
+}
+
+// anonymizeUser removes a user's personal data. The user row, searches,
+// payment orders and wallet transactions stay for accounting, moved to a
+// placeholder address, and so do organization records; everything else the
+// user owns is deleted.
+func (s *Server) anonymizeUser(user *models.User) error {
+	if user.FrozenAmount > 0 {
+		return errors.New("searches still running, retrying later")
+	}
+	oldEmail := user.Email
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := s.checkNotSoleOrgOwner(tx, user.ID); err != nil {
+			return err
+		}
+
+		var collectionIDs, scheduleIDs, endpointIDs []uint
+		if err := tx.Model(&models.Collection{}).Where("user_id = ? AND organization_id IS NULL", oldEmail).Pluck("id", &collectionIDs).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Schedule{}).Where("user_id = ? AND organization_id IS NULL", oldEmail).Pluck("id", &scheduleIDs).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.WebhookEndpoint{}).Where("user_id = ? AND organization_id IS NULL", user.ID).Pluck("id", &endpointIDs).Error; err != nil {
+			return err
+		}
+		deletes := []*gorm.DB{
+			tx.Where("collection_id IN ?", collectionIDs).Delete(&models.CollectionItem{}),
+			tx.Where("id IN ?", collectionIDs).Delete(&models.Collection{}),
+			tx.Where("schedule_id IN ?", scheduleIDs).Delete(&models.ScheduleRun{}),
+			tx.Where("id IN ?", scheduleIDs).Delete(&models.Schedule{}),
+			tx.Where("email = ?", oldEmail).Delete(&models.LoginAttempt{}),
+			tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}),
+			tx.Where("user_id = ?", user.ID).Delete(&models.APIKey{}),
+			tx.Where("user_id = ?", user.ID).Delete(&models.OIDCIdentity{}),
+			tx.Where("user_id = ?", user.ID).Delete(&models.OrganizationMember{}),
+			tx.Where("user_id = ?", user.ID).Delete(&models.RefreshToken{}),
+			tx.Where("user_id = ?", user.ID).Delete(&models.Session{}),
+			tx.Where("user_id = ?", user.ID).Delete(&models.ProfileChange{}),
+			tx.Where("user_id = ?", user.ID).Delete(&models.Notification{}),
+			tx.Where("user_id = ?", user.ID).Delete(&models.NotificationPreference{}),
+			tx.Where("endpoint_id IN ?", endpointIDs).Delete(&models.WebhookDelivery{}),
+			tx.Where("id IN ?", endpointIDs).Delete(&models.WebhookEndpoint{}),
+		}
+		for _, res := range deletes {
+			if res.Error != nil {
+				return res.Error
+			}
+		}
+
+		// Organization collections, schedules and webhook endpoints stay with
+		// the organization
+		placeholder := fmt.Sprintf("deleted-user-%d@deleted.invalid", user.ID)
+		if err := s.changeUserEmail(tx, user, placeholder); err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		return tx.Model(user).Updates(map[string]any{
+			"name":               "Deleted user",
+			"password":           "",
+			"is_verified":        false,
+			"role":               models.RoleUser,
+			"city":               nil,
+			"state":              nil,
+			"country":            nil,
+			"mobile_number":      nil,
+			"ip_address":         nil,
+			"timezone":           nil,
+			"business_type":      nil,
+			"company":            nil,
+			"totp_secret":        nil,
+			"totp_enabled":       false,
+			"locked_until":       nil,
+			"last_login_at":      nil,
+			"anonymized_at":      now,
+			"failed_login_count": 0,
+		}).Error
+	})
+}
+
+func (s *Server) sendAccountDeletionEmail(email string, scheduledAt time.Time) error {
+	return s.sendEmail(email, "account_deletion", map[string]any{"ScheduledAt": scheduledAt.Format("2006-01-02 15:04")})
+}
@@ -1,13 +1,20 @@
 package server
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/frontinsight/backend/internal/events"
 	"github.com/frontinsight/backend/internal/models"
+	"github.com/frontinsight/backend/internal/utils"
 	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // AdminDashboard godoc
@@ -21,10 +28,8 @@ import (
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /admin/dashboard [get]
 func (s *Server) AdminDashboard(c echo.Context) error {
-	user := c.Get("user").(*models.User)
-
 	// Log admin activity
-	s.logAdminActivity(user.ID, "view", "dashboard", nil, "", c)
+	s.logAdminActivity(currentUserID(c), "view", "dashboard", nil, "", c)
 
 	var stats models.AdminDashboardStats
 
@@ -90,15 +95,14 @@ func (s *Server) AdminDashboard(c echo.Context) error {
 // @Param search query string false "Search by name or email"
 // @Param role query string false "Filter by role"
 // @Param verified query bool false "Filter by verification status"
+// @Param locked query bool false "Only users locked out after failed logins"
 // @Success 200 {object} map[string]interface{} "List of users with statistics"
 // @Failure 403 {object} map[string]string "Forbidden"
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /admin/users [get]
 func (s *Server) AdminUsers(c echo.Context) error {
-	user := c.Get("user").(*models.User)
-
 	// Log admin activity
-	s.logAdminActivity(user.ID, "view", "users", nil, "", c)
+	s.logAdminActivity(currentUserID(c), "view", "users", nil, "", c)
 
 	// Parse query parameters
 	page, _ := strconv.Atoi(c.QueryParam("page"))
@@ -136,6 +140,10 @@ func (s *Server) AdminUsers(c echo.Context) error {
 		}
 	}
 
+	if c.QueryParam("locked") == "true" {
+		query = query.Where("locked_until > ?", time.Now().UTC())
+	}
+
 	// Get total count
 	var total int64
 	query.Count(&total)
@@ -184,7 +192,6 @@ func (s *Server) AdminUsers(c echo.Context) error {
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /admin/users/{id} [get]
 func (s *Server) AdminUserDetails(c echo.Context) error {
-	user := c.Get("user").(*models.User)
 	userID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]any{
@@ -203,7 +210,7 @@ func (s *Server) AdminUserDetails(c echo.Context) error {
 	}
 
 	// Log admin activity
-	s.logAdminActivity(user.ID, "view", "user", &targetUser.ID, fmt.Sprintf("Viewed user: %s", targetUser.Email), c)
+	s.logAdminActivity(currentUserID(c), "view", "user", &targetUser.ID, fmt.Sprintf("Viewed user: %s", targetUser.Email), c)
 
 	// Get user statistics
 	var stats struct {
@@ -237,20 +244,30 @@ func (s *Server) AdminUserDetails(c echo.Context) error {
 	var recentCollections []models.Collection
 	s.DB.Where("user_id = ?", targetUser.Email).Order("created_at DESC").Limit(10).Find(&recentCollections)
 
+	// Get signed-in devices
+	sessions, _ := s.activeSessions(targetUser.ID)
+
+	// Get recent profile edits
+	var profileChanges []models.ProfileChange
+	s.DB.Where("user_id = ?", targetUser.ID).Order("id DESC").Limit(20).Find(&profileChanges)
+
 	return c.JSON(http.StatusOK, map[string]any{
 		"success": true,
 		"data": map[string]any{
 			"user":               targetUser,
+			"locked":             accountRetryAfter(&targetUser) > 0,
 			"stats":              stats,
 			"recent_searches":    recentSearches,
 			"recent_collections": recentCollections,
+			"sessions":           sessions,
+			"profile_changes":    profileChanges,
 		},
 	})
 }
 
 // AdminUpdateUser godoc
 // @Summary Update user information
-// @Description Update user details including role and verification status
+// @Description Update user details including email, role and verification status. Changing the role needs the users.roles permission. Changing the email moves the user's searches, collections, schedules and wallet history to the new address and signs the user out everywhere.
 // @Tags Admin
 // @Accept json
 // @Produce json
@@ -264,7 +281,10 @@ func (s *Server) AdminUserDetails(c echo.Context) error {
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /admin/users/{id} [put]
 func (s *Server) AdminUpdateUser(c echo.Context) error {
-	adminUser := c.Get("user").(*models.User)
+	adminUser, err := s.currentUser(c)
+	if err != nil {
+		return unauthenticated(c)
+	}
 	userID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]any{
@@ -294,37 +314,115 @@ func (s *Server) AdminUpdateUser(c echo.Context) error {
 	// Track changes for audit log
 	changes := make(map[string]interface{})
 
-	// Update allowed fields
+	// Changing the address or verification of an account hands over its
+	// password resets, so it needs the same standing as changing its role
+	canManageLogin := s.canGrantRole(c, targetUser.Role)
+	loginForbidden := func() error {
+		return c.JSON(http.StatusForbidden, map[string]any{
+			"success": false,
+			"message": "You cannot change the email or verification of an account with permissions you do not have",
+		})
+	}
+
+	// Update allowed fields; name and email edits also go to the user's profile history
+	var history []*models.ProfileChange
 	if name, ok := updateData["name"].(string); ok && name != "" {
 		changes["name"] = map[string]interface{}{"old": targetUser.Name, "new": name}
+		history = append(history, s.newProfileChange(c, targetUser.ID, "name", &targetUser.Name, &name))
 		targetUser.Name = name
 	}
 
 	if role, ok := updateData["role"].(string); ok && role != "" {
-		// Only super_admin can change roles
-		if adminUser.Role != "super_admin" {
+		if !s.hasPermission(c, models.PermUsersRoles) {
 			return c.JSON(http.StatusForbidden, map[string]any{
 				"success": false,
-				"message": "Only super admin can change user roles",
+				"message": "Missing permission: " + models.PermUsersRoles,
+			})
+		}
+		newRole := s.loadRole(role)
+		if newRole == nil {
+			return c.JSON(http.StatusBadRequest, map[string]any{
+				"success": false,
+				"message": "Unknown role: " + role,
+			})
+		}
+		// Admins cannot grant, or take away, permissions they do not hold themselves
+		if !s.canGrant(c, newRole.Permissions) || !s.canGrantRole(c, targetUser.Role) {
+			return c.JSON(http.StatusForbidden, map[string]any{
+				"success": false,
+				"message": "You cannot assign or change a role with permissions you do not have",
 			})
 		}
 		changes["role"] = map[string]interface{}{"old": targetUser.Role, "new": role}
 		targetUser.Role = role
 	}
 
-	if isVerified, ok := updateData["is_verified"].(bool); ok {
+	if isVerified, ok := updateData["is_verified"].(bool); ok && isVerified != targetUser.IsVerified {
+		if !canManageLogin {
+			return loginForbidden()
+		}
 		changes["is_verified"] = map[string]interface{}{"old": targetUser.IsVerified, "new": isVerified}
 		targetUser.IsVerified = isVerified
 	}
 
-	// Save changes
-	if err := s.DB.Save(&targetUser).Error; err != nil {
+	newEmail, oldEmail := "", targetUser.Email
+	if email, ok := updateData["email"].(string); ok {
+		email = strings.TrimSpace(strings.ToLower(email))
+		if email != "" && email != targetUser.Email {
+			if !canManageLogin {
+				return loginForbidden()
+			}
+			if !utils.ValidateEmail(email) {
+				return c.JSON(http.StatusBadRequest, map[string]any{
+					"success": false,
+					"message": "Invalid email address format",
+				})
+			}
+			changes["email"] = map[string]interface{}{"old": targetUser.Email, "new": email}
+			history = append(history, s.newProfileChange(c, targetUser.ID, "email", &targetUser.Email, &email))
+			newEmail = email
+		}
+	}
+
+	// Save changes; an email change moves everything keyed on the old address
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&targetUser).Error; err != nil {
+			return err
+		}
+		for _, change := range history {
+			if change != nil {
+				if err := tx.Create(change).Error; err != nil {
+					return err
+				}
+			}
+		}
+		if newEmail != "" {
+			return s.changeUserEmail(tx, &targetUser, newEmail)
+		}
+		return nil
+	})
+	if errors.Is(err, errEmailTaken) {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"success": false,
+			"message": "Email already registered",
+		})
+	}
+	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]any{
 			"success": false,
 			"message": "Failed to update user",
 		})
 	}
 
+	// Tell the old address, as a self-service change does
+	if newEmail != "" {
+		go func() {
+			if err := s.sendEmailChangedNotice(oldEmail, newEmail); err != nil {
+				fmt.Printf("ERROR: Failed to send email change notice to %s: %v\n", oldEmail, err)
+			}
+		}()
+	}
+
 	// Log admin activity
 	details := fmt.Sprintf("Updated user: %s, changes: %+v", targetUser.Email, changes)
 	s.logAdminActivity(adminUser.ID, "update", "user", &targetUser.ID, details, c)
@@ -336,6 +434,129 @@ func (s *Server) AdminUpdateUser(c echo.Context) error {
 	})
 }
 
+// AdminUnlockUser godoc
+// @Summary Unlock a user account
+// @Description Lift a lockout caused by failed logins and reset the failure count
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} map[string]interface{} "Account unlocked"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 404 {object} map[string]string "User not found"
+// @Router /admin/users/{id}/unlock [post]
+func (s *Server) AdminUnlockUser(c echo.Context) error {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{"success": false, "message": "Invalid user ID"})
+	}
+
+	var targetUser models.User
+	if err := s.DB.First(&targetUser, userID).Error; err != nil {
+		return c.JSON(http.StatusNotFound, map[string]any{"success": false, "message": "User not found"})
+	}
+	if err := s.unlockAccount(targetUser.ID); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]any{"success": false, "message": "Failed to unlock user"})
+	}
+
+	s.logAdminActivity(currentUserID(c), "unlock", "user", &targetUser.ID, fmt.Sprintf("Unlocked user: %s after %d failed logins", targetUser.Email, targetUser.FailedLoginCount), c)
+
+	return c.JSON(http.StatusOK, map[string]any{"success": true, "message": "User unlocked"})
+}
+
+// errBalanceTooLow is returned when a debit would take a wallet below zero
+var errBalanceTooLow = errors.New("balance too low")
+
+// AdminAdjustWallet godoc
+// @Summary Adjust a user's wallet balance
+// @Description Credit (positive amount) or debit (negative amount) a user's personal wallet, for refunds and corrections. The adjustment is recorded as a wallet transaction with the reason and in the admin audit log. A debit cannot take the balance below zero.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param request body map[string]interface{} true "amount and reason"
+// @Success 200 {object} map[string]interface{} "Balance adjusted"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "User not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /admin/users/{id}/wallet/adjust [post]
+func (s *Server) AdminAdjustWallet(c echo.Context) error {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{"success": false, "message": "Invalid user ID"})
+	}
+	var req struct {
+		Amount float64 `json:"amount"`
+		Reason string  `json:"reason"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{"success": false, "message": "Invalid request"})
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Amount == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]any{"success": false, "message": "Amount must not be zero"})
+	}
+	if req.Reason == "" || len(req.Reason) > 500 {
+		return c.JSON(http.StatusBadRequest, map[string]any{"success": false, "message": "A reason of up to 500 characters is required"})
+	}
+
+	var targetUser models.User
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the wallet so a concurrent freeze or refund is not overwritten
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&targetUser, userID).Error; err != nil {
+			return err
+		}
+		balanceBefore := targetUser.Balance
+		if balanceBefore+req.Amount < 0 {
+			return errBalanceTooLow
+		}
+		targetUser.Balance += req.Amount
+		if err := tx.Model(&targetUser).Update("balance", targetUser.Balance).Error; err != nil {
+			return err
+		}
+		txnType := "credit"
+		if req.Amount < 0 {
+			txnType = "debit"
+		}
+		description := "Adjustment by support: " + req.Reason
+		return tx.Create(&models.Transaction{
+			UserID:        targetUser.Email,
+			TxnType:       txnType,
+			Amount:        math.Abs(req.Amount),
+			BalanceBefore: balanceBefore,
+			BalanceAfter:  targetUser.Balance,
+			Description:   &description,
+			Status:        "completed",
+			CreatedAt:     time.Now(),
+			UpdatedAt:     time.Now(),
+		}).Error
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return c.JSON(http.StatusNotFound, map[string]any{"success": false, "message": "User not found"})
+	case errors.Is(err, errBalanceTooLow):
+		return c.JSON(http.StatusBadRequest, map[string]any{"success": false, "message": "The debit is larger than the available balance"})
+	case err != nil:
+		fmt.Printf("ERROR: Failed to adjust wallet of user %d: %v\n", userID, err)
+		return c.JSON(http.StatusInternalServerError, map[string]any{"success": false, "message": "Failed to adjust balance"})
+	}
+
+	s.logAdminActivity(currentUserID(c), "adjust_wallet", "user", &targetUser.ID, fmt.Sprintf("Adjusted wallet of %s by %s: %s", targetUser.Email, formatCurrency(req.Amount), req.Reason), c)
+	s.events.Publish(targetUser.Email, events.WalletBalance, map[string]any{
+		"balance":         targetUser.Balance,
+		"frozen_amount":   targetUser.FrozenAmount,
+		"organization_id": nil,
+	})
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"success": true,
+		"message": "Balance adjusted",
+		"balance": targetUser.Balance,
+	})
+}
+
 // AdminSearches godoc
 // @Summary Get all searches with user information
 // @Description Retrieve all searches across all users with pagination and filtering
@@ -352,10 +573,8 @@ func (s *Server) AdminUpdateUser(c echo.Context) error {
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /admin/searches [get]
 func (s *Server) AdminSearches(c echo.Context) error {
-	user := c.Get("user").(*models.User)
-
 	// Log admin activity
-	s.logAdminActivity(user.ID, "view", "searches", nil, "", c)
+	s.logAdminActivity(currentUserID(c), "view", "searches", nil, "", c)
 
 	// Parse query parameters
 	page, _ := strconv.Atoi(c.QueryParam("page"))
@@ -439,10 +658,8 @@ func (s *Server) AdminSearches(c echo.Context) error {
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /admin/collections [get]
 func (s *Server) AdminCollections(c echo.Context) error {
-	user := c.Get("user").(*models.User)
-
 	// Log admin activity
-	s.logAdminActivity(user.ID, "view", "collections", nil, "", c)
+	s.logAdminActivity(currentUserID(c), "view", "collections", nil, "", c)
 
 	// Parse query parameters
 	page, _ := strconv.Atoi(c.QueryParam("page"))
@@ -518,10 +735,8 @@ func (s *Server) AdminCollections(c echo.Context) error {
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /admin/activities [get]
 func (s *Server) AdminActivities(c echo.Context) error {
-	user := c.Get("user").(*models.User)
-
 	// Log admin activity
-	s.logAdminActivity(user.ID, "view", "activities", nil, "", c)
+	s.logAdminActivity(currentUserID(c), "view", "activities", nil, "", c)
 
 	// Parse query parameters
 	page, _ := strconv.Atoi(c.QueryParam("page"))
@@ -594,8 +809,7 @@ func (s *Server) AdminActivities(c echo.Context) error {
 // AdminSchedules handles GET /admin/schedules
 func (s *Server) AdminSchedules(c echo.Context) error {
 	// Log admin activity
-	user := c.Get("user").(*models.User)
-	s.logAdminActivity(user.ID, "view", "schedules", nil, "", c)
+	s.logAdminActivity(currentUserID(c), "view", "schedules", nil, "", c)
 
 	// Parse query parameters
 	page := 1
@@ -0,0 +1,263 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/frontinsight/backend/internal/models"
+	"github.com/frontinsight/backend/internal/utils"
+)
+
+const (
+	// apiKeyPrefix identifies API keys in the Authorization header and in leaked-secret scans
+	apiKeyPrefix = "fi_"
+	// apiKeyDisplayLength is how much of the key is stored in clear to tell keys apart
+	apiKeyDisplayLength = len(apiKeyPrefix) + 8
+
+	maxAPIKeysPerUser = 20
+	// lastUsedInterval throttles last-used updates so busy scripts don't write on every request
+	lastUsedInterval = time.Minute
+)
+
+var (
+	errInvalidAPIKey = errors.New("invalid, expired or revoked API key")
+
+	validAPIKeyScopes = []string{models.APIKeyScopeRead, models.APIKeyScopeSubmit, models.APIKeyScopeBilling}
+)
+
+// authenticateAPIKey resolves a raw API key to the key record and its owner
+func (s *Server) authenticateAPIKey(rawKey, ipAddress string) (*models.APIKey, *models.User, error) {
+	var key models.APIKey
+	if err := s.DB.Where("key_hash = ? AND revoked_at IS NULL", utils.HashToken(rawKey)).First(&key).Error; err != nil {
+		return nil, nil, errInvalidAPIKey
+	}
+	now := time.Now().UTC()
+	if key.ExpiresAt != nil && key.ExpiresAt.Before(now) {
+		return nil, nil, errInvalidAPIKey
+	}
+
+	var user models.User
+	if err := s.DB.First(&user, key.UserID).Error; err != nil || !user.IsVerified {
+		return nil, nil, errInvalidAPIKey
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > lastUsedInterval {
+		key.LastUsedAt = &now
+		key.LastUsedIP = &ipAddress
+		_ = s.DB.Model(&models.APIKey{}).Where("id = ?", key.ID).
+			Updates(map[string]any{"last_used_at": now, "last_used_ip": ipAddress}).Error
+	}
+
+	return &key, &user, nil
+}
+
+func setAPIKeyAuth(c echo.Context, key *models.APIKey, user *models.User) {
+	c.Set("api_key", key)
+	c.Set("user", user)
+	c.Set("user_id", user.ID)
+	c.Set("user_email", user.Email)
+}
+
+// currentAPIKey returns the API key the request was authenticated with, or nil for login sessions
+func currentAPIKey(c echo.Context) *models.APIKey {
+	key, _ := c.Get("api_key").(*models.APIKey)
+	return key
+}
+
+// RequireScope limits a route to API keys holding the scope. Requests
+// authenticated with a login session are not restricted.
+func (s *Server) RequireScope(scope string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if key := currentAPIKey(c); key != nil && !key.HasScope(scope) {
+				return c.JSON(http.StatusForbidden, map[string]any{
+					"success": false,
+					"message": "API key is missing the '" + scope + "' scope",
+				})
+			}
+			return next(c)
+		}
+	}
+}
+
+// SessionOnlyMiddleware rejects API keys on routes that manage the account itself
+func (s *Server) SessionOnlyMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if currentAPIKey(c) != nil {
+				return c.JSON(http.StatusForbidden, map[string]any{
+					"success": false,
+					"message": "This endpoint requires a login session, not an API key",
+				})
+			}
+			return next(c)
+		}
+	}
+}
+
+// ListAPIKeys godoc
+// @Summary List API keys
+// @Description List the authenticated user's API keys. The secret part of a key is never returned after creation.
+// @Tags API Keys
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "API keys"
+// @Failure 401 {object} simpleResponse
+// @Router /auth/api-keys [get]
+func (s *Server) ListAPIKeys(c echo.Context) error {
+	userID := currentUserID(c)
+	if userID == 0 {
+		return unauthenticated(c)
+	}
+
+	var keys []models.APIKey
+	if err := s.DB.Where("user_id = ? AND revoked_at IS NULL", userID).Order("created_at DESC").Find(&keys).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to load API keys."})
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{"success": true, "api_keys": keys})
+}
+
+type createAPIKeyRequest struct {
+	Name          string   `json:"name" example:"Nightly export" binding:"required"`
+	Scopes        []string `json:"scopes" example:"read,submit" binding:"required"`
+	ExpiresInDays *int     `json:"expires_in_days" example:"90"` // omit for a key that does not expire
+}
+
+// CreateAPIKey godoc
+// @Summary Create an API key
+// @Description Create an API key for scripts. Scopes: read, submit, billing. The key is returned once and cannot be retrieved later.
+// @Tags API Keys
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body createAPIKeyRequest true "Key name, scopes and optional expiry"
+// @Success 201 {object} map[string]interface{} "API key created"
+// @Failure 400 {object} simpleResponse
+// @Failure 401 {object} simpleResponse
+// @Router /auth/api-keys [post]
+func (s *Server) CreateAPIKey(c echo.Context) error {
+	userID := currentUserID(c)
+	if userID == 0 {
+		return unauthenticated(c)
+	}
+	var req createAPIKeyRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Invalid payload"})
+	}
+
+	name := utils.SanitizeString(req.Name)
+	if name == "" || len(name) > 100 {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Name is required and must be at most 100 characters."})
+	}
+
+	scopes, err := normalizeScopes(req.Scopes)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: err.Error()})
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresInDays != nil {
+		if *req.ExpiresInDays < 1 || *req.ExpiresInDays > 365 {
+			return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "expires_in_days must be between 1 and 365."})
+		}
+		t := time.Now().UTC().AddDate(0, 0, *req.ExpiresInDays)
+		expiresAt = &t
+	}
+
+	var count int64
+	s.DB.Model(&models.APIKey{}).Where("user_id = ? AND revoked_at IS NULL", userID).Count(&count)
+	if count >= maxAPIKeysPerUser {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "API key limit reached. Revoke an unused key first."})
+	}
+
+	secret, err := utils.GenerateToken(24)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to create API key."})
+	}
+	rawKey := apiKeyPrefix + secret
+	key := models.APIKey{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    rawKey[:apiKeyDisplayLength],
+		KeyHash:   utils.HashToken(rawKey),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}
+	if err := s.DB.Create(&key).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to create API key."})
+	}
+
+	return c.JSON(http.StatusCreated, map[string]any{
+		"success": true,
+		"message": "API key created. Copy it now; it will not be shown again.",
+		"key":     rawKey,
+		"api_key": key,
+	})
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke an API key
+// @Description Revoke one of the authenticated user's API keys. Scripts using it stop working immediately.
+// @Tags API Keys
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "API key ID"
+// @Success 200 {object} simpleResponse
+// @Failure 400 {object} simpleResponse
+// @Failure 404 {object} simpleResponse
+// @Router /auth/api-keys/{id} [delete]
+func (s *Server) RevokeAPIKey(c echo.Context) error {
+	userID := currentUserID(c)
+	if userID == 0 {
+		return unauthenticated(c)
+	}
+	keyID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Invalid API key ID"})
+	}
+
+	res := s.DB.Model(&models.APIKey{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", keyID, userID).
+		Update("revoked_at", time.Now().UTC())
+	if res.Error != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to revoke API key."})
+	}
+	if res.RowsAffected == 0 {
+		return c.JSON(http.StatusNotFound, simpleResponse{Success: false, Message: "API key not found"})
+	}
+
+	return c.JSON(http.StatusOK, simpleResponse{Success: true, Message: "API key revoked."})
+}
+
+// normalizeScopes validates requested scopes and removes duplicates
+func normalizeScopes(requested []string) ([]string, error) {
+	seen := make(map[string]bool)
+	scopes := make([]string, 0, len(requested))
+	for _, scope := range requested {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		valid := false
+		for _, allowed := range validAPIKeyScopes {
+			if scope == allowed {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, errors.New("Invalid scope '" + scope + "'. Allowed scopes: " + strings.Join(validAPIKeyScopes, ", ") + ".")
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	if len(scopes) == 0 {
+		return nil, errors.New("At least one scope is required.")
+	}
+	return scopes, nil
+}
@@ -2,16 +2,15 @@ package server
 
 import (
 	"crypto/rand"
-	"crypto/tls"
+	"errors"
 	"fmt"
-	"net"
 	"net/http"
-	"net/smtp"
 	"strings"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 
 	"github.com/frontinsight/backend/internal/models"
 	"github.com/frontinsight/backend/internal/utils"
@@ -91,11 +90,7 @@ func (s *Server) Signup(c echo.Context) error {
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to create user."})
 	}
-	// IP from headers
-	ip := c.Request().Header.Get("X-Forwarded-For")
-	if ip == "" {
-		ip = c.RealIP()
-	}
+	ip := s.getClientIP(c)
 	// Create user (always unverified - requires OTP verification)
 	user := models.User{
 		Email:        req.Email,
@@ -127,15 +122,12 @@ func (s *Server) Signup(c echo.Context) error {
 	if err := s.DB.Create(&verification).Error; err != nil {
 		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to store verification code."})
 	}
-	// Send email
+	// Queue the email; the outbox keeps retrying if the mail server is down
 	if err := s.sendVerificationEmail(req.Email, code); err != nil {
-		// Log detailed error for debugging
-		fmt.Printf("ERROR: Failed to send verification email to %s: %v\n", req.Email, err)
-		fmt.Printf("SMTP Config: Host=%s, Port=%d, User=%s\n", s.Cfg.SMTPHost, s.Cfg.SMTPPort, s.Cfg.SMTPUser)
-		// Return error to user so they know email failed
+		fmt.Printf("ERROR: Failed to queue verification email to %s: %v\n", req.Email, err)
 		return c.JSON(http.StatusInternalServerError, map[string]any{
 			"success": false,
-			"message": fmt.Sprintf("Failed to send verification email. Please contact support. Error: %v", err),
+			"message": "Failed to send verification email. Please use resend verification to try again.",
 			"email":   req.Email,
 		})
 	}
@@ -198,27 +190,28 @@ func (s *Server) VerifyEmail(c echo.Context) error {
 	// Update user as verified
 	user.IsVerified = true
 
-	// Generate JWT token for automatic login
-	token, err := utils.GenerateJWT(user.ID, user.Email, s.Cfg.JWTSecret, s.Cfg.JWTExpiry)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to generate authentication token."})
-	}
-
-	// Update user's last login time and session token
+	// Update user's last login time
 	now := time.Now().UTC()
 	user.LastLoginAt = &now
-	user.SessionToken = &token
 
 	// Save all user updates
 	if err := s.DB.Save(&user).Error; err != nil {
 		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to update user session."})
 	}
 
+	// Issue access and refresh tokens for automatic login
+	tokens, err := s.startSession(s.DB, &user, c)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to generate authentication token."})
+	}
+
 	// Return login response (same format as Login endpoint)
 	return c.JSON(http.StatusOK, map[string]any{
-		"success": true,
-		"message": "Email verified successfully! You have been logged in.",
-		"token":   token,
+		"success":       true,
+		"message":       "Email verified successfully! You have been logged in.",
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
 		"user": map[string]any{
 			"id":       user.ID,
 			"email":    user.Email,
@@ -262,16 +255,13 @@ func (s *Server) ResendVerification(c echo.Context) error {
 	if err := s.DB.Create(&v).Error; err != nil {
 		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to store verification code."})
 	}
-	// Send email using the updated plain SMTP logic
 	if err := s.sendVerificationEmail(req.Email, code); err != nil {
-		// Log detailed error for debugging
-		fmt.Printf("ERROR: Failed to send verification email to %s: %v\n", req.Email, err)
-		fmt.Printf("SMTP Config: Host=%s, Port=%d, User=%s\n", s.Cfg.SMTPHost, s.Cfg.SMTPPort, s.Cfg.SMTPUser)
+		fmt.Printf("ERROR: Failed to queue verification email to %s: %v\n", req.Email, err)
 		// Clean up the verification code if email failed
 		_ = s.DB.Delete(&v).Error
 		return c.JSON(http.StatusInternalServerError, simpleResponse{
 			Success: false,
-			Message: fmt.Sprintf("Failed to send verification email. Please try again. Error: %v", err),
+			Message: "Failed to send verification email. Please try again.",
 		})
 	}
 	return c.JSON(http.StatusOK, simpleResponse{Success: true, Message: "New verification code sent to your email."})
@@ -284,7 +274,7 @@ type loginRequest struct {
 
 // Login godoc
 // @Summary User login
-// @Description Authenticate user and return JWT token
+// @Description Authenticate user and return JWT token. When two-factor authentication is enabled the response has mfa_required and an mfa_token to complete at /login/2fa.
 // @Tags Authentication
 // @Accept json
 // @Produce json
@@ -292,6 +282,7 @@ type loginRequest struct {
 // @Success 200 {object} map[string]interface{} "Login successful"
 // @Failure 400 {object} simpleResponse
 // @Failure 401 {object} simpleResponse
+// @Failure 429 {object} simpleResponse "Too many failed attempts; see Retry-After"
 // @Router /login [post]
 func (s *Server) Login(c echo.Context) error {
 	var req loginRequest
@@ -311,24 +302,33 @@ func (s *Server) Login(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Invalid email address format."})
 	}
 
-	// Get client IP for rate limiting
-	ipAddress := c.Request().Header.Get("X-Forwarded-For")
-	if ipAddress == "" {
-		ipAddress = c.RealIP()
-	}
-
-	// Check for too many failed attempts
-	if s.GetFailedAttemptsCount(email, ipAddress) >= 5 {
-		return c.JSON(http.StatusTooManyRequests, simpleResponse{Success: false, Message: "Too many failed login attempts. Please try again later."})
+	// Slow down IPs that keep failing, whichever accounts they try
+	ipAddress := s.getClientIP(c)
+	if wait := s.loginIPRetryAfter(ipAddress); wait > 0 {
+		return tooManyLoginAttempts(c, wait)
 	}
 
 	var user models.User
 	if err := s.DB.Where("email = ?", email).First(&user).Error; err != nil || user.ID == 0 {
+		// Take as long as a wrong password, so timing does not reveal which
+		// addresses are registered
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(password))
 		s.RecordLoginAttempt(email, ipAddress, false)
 		return c.JSON(http.StatusUnauthorized, map[string]any{"success": false, "message": "Invalid email or password."})
 	}
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+	passwordErr := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+	if wait := accountRetryAfter(&user); wait > 0 {
+		s.RecordLoginAttempt(email, ipAddress, false)
+		// Only someone who knows the password learns that the account is
+		// locked; anyone else gets the answer an unknown address gets
+		if passwordErr == nil {
+			return tooManyLoginAttempts(c, wait)
+		}
+		return c.JSON(http.StatusUnauthorized, map[string]any{"success": false, "message": "Invalid email or password."})
+	}
+	if passwordErr != nil {
 		s.RecordLoginAttempt(email, ipAddress, false)
+		s.recordAccountFailure(&user, ipAddress)
 		return c.JSON(http.StatusUnauthorized, map[string]any{"success": false, "message": "Invalid email or password."})
 	}
 	if !user.IsVerified {
@@ -341,29 +341,52 @@ func (s *Server) Login(c echo.Context) error {
 		})
 	}
 
-	// Generate JWT token
-	token, err := utils.GenerateJWT(user.ID, user.Email, s.Cfg.JWTSecret, s.Cfg.JWTExpiry)
+	// Accounts with two-factor authentication must present a code before a session starts
+	if user.TOTPEnabled {
+		mfaToken, err := s.issueMFAToken(&user)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to generate authentication token."})
+		}
+		return c.JSON(http.StatusOK, map[string]any{
+			"success":      true,
+			"message":      "Enter the code from your authenticator app.",
+			"mfa_required": true,
+			"mfa_token":    mfaToken,
+		})
+	}
+
+	return s.completeLogin(c, &user, ipAddress)
+}
+
+// completeLogin starts a session for a user who passed every login step and
+// returns the login response
+func (s *Server) completeLogin(c echo.Context, user *models.User, ipAddress string) error {
+	// Start a new session for this device; other devices stay signed in
+	tokens, err := s.startSession(s.DB, user, c)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to generate authentication token."})
 	}
 
-	// Update user's last login time and session token
+	// Update user's last login time and clear any failed attempts
 	now := time.Now().UTC()
 	user.LastLoginAt = &now
-	user.SessionToken = &token
-	if err := s.DB.Save(&user).Error; err != nil {
+	user.FailedLoginCount = 0
+	user.LockedUntil = nil
+	if err := s.DB.Save(user).Error; err != nil {
 		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to update user session."})
 	}
 
 	// Record successful login attempt
-	s.RecordLoginAttempt(email, ipAddress, true)
+	s.RecordLoginAttempt(user.Email, ipAddress, true)
 
-	// Removed automatic polling - users will manually refresh job status
+	// No automatic polling - QL2 webhooks and manual refreshes update job status, and open tabs hear about it over /events/stream
 
 	return c.JSON(http.StatusOK, map[string]any{
-		"success": true,
-		"message": "Login successful.",
-		"token":   token,
+		"success":       true,
+		"message":       "Login successful.",
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
 		"user": map[string]any{
 			"id":       user.ID,
 			"email":    user.Email,
@@ -374,6 +397,47 @@ func (s *Server) Login(c echo.Context) error {
 	})
 }
 
+type refreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" example:"9b1f0c..." binding:"required"`
+}
+
+// RefreshToken godoc
+// @Summary Refresh access token
+// @Description Exchange a refresh token for a new access token and a new refresh token. Each refresh token can be used once; reusing one signs out the session it belongs to.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body refreshTokenRequest true "Refresh token"
+// @Success 200 {object} map[string]interface{} "New token pair"
+// @Failure 400 {object} simpleResponse
+// @Failure 401 {object} simpleResponse
+// @Router /auth/refresh [post]
+func (s *Server) RefreshToken(c echo.Context) error {
+	var req refreshTokenRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Invalid payload"})
+	}
+	rawRefresh := strings.TrimSpace(req.RefreshToken)
+	if rawRefresh == "" {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Refresh token is required."})
+	}
+
+	_, tokens, err := s.rotateRefreshToken(rawRefresh, s.getClientIP(c))
+	if err != nil {
+		if errors.Is(err, errInvalidRefreshToken) || errors.Is(err, errRefreshTokenReused) {
+			return c.JSON(http.StatusUnauthorized, simpleResponse{Success: false, Message: "Session expired or invalid. Please log in again."})
+		}
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to refresh session."})
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"success":       true,
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+	})
+}
+
 // Logout godoc
 // @Summary User logout
 // @Description Logout user and invalidate session
@@ -385,16 +449,13 @@ func (s *Server) Login(c echo.Context) error {
 // @Failure 401 {object} simpleResponse
 // @Router /logout [post]
 func (s *Server) Logout(c echo.Context) error {
-	user := c.Get("user").(*models.User)
-
-	// Clear session token
-	user.SessionToken = nil
-	if err := s.DB.Save(&user).Error; err != nil {
-		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to logout."})
+	// End only the session behind this access token
+	if claims := currentClaims(c); claims != nil {
+		if err := s.revokeSession(s.DB, claims.SessionID); err != nil {
+			return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to logout."})
+		}
 	}
 
-	// Session tracking removed - no longer needed for polling
-
 	return c.JSON(http.StatusOK, simpleResponse{Success: true, Message: "Logged out successfully."})
 }
 
@@ -409,34 +470,83 @@ func (s *Server) Logout(c echo.Context) error {
 // @Failure 401 {object} simpleResponse
 // @Router /profile [get]
 func (s *Server) GetProfile(c echo.Context) error {
-	user := c.Get("user").(*models.User)
-
+	user, err := s.currentUser(c)
+	if err != nil {
+		return unauthenticated(c)
+	}
 	return c.JSON(http.StatusOK, map[string]any{
 		"success": true,
-		"user": map[string]any{
-			"id":            user.ID,
-			"email":         user.Email,
-			"name":          user.Name,
-			"role":          user.Role,
-			"is_verified":   user.IsVerified,
-			"city":          user.City,
-			"state":         user.State,
-			"country":       user.Country,
-			"mobile_number": user.MobileNumber,
-			"timezone":      user.Timezone,
-			"business_type": user.BusinessType,
-			"company":       user.Company,
-			"balance":       user.Balance,
-			"frozen_amount": user.FrozenAmount,
-			"last_login_at": user.LastLoginAt,
-			"created_at":    user.CreatedAt,
-		},
+		"user":    s.profilePayload(c, user),
 	})
 }
 
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password" example:"OldPassword@123" binding:"required"`
+	NewPassword     string `json:"new_password" example:"NewPassword@123" binding:"required"`
+}
+
+// ChangePassword godoc
+// @Summary Change password
+// @Description Change the authenticated user's password. The current password must be supplied, and every existing session is invalidated on success.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body changePasswordRequest true "Current and new password"
+// @Success 200 {object} simpleResponse
+// @Failure 400 {object} simpleResponse
+// @Failure 401 {object} simpleResponse
+// @Failure 500 {object} simpleResponse
+// @Router /auth/change-password [post]
 func (s *Server) ChangePassword(c echo.Context) error {
-	// For parity with current frontend, return success without implementation here
-	return c.JSON(http.StatusOK, simpleResponse{Success: true, Message: "Password changed successfully."})
+	var req changePasswordRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Invalid payload"})
+	}
+	currentPassword := utils.SanitizeString(req.CurrentPassword)
+	newPassword := utils.SanitizeString(req.NewPassword)
+
+	if currentPassword == "" || newPassword == "" {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Current and new password are required."})
+	}
+
+	user, err := s.currentUser(c)
+	if err != nil {
+		return unauthenticated(c)
+	}
+
+	// Verify the current password before allowing any change
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(currentPassword)); err != nil {
+		return c.JSON(http.StatusUnauthorized, simpleResponse{Success: false, Message: "Current password is incorrect."})
+	}
+
+	// Validate password strength
+	if valid, msg := utils.ValidatePassword(newPassword); !valid {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: msg})
+	}
+
+	if currentPassword == newPassword {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "New password must be different from the current password."})
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), 12)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to change password."})
+	}
+
+	// Store the new hash and revoke every session so issued tokens stop working
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		user.Password = string(hash)
+		if err := tx.Save(user).Error; err != nil {
+			return err
+		}
+		return s.revokeAllSessions(tx, user.ID)
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to change password."})
+	}
+
+	return c.JSON(http.StatusOK, simpleResponse{Success: true, Message: "Password changed successfully. Please log in again."})
 }
 
 type updateTimezoneRequest struct {
@@ -471,11 +581,24 @@ func (s *Server) UpdateTimezone(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Invalid timezone format"})
 	}
 
-	user := c.Get("user").(*models.User)
+	user, err := s.currentUser(c)
+	if err != nil {
+		return unauthenticated(c)
+	}
 
-	// Update user's timezone
-	user.Timezone = &req.Timezone
-	if err := s.DB.Save(&user).Error; err != nil {
+	// Update user's timezone and keep the old value in the profile history
+	change := s.newProfileChange(c, user.ID, "timezone", user.Timezone, &req.Timezone)
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		user.Timezone = &req.Timezone
+		if err := tx.Save(user).Error; err != nil {
+			return err
+		}
+		if change != nil {
+			return tx.Create(change).Error
+		}
+		return nil
+	})
+	if err != nil {
 		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to update timezone"})
 	}
 
@@ -537,112 +660,11 @@ func (s *Server) SendVerificationEmail(email, code string) error {
 }
 
 func (s *Server) sendVerificationEmail(email, code string) error {
-	// Build simple HTML body
-	subject := "Email Verification - Front Insight"
-	body := fmt.Sprintf(`<html><body><h2>Email Verification</h2><p>Your verification code is: <strong style="font-size:24px;color:#1976d2;">%s</strong></p><p>This code will expire in 10 minutes.</p></body></html>`, code)
-	msg := "MIME-Version: 1.0\r\n" +
-		"Content-Type: text/html; charset=\"UTF-8\"\r\n" +
-		"Subject: " + subject + "\r\n" +
-		"From: " + s.Cfg.SMTPUser + "\r\n" +
-		"To: " + email + "\r\n\r\n" +
-		body
-
-	addr := net.JoinHostPort(s.Cfg.SMTPHost, fmt.Sprintf("%d", s.Cfg.SMTPPort))
-
-	// Determine connection type based on port
-	useTLS := s.Cfg.SMTPPort == 465
-	useSTARTTLS := s.Cfg.SMTPPort == 587
-	usePlainSMTP := s.Cfg.SMTPPort == 25 // Plain SMTP without TLS/SSL (like Python example)
-
-	var client *smtp.Client
-	var err error
-
-	if useTLS {
-		// SSL/TLS connection (port 465)
-		tlsConfig := &tls.Config{
-			ServerName:         s.Cfg.SMTPHost,
-			InsecureSkipVerify: s.Cfg.DevMode,
-		}
-		conn, err := tls.Dial("tcp", addr, tlsConfig)
-		if err != nil {
-			return fmt.Errorf("failed to connect to SMTP server with TLS: %w", err)
-		}
-		defer conn.Close()
-
-		client, err = smtp.NewClient(conn, s.Cfg.SMTPHost)
-		if err != nil {
-			return fmt.Errorf("failed to create SMTP client: %w", err)
-		}
-	} else {
-		// Plain connection
-		conn, err := net.DialTimeout("tcp", addr, 10*time.Second)
-		if err != nil {
-			return fmt.Errorf("failed to connect to SMTP server: %w", err)
-		}
-		defer conn.Close()
-
-		client, err = smtp.NewClient(conn, s.Cfg.SMTPHost)
-		if err != nil {
-			return fmt.Errorf("failed to create SMTP client: %w", err)
-		}
-
-		// Use STARTTLS only for port 587 (not for port 25)
-		if useSTARTTLS {
-			if ok, _ := client.Extension("STARTTLS"); ok {
-				tlsConfig := &tls.Config{
-					ServerName:         s.Cfg.SMTPHost,
-					InsecureSkipVerify: s.Cfg.DevMode,
-				}
-				if err = client.StartTLS(tlsConfig); err != nil {
-					return fmt.Errorf("failed to start TLS: %w", err)
-				}
-			} else {
-				return fmt.Errorf("STARTTLS not supported on port 587 (required for authentication)")
-			}
-		}
-		// For port 25, use plain SMTP without TLS/STARTTLS (like Python example)
-	}
-
-	defer client.Close()
-
-	// Authenticate only if not using plain SMTP on port 25
-	// Port 25 plain SMTP typically doesn't require authentication (like Python example)
-	if !usePlainSMTP && s.Cfg.SMTPUser != "" && s.Cfg.SMTPPass != "" {
-		auth := smtp.PlainAuth("", s.Cfg.SMTPUser, s.Cfg.SMTPPass, s.Cfg.SMTPHost)
-		if err = client.Auth(auth); err != nil {
-			return fmt.Errorf("SMTP authentication failed: %w", err)
-		}
-	}
-
-	// Set sender
-	if err = client.Mail(s.Cfg.SMTPUser); err != nil {
-		return fmt.Errorf("failed to set sender: %w", err)
-	}
-
-	// Set recipient
-	if err = client.Rcpt(email); err != nil {
-		return fmt.Errorf("failed to set recipient: %w", err)
-	}
-
-	// Send email data
-	writer, err := client.Data()
-	if err != nil {
-		return fmt.Errorf("failed to open data writer: %w", err)
-	}
-
-	if _, err = writer.Write([]byte(msg)); err != nil {
-		writer.Close()
-		return fmt.Errorf("failed to write email data: %w", err)
-	}
-
-	if err = writer.Close(); err != nil {
-		return fmt.Errorf("failed to close data writer: %w", err)
-	}
-
-	// Quit
-	if err = client.Quit(); err != nil {
-		return fmt.Errorf("failed to quit SMTP session: %w", err)
-	}
+	return s.sendEmail(email, "verification", map[string]any{"Code": code, "Minutes": 10})
+}
 
-	return nil
+// sendEmail queues an email rendered from one of the mailer templates. The
+// outbox worker delivers it and retries while the mail server is unavailable.
+func (s *Server) sendEmail(to, template string, data map[string]any) error {
+	return s.mail.Enqueue(s.DB, to, template, data)
 }
@@ -3,11 +3,8 @@ package server
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"net/http"
-	"net/url"
-	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -19,28 +16,10 @@ import (
 	"gorm.io/gorm"
 
 	"github.com/frontinsight/backend/internal/models"
+	"github.com/frontinsight/backend/internal/ql2"
 	"github.com/frontinsight/backend/internal/services"
 )
 
-// mapping similar to Python create_input_new.py / server.py
-var siteFullNameToCode = map[string]string{
-	"EXPEDIA":      "EXP",
-	"PRICELINE":    "PL",
-	"MARRIOTT":     "MC",
-	"CHOICEHOTELS": "CH",
-	"BESTWESTERN":  "BW",
-	"REDROOF":      "RR",
-	"ACCORHOTELS":  "RT",
-}
-
-func toScriptCode(name string) string {
-	u := strings.ToUpper(strings.TrimSpace(name))
-	if code, ok := siteFullNameToCode[u]; ok {
-		return code
-	}
-	return name
-}
-
 // Use models.JobData instead of local jobData
 type jobData = models.JobData
 
@@ -49,11 +28,12 @@ type saveMultiFormRequest struct {
 	Action         string    `json:"action" example:"start" enums:"start,save,schedule"`
 	ScheduleTS     *string   `json:"scheduleTs" example:"202509181400"`                    // YYYYMMDDHHMI format for IST timezone
 	CollectionName *string   `json:"collection_name" example:"My Hotel Search Collection"` // User-provided collection name
+	OrganizationID *uint     `json:"organization_id" example:"3"`                          // Optional: run against an organization wallet (owner or manager)
 }
 
 // SaveMultiForm godoc
 // @Summary Submit search jobs
-// @Description Submit hotel search jobs with options to start immediately, save as collection, or schedule for later
+// @Description Submit hotel search jobs with options to start immediately, save as collection, or schedule for later. With organization_id the collection, search and schedule belong to the organization and searches are charged to its wallet. Starting or scheduling jobs that cannot be sent to QL2 is refused with the list of them under skipped.
 // @Tags Searches
 // @Accept json
 // @Produce json
@@ -68,14 +48,18 @@ func (s *Server) SaveMultiForm(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]any{"success": false, "message": "Invalid payload"})
 	}
 	// Get user info from authenticated context
-	user := c.Get("user").(*models.User)
-	userIDStr := user.Email // Use email as UserID for foreign key constraint
+	userIDStr := currentUserEmail(c) // Use email as UserID for foreign key constraint
 	if len(req.Jobs) == 0 {
 		return c.JSON(http.StatusBadRequest, map[string]any{"success": false, "message": "No jobs provided"})
 	}
 	if req.Action == "" {
 		req.Action = "start"
 	}
+	if req.OrganizationID != nil {
+		if _, err := s.orgMembership(currentUserID(c), *req.OrganizationID, models.OrgRoleManager); err != nil {
+			return orgError(c, err)
+		}
+	}
 
 	// Validate collection name (required)
 	if req.CollectionName == nil || strings.TrimSpace(*req.CollectionName) == "" {
@@ -90,22 +74,28 @@ func (s *Server) SaveMultiForm(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]any{"success": false, "message": "Collection name cannot exceed 255 characters"})
 	}
 
-	// Check for duplicate collection name for this user
+	// Check for duplicate collection name for this user or organization
 	var existingCollection models.Collection
-	if err := s.DB.Where("user_id = ? AND name = ?", userIDStr, collectionName).First(&existingCollection).Error; err == nil {
+	if err := collectionScope(s.DB, userIDStr, req.OrganizationID).Where("name = ?", collectionName).First(&existingCollection).Error; err == nil {
 		return c.JSON(http.StatusBadRequest, map[string]any{"success": false, "message": "A collection with this name already exists. Please choose a different name."})
 	}
 
 	if hasDuplicateJobs(req.Jobs) {
 		return c.JSON(http.StatusBadRequest, map[string]any{"success": false, "message": "Collection contains duplicate jobs"})
 	}
-	isDuplicate := hasDuplicateCollection(s.DB, userIDStr, req.Jobs, 0)
+	// Jobs that are started or scheduled are charged, so all of them must reach QL2
+	if req.Action != "save" {
+		if _, skipped := ql2.BuildRows(req.Jobs, userIDStr, collectionName); len(skipped) > 0 {
+			return skippedJobsError(c, skipped)
+		}
+	}
+	isDuplicate := hasDuplicateCollection(s.DB, userIDStr, req.OrganizationID, req.Jobs, 0)
 	switch req.Action {
 	case "save":
 		if isDuplicate {
 			return c.JSON(http.StatusBadRequest, map[string]any{"success": false, "message": "A collection with identical jobs already exists"})
 		}
-		col, err := s.createCollection(userIDStr, req.Jobs, "saved", nil, collectionName)
+		col, err := s.createCollection(userIDStr, req.OrganizationID, req.Jobs, "saved", nil, collectionName)
 		if err != nil {
 			return c.JSON(http.StatusInternalServerError, map[string]any{"success": false, "message": err.Error()})
 		}
@@ -143,57 +133,66 @@ func (s *Server) SaveMultiForm(c echo.Context) error {
 			Scheduled:      false,
 			Amount:         searchAmount,
 			FrozenAmount:   0.00, // Will be set when frozen
-		}
-		if err := s.DB.Create(&search).Error; err != nil {
-			return c.JSON(http.StatusInternalServerError, map[string]any{"success": false, "message": "Failed to create search: " + err.Error()})
+			OrganizationID: req.OrganizationID,
 		}
 
-		// Create search items
-		for _, j := range req.Jobs {
-			checkIn, _ := time.Parse("2006-01-02", j.CheckInDate)
-			checkOut, _ := time.Parse("2006-01-02", j.CheckOutDate)
-			// Ensure POS values are plain strings, not JSON-encoded
-			posValues := make([]string, len(j.Website.POS))
-			for i, pos := range j.Website.POS {
-				posValues[i] = pos
+		// Create the search, freeze its amount and queue the QL2 job together,
+		// so a search never holds money without a job that will settle it
+		var announce func()
+		var freezeErr error
+		err = s.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&search).Error; err != nil {
+				return fmt.Errorf("failed to create search: %w", err)
 			}
-			_ = s.DB.Create(&models.SearchItem{
-				SearchID:     search.ID,
-				Location:     j.Location,
-				CheckInDate:  checkIn,
-				CheckOutDate: checkOut,
-				Adults:       j.Adults,
-				StarRating:   j.StarRating,
-				Website:      j.Website.Name,
-				POS:          pq.StringArray(posValues),
-				Amount:       0.00, // Individual item amount not used
-			}).Error
-		}
 
-		// Check balance and freeze amount before submitting to QL2
-		if err := services.CheckBalanceAndFreeze(userIDStr, searchAmount, search.ID, s.DB); err != nil {
-			// Delete the search if freeze fails
-			s.DB.Delete(&search)
-			return c.JSON(http.StatusBadRequest, map[string]any{"success": false, "message": err.Error()})
-		}
+			// Create search items
+			for _, j := range req.Jobs {
+				checkIn, _ := time.Parse("2006-01-02", j.CheckInDate)
+				checkOut, _ := time.Parse("2006-01-02", j.CheckOutDate)
+				// Ensure POS values are plain strings, not JSON-encoded
+				posValues := make([]string, len(j.Website.POS))
+				for i, pos := range j.Website.POS {
+					posValues[i] = pos
+				}
+				if err := tx.Create(&models.SearchItem{
+					SearchID:     search.ID,
+					Location:     j.Location,
+					CheckInDate:  checkIn,
+					CheckOutDate: checkOut,
+					Adults:       j.Adults,
+					StarRating:   j.StarRating,
+					Website:      j.Website.Name,
+					POS:          pq.StringArray(posValues),
+					Amount:       0.00, // Individual item amount not used
+				}).Error; err != nil {
+					return fmt.Errorf("failed to create search items: %w", err)
+				}
+			}
 
-		// Submit to QL2 (after successful freeze)
-		err = s.submitCollectionToQL2(jobName, req.Jobs, userIDStr, collectionName)
+			// Check balance and freeze amount before submitting to QL2
+			if announce, freezeErr = services.FreezeForSearch(tx, userIDStr, searchAmount, search.ID); freezeErr != nil {
+				return freezeErr
+			}
+			return s.queueQL2Submission(tx, &search, req.Jobs)
+		})
+		if freezeErr != nil {
+			return c.JSON(http.StatusBadRequest, map[string]any{"success": false, "message": freezeErr.Error()})
+		}
 		if err != nil {
-			// If QL2 submission fails, we should unfreeze the amount
-			// For now, just return error - the frozen amount will be handled when search is marked as failed
 			return c.JSON(http.StatusInternalServerError, map[string]any{"success": false, "message": "Failed to submit job: " + err.Error()})
 		}
+		announce()
+		s.submissions.Wake()
 
 		if isDuplicate {
-			_ = s.updateLastRunForDuplicate(userIDStr, req.Jobs)
+			_ = s.updateLastRunForDuplicate(userIDStr, req.OrganizationID, req.Jobs)
 		} else {
-			_, _ = s.createCollection(userIDStr, req.Jobs, "submitted", ptr(now), collectionName)
+			_, _ = s.createCollection(userIDStr, req.OrganizationID, req.Jobs, "submitted", ptr(now), collectionName)
 		}
 		return c.JSON(http.StatusOK, map[string]any{"success": true, "message": "Job started and collection recorded"})
 	case "schedule":
 		// Create collection first
-		collection, err := s.createCollection(userIDStr, req.Jobs, "scheduled", nil, collectionName)
+		collection, err := s.createCollection(userIDStr, req.OrganizationID, req.Jobs, "scheduled", nil, collectionName)
 		if err != nil {
 			return c.JSON(http.StatusInternalServerError, map[string]any{"success": false, "message": err.Error()})
 		}
@@ -267,9 +266,9 @@ func (s *Server) SaveMultiForm(c echo.Context) error {
 	return c.JSON(http.StatusBadRequest, map[string]any{"success": false, "message": "Invalid action"})
 }
 
-func (s *Server) updateLastRunForDuplicate(userID string, jobs []jobData) error {
+func (s *Server) updateLastRunForDuplicate(userID string, orgID *uint, jobs []jobData) error {
 	var cols []models.Collection
-	if err := s.DB.Where("user_id = ?", userID).Find(&cols).Error; err != nil {
+	if err := collectionScope(s.DB, userID, orgID).Find(&cols).Error; err != nil {
 		return err
 	}
 	for _, col := range cols {
@@ -285,8 +284,8 @@ func (s *Server) updateLastRunForDuplicate(userID string, jobs []jobData) error
 	return nil
 }
 
-func (s *Server) createCollection(userID string, jobs []jobData, status string, lastRun *time.Time, collectionName string) (*models.Collection, error) {
-	col := models.Collection{UserID: userID, Name: collectionName, Description: ptr(fmt.Sprintf("Collection with %d jobs", len(jobs))), Status: status, LastRunAt: lastRun}
+func (s *Server) createCollection(userID string, orgID *uint, jobs []jobData, status string, lastRun *time.Time, collectionName string) (*models.Collection, error) {
+	col := models.Collection{UserID: userID, OrganizationID: orgID, Name: collectionName, Description: ptr(fmt.Sprintf("Collection with %d jobs", len(jobs))), Status: status, LastRunAt: lastRun}
 	if err := s.DB.Create(&col).Error; err != nil {
 		return nil, err
 	}
@@ -313,94 +312,61 @@ func (s *Server) createCollection(userID string, jobs []jobData, status string,
 	return &col, nil
 }
 
+// skippedJobsError refuses a submission with jobs QL2 cannot run, listing
+// them so the user can fix them. Every job is charged, so the others are not
+// sent on their own.
+func skippedJobsError(c echo.Context, skipped []ql2.SkippedJob) error {
+	return c.JSON(http.StatusBadRequest, map[string]any{
+		"success": false,
+		"message": "Some jobs cannot be sent to QL2: " + ql2.DescribeSkipped(skipped),
+		"skipped": skipped,
+	})
+}
+
 // Use models.SubmitOption instead of local submitOption
 type submitOption = models.SubmitOption
 
-func (s *Server) submitCollectionToQL2(jobName string, jobs []jobData, userID string, collectionName string, opts ...submitOption) error {
+// queueQL2Submission builds the QL2 job of a search and queues it in tx, the
+// transaction that creates the search and freezes its amount. Call
+// s.submissions.Wake once tx has committed.
+func (s *Server) queueQL2Submission(tx *gorm.DB, search *models.Search, jobs []jobData, opts ...submitOption) error {
 	if s.Cfg.QL2Username == "" || s.Cfg.QL2Password == "" {
 		return nil
 	}
+	userID := search.UserID
+	collectionName := ""
+	if search.CollectionName != nil {
+		collectionName = *search.CollectionName
+	}
 	var schedule string
 	for _, o := range opts {
 		o(&schedule)
 	}
-	lines := make([]string, 0)
-	for _, j := range jobs {
-		locParts := strings.Split(j.Location, ",")
-		for i := range locParts {
-			locParts[i] = strings.TrimSpace(locParts[i])
-		}
-		if len(locParts) < 2 {
-			continue
-		}
-		city := locParts[0]
-		country := locParts[len(locParts)-1]
-		state := ""
-		if len(locParts) == 3 {
-			state = locParts[1]
-		}
-		ci := strings.ReplaceAll(j.CheckInDate, "-", "")
-		co := strings.ReplaceAll(j.CheckOutDate, "-", "")
-
-		scriptCode := toScriptCode(j.Website.Name)
-		poses := j.Website.POS
-		if len(poses) == 0 {
-			poses = []string{""}
-		}
-		for _, pos := range poses {
-			row := fmt.Sprintf("%s,%s,%s,%s,%s,%s,,,,,15,%s,25,A,userId=%s&jobname=%s&,1,,,0,%d,,,,%s,,,", scriptCode, city, state, country, ci, co, j.StarRating, userID, collectionName, j.Adults, pos)
-			lines = append(lines, row)
-		}
-	}
-	if len(lines) == 0 {
-		return errors.New("no CSV rows generated for QL2 submission")
-	}
-	csv := strings.Join(lines, string(rune(10)))
-
-	// save this csv to a file
-	os.WriteFile("ql2_submission.csv", []byte(csv), 0644)
-	fmt.Println("Saved CSV to ql2_submission.csv")
-
-	// build endpoint: when scheduling, force startjob=n; else use configured start flag
-	startFlag := s.Cfg.QL2StartJob
-	if schedule != "" {
-		startFlag = "n"
-	}
-	base := fmt.Sprintf(
-		"http://client.ql2.com/submit?username=%s&password=%s&app=hotel&createorreplacejob=%s&startjob=%s&priority=high",
-		url.QueryEscape(s.Cfg.QL2Username),
-		url.QueryEscape(s.Cfg.QL2Password),
-		url.QueryEscape(jobName),
-		url.QueryEscape(startFlag),
-	)
-	if schedule != "" {
-		base += "&setschedule=" + url.QueryEscape(schedule)
+	rows, skipped := ql2.BuildRows(jobs, userID, collectionName)
+	if len(skipped) > 0 {
+		return &ql2.JobsError{Skipped: skipped}
 	}
-
-	req, _ := http.NewRequest(http.MethodPost, base, strings.NewReader(csv))
-	req.Header.Set("Content-Type", "text/plain")
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
+	csv, err := ql2.EncodeInput(rows)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
-		return fmt.Errorf("ql2 submit: status %d", resp.StatusCode)
-	}
 
-	// Note: Search entry creation is handled by the calling function
-	// This function only handles QL2 submission
-
-	return nil
+	// Scheduled jobs are stored at QL2 and started by its scheduler
+	return s.submissions.Enqueue(tx, search.ID, ql2.Submission{
+		JobName:  *search.JobName,
+		CSV:      csv,
+		Start:    schedule == "" && strings.EqualFold(s.Cfg.QL2StartJob, "y"),
+		Schedule: schedule,
+	})
 }
 
 // MyCollections godoc
 // @Summary Get user's collections
-// @Description Retrieve all collections for a specific user with optional filtering
+// @Description Retrieve all collections for a specific user with optional filtering. With organization_id, list the organization's collections instead.
 // @Tags Collections
 // @Produce json
 // @Param userId query string true "User ID (email)"
+// @Param organization_id query int false "Organization ID"
 // @Param location query string false "Filter by location"
 // @Param website query string false "Filter by website"
 // @Param checkInStart query string false "Check-in start date (YYYY-MM-DD)"
@@ -411,16 +377,18 @@ func (s *Server) submitCollectionToQL2(jobName string, jobs []jobData, userID st
 // @Failure 400 {object} map[string]interface{} "Bad request"
 // @Router /my-collections [get]
 func (s *Server) MyCollections(c echo.Context) error {
-	// Get user info from authenticated context
-	user := c.Get("user").(*models.User)
-	userIDStr := user.Email // Use email as UserID for foreign key constraint
 	locationFilter := strings.TrimSpace(c.QueryParam("location"))
 	websiteFilter := strings.TrimSpace(c.QueryParam("website"))
 	checkInStart := strings.TrimSpace(c.QueryParam("checkInStart"))
 	checkOutStart := strings.TrimSpace(c.QueryParam("checkOutStart"))
 
+	scope, err := s.ownerScope(c)
+	if err != nil {
+		return orgError(c, err)
+	}
+
 	var collections []models.Collection
-	if err := s.DB.Where("user_id = ?", userIDStr).Order("updated_at DESC").Find(&collections).Error; err != nil {
+	if err := scope.Order("updated_at DESC").Find(&collections).Error; err != nil {
 		return c.JSON(http.StatusOK, map[string]any{"success": true, "collections": []any{}})
 	}
 
@@ -477,16 +445,17 @@ func (s *Server) MyCollections(c echo.Context) error {
 		}
 
 		colData := map[string]any{
-			"id":             col.ID,
-			"name":           col.Name,
-			"description":    col.Description,
-			"status":         col.Status,
-			"scheduled_date": nil,
-			"last_run_at":    toISO(col.LastRunAt),
-			"created_at":     col.CreatedAt.Format(time.RFC3339),
-			"updated_at":     col.UpdatedAt.Format(time.RFC3339),
-			"search_count":   len(filtered),
-			"searches":       []any{},
+			"id":              col.ID,
+			"name":            col.Name,
+			"description":     col.Description,
+			"status":          col.Status,
+			"scheduled_date":  nil,
+			"last_run_at":     toISO(col.LastRunAt),
+			"created_at":      col.CreatedAt.Format(time.RFC3339),
+			"updated_at":      col.UpdatedAt.Format(time.RFC3339),
+			"organization_id": col.OrganizationID,
+			"search_count":    len(filtered),
+			"searches":        []any{},
 		}
 		for _, it := range filtered {
 			colData["searches"] = append(colData["searches"].([]any), map[string]any{
@@ -508,23 +477,24 @@ func (s *Server) MyCollections(c echo.Context) error {
 
 func (s *Server) GetCollection(c echo.Context) error {
 	id, _ := strconv.Atoi(c.Param("id"))
-	var col models.Collection
-	if err := s.DB.First(&col, id).Error; err != nil || col.ID == 0 {
-		return c.JSON(http.StatusNotFound, map[string]any{"success": false, "message": "Collection not found"})
+	col, err := s.ownedCollection(c, id, accessRead)
+	if err != nil {
+		return accessError(c, err, "Collection not found")
 	}
 	var items []models.CollectionItem
 	_ = s.DB.Where("collection_id = ?", col.ID).Find(&items).Error
 	resp := map[string]any{
-		"id":             col.ID,
-		"name":           col.Name,
-		"description":    col.Description,
-		"status":         col.Status,
-		"scheduled_date": nil,
-		"last_run_at":    toISO(col.LastRunAt),
-		"created_at":     col.CreatedAt.Format(time.RFC3339),
-		"updated_at":     col.UpdatedAt.Format(time.RFC3339),
-		"search_count":   len(items),
-		"searches":       []any{},
+		"id":              col.ID,
+		"name":            col.Name,
+		"description":     col.Description,
+		"status":          col.Status,
+		"scheduled_date":  nil,
+		"last_run_at":     toISO(col.LastRunAt),
+		"created_at":      col.CreatedAt.Format(time.RFC3339),
+		"updated_at":      col.UpdatedAt.Format(time.RFC3339),
+		"organization_id": col.OrganizationID,
+		"search_count":    len(items),
+		"searches":        []any{},
 	}
 	for _, it := range items {
 		resp["searches"] = append(resp["searches"].([]any), map[string]any{
@@ -543,6 +513,9 @@ func (s *Server) GetCollection(c echo.Context) error {
 
 func (s *Server) DeleteCollection(c echo.Context) error {
 	id, _ := strconv.Atoi(c.Param("id"))
+	if _, err := s.ownedCollection(c, id, accessWrite); err != nil {
+		return accessError(c, err, "Collection not found")
+	}
 	return s.DB.Transaction(func(tx *gorm.DB) error {
 		if err := tx.Where("collection_id = ?", id).Delete(&models.CollectionItem{}).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, map[string]any{"success": false, "message": err.Error()})
@@ -565,22 +538,15 @@ type updateCollectionRequest struct {
 
 func (s *Server) UpdateCollection(c echo.Context) error {
 	// Get user from context first
-	userInterface := c.Get("user")
-	if userInterface == nil {
+	userIDStr := currentUserEmail(c)
+	if userIDStr == "" {
 		return c.JSON(http.StatusUnauthorized, map[string]any{"success": false, "message": "User not authenticated"})
 	}
-	user := userInterface.(*models.User)
-	userIDStr := user.Email
 
 	id, _ := strconv.Atoi(c.Param("id"))
-	var col models.Collection
-	if err := s.DB.First(&col, id).Error; err != nil || col.ID == 0 {
-		return c.JSON(http.StatusNotFound, map[string]any{"success": false, "message": "Collection not found"})
-	}
-
-	// Verify ownership
-	if col.UserID != userIDStr {
-		return c.JSON(http.StatusForbidden, map[string]any{"success": false, "message": "User not authorized to update this collection"})
+	col, err := s.ownedCollection(c, id, accessWrite)
+	if err != nil {
+		return accessError(c, err, "Collection not found")
 	}
 
 	var req updateCollectionRequest
@@ -596,14 +562,14 @@ func (s *Server) UpdateCollection(c echo.Context) error {
 	if hasDuplicateJobs(req.Jobs) {
 		return c.JSON(http.StatusBadRequest, map[string]any{"success": false, "message": "Collection contains duplicate jobs"})
 	}
-	if hasDuplicateCollection(s.DB, col.UserID, req.Jobs, uint(id)) {
+	if hasDuplicateCollection(s.DB, col.UserID, col.OrganizationID, req.Jobs, uint(id)) {
 		return c.JSON(http.StatusBadRequest, map[string]any{"success": false, "message": "A collection with identical jobs already exists"})
 	}
 	// Update collection
 	col.Name = req.Name
 	col.Description = req.Description
 	col.UpdatedAt = s.TimezoneService.GetCurrentUTC()
-	if err := s.DB.Save(&col).Error; err != nil {
+	if err := s.DB.Save(col).Error; err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]any{"success": false, "message": err.Error()})
 	}
 	// Replace items
@@ -635,16 +601,35 @@ func (s *Server) UpdateCollection(c echo.Context) error {
 
 func (s *Server) SubmitCollection(c echo.Context) error {
 	id, _ := strconv.Atoi(c.Param("id"))
-	var col models.Collection
-	if err := s.DB.First(&col, id).Error; err != nil || col.ID == 0 {
-		return c.JSON(http.StatusNotFound, map[string]any{"success": false, "message": "Collection not found"})
+	col, err := s.ownedCollection(c, id, accessWrite)
+	if err != nil {
+		return accessError(c, err, "Collection not found")
+	}
+	// Organization collections can be run by any owner or manager and are
+	// charged to the organization wallet
+	submitter := col.UserID
+	if col.OrganizationID != nil {
+		submitter = currentUserEmail(c)
+	}
+
+	// Load items before submission
+	var items []models.CollectionItem
+	if err := s.DB.Where("collection_id = ?", col.ID).Find(&items).Error; err != nil {
+		items = []models.CollectionItem{}
+	}
+
+	// Convert items to jobs for price calculation
+	jobs := toJobsFromItems(items)
+	if _, skipped := ql2.BuildRows(jobs, submitter, col.Name); len(skipped) > 0 {
+		return skippedJobsError(c, skipped)
 	}
+
 	// Update status/last run
 	now := s.TimezoneService.GetCurrentUTC()
 	col.Status = "submitted"
 	col.LastRunAt = &now
 	col.UpdatedAt = now
-	_ = s.DB.Save(&col).Error
+	_ = s.DB.Save(col).Error
 
 	// Create job name for submission - include collection name
 	fileTimestamp := now.Format("20060102_150405")
@@ -652,15 +637,6 @@ func (s *Server) SubmitCollection(c echo.Context) error {
 	safeCollectionName := safeUserId(col.Name)
 	jobName := fmt.Sprintf("%s_collection_%s_%s", safeCollectionName, safeUserId(col.UserID), fileTimestamp)
 
-	// Load items before submission
-	var items []models.CollectionItem
-	if err := s.DB.Where("collection_id = ?", col.ID).Find(&items).Error; err != nil {
-		items = []models.CollectionItem{}
-	}
-
-	// Convert items to jobs for price calculation
-	jobs := toJobsFromItems(items)
-
 	// Calculate search amount
 	searchAmount, err := services.CalculateSearchAmountFromJobs(jobs, s.DB)
 	if err != nil {
@@ -678,7 +654,7 @@ func (s *Server) SubmitCollection(c echo.Context) error {
 	// Create search with calculated amount
 	collectionName := col.Name
 	search := models.Search{
-		UserID:         col.UserID,
+		UserID:         submitter,
 		JobName:        &jobName,
 		CollectionName: &collectionName,
 		Timestamp:      timestampStr,
@@ -686,52 +662,59 @@ func (s *Server) SubmitCollection(c echo.Context) error {
 		Scheduled:      false,
 		Amount:         searchAmount,
 		FrozenAmount:   0.00, // Will be set when frozen
-	}
-	if err := s.DB.Create(&search).Error; err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]any{"success": false, "message": "Failed to create search: " + err.Error()})
+		OrganizationID: col.OrganizationID,
 	}
 
-	// Create search items
-	for _, it := range items {
-		_ = s.DB.Create(&models.SearchItem{
-			SearchID:     search.ID,
-			Location:     it.Location,
-			CheckInDate:  it.CheckInDate,
-			CheckOutDate: it.CheckOutDate,
-			Adults:       it.Adults,
-			StarRating:   it.StarRating,
-			Website:      it.Website,
-			POS:          it.POS,
-			Amount:       0.00, // Individual item amount not used
-		}).Error
-	}
+	// Create the search, freeze its amount and queue the whole collection as
+	// one QL2 job together
+	var announce func()
+	var freezeErr error
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&search).Error; err != nil {
+			return fmt.Errorf("failed to create search: %w", err)
+		}
 
-	// Check balance and freeze amount before submitting to QL2
-	if err := services.CheckBalanceAndFreeze(col.UserID, searchAmount, search.ID, s.DB); err != nil {
-		// Delete the search if freeze fails
-		s.DB.Delete(&search)
-		return c.JSON(http.StatusBadRequest, map[string]any{"success": false, "message": err.Error()})
-	}
+		// Create search items
+		for _, it := range items {
+			if err := tx.Create(&models.SearchItem{
+				SearchID:     search.ID,
+				Location:     it.Location,
+				CheckInDate:  it.CheckInDate,
+				CheckOutDate: it.CheckOutDate,
+				Adults:       it.Adults,
+				StarRating:   it.StarRating,
+				Website:      it.Website,
+				POS:          it.POS,
+				Amount:       0.00, // Individual item amount not used
+			}).Error; err != nil {
+				return fmt.Errorf("failed to create search items: %w", err)
+			}
+		}
 
-	// Submit entire collection as one job (best-effort)
-	err = s.submitCollectionToQL2(jobName, jobs, col.UserID, col.Name)
+		// Check balance and freeze amount before submitting to QL2
+		if announce, freezeErr = services.FreezeForSearch(tx, submitter, searchAmount, search.ID); freezeErr != nil {
+			return freezeErr
+		}
+		return s.queueQL2Submission(tx, &search, jobs)
+	})
+	if freezeErr != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{"success": false, "message": freezeErr.Error()})
+	}
 	if err != nil {
-		// If QL2 submission fails, we should unfreeze the amount
-		// For now, just return error - the frozen amount will be handled when search is marked as failed
 		return c.JSON(http.StatusInternalServerError, map[string]any{"success": false, "message": "Failed to submit job: " + err.Error()})
 	}
-	// Removed automatic polling - users will manually refresh job status
+	announce()
+	s.submissions.Wake()
+	// No automatic polling - QL2 webhooks and manual refreshes update job status, and open tabs hear about it over /events/stream
 	return c.JSON(http.StatusOK, map[string]any{"success": true, "message": fmt.Sprintf("Collection submitted successfully with %d searches", len(items))})
 }
 
 func (s *Server) UpdateCollectionItem(c echo.Context) error {
 	// Get user info from authenticated context
-	userInterface := c.Get("user")
-	if userInterface == nil {
+	userIDStr := currentUserEmail(c) // Use email as UserID for foreign key constraint
+	if userIDStr == "" {
 		return c.JSON(http.StatusUnauthorized, map[string]any{"success": false, "message": "User not authenticated"})
 	}
-	user := userInterface.(*models.User)
-	userIDStr := user.Email // Use email as UserID for foreign key constraint
 
 	id, _ := strconv.Atoi(c.Param("id"))
 	var body struct {
@@ -746,18 +729,9 @@ func (s *Server) UpdateCollectionItem(c echo.Context) error {
 	if err := c.Bind(&body); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]any{"success": false, "message": "Invalid payload"})
 	}
-	var item models.CollectionItem
-	if err := s.DB.First(&item, id).Error; err != nil || item.ID == 0 {
-		return c.JSON(http.StatusNotFound, map[string]any{"success": false, "message": "Collection item not found"})
-	}
-
-	// Verify that the collection item belongs to the authenticated user
-	var collection models.Collection
-	if err := s.DB.First(&collection, item.CollectionID).Error; err != nil {
-		return c.JSON(http.StatusNotFound, map[string]any{"success": false, "message": "Collection not found"})
-	}
-	if collection.UserID != userIDStr {
-		return c.JSON(http.StatusForbidden, map[string]any{"success": false, "message": "User not authorized to update this collection item"})
+	item, _, err := s.ownedCollectionItem(c, id, accessWrite)
+	if err != nil {
+		return accessError(c, err, "Collection item not found")
 	}
 
 	// Prepare updated values
@@ -838,7 +812,7 @@ func (s *Server) UpdateCollectionItem(c echo.Context) error {
 		}
 		item.POS = pq.StringArray(posValues)
 	}
-	if err := s.DB.Save(&item).Error; err != nil {
+	if err := s.DB.Save(item).Error; err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]any{"success": false, "message": "Failed to update collection item"})
 	}
 	return c.JSON(http.StatusOK, map[string]any{"success": true, "message": "Collection item updated successfully"})
@@ -857,30 +831,19 @@ func (s *Server) UpdateCollectionItem(c echo.Context) error {
 // @Router /collection-item/:id [delete]
 func (s *Server) DeleteCollectionItem(c echo.Context) error {
 	// Get user info from authenticated context
-	userInterface := c.Get("user")
-	if userInterface == nil {
+	userIDStr := currentUserEmail(c)
+	if userIDStr == "" {
 		return c.JSON(http.StatusUnauthorized, map[string]any{"success": false, "message": "User not authenticated"})
 	}
-	user := userInterface.(*models.User)
-	userIDStr := user.Email
 
 	id, _ := strconv.Atoi(c.Param("id"))
-	var item models.CollectionItem
-	if err := s.DB.First(&item, id).Error; err != nil || item.ID == 0 {
-		return c.JSON(http.StatusNotFound, map[string]any{"success": false, "message": "Collection item not found"})
-	}
-
-	// Verify that the collection item belongs to the authenticated user
-	var collection models.Collection
-	if err := s.DB.First(&collection, item.CollectionID).Error; err != nil {
-		return c.JSON(http.StatusNotFound, map[string]any{"success": false, "message": "Collection not found"})
-	}
-	if collection.UserID != userIDStr {
-		return c.JSON(http.StatusForbidden, map[string]any{"success": false, "message": "User not authorized to delete this collection item"})
+	item, collection, err := s.ownedCollectionItem(c, id, accessWrite)
+	if err != nil {
+		return accessError(c, err, "Collection item not found")
 	}
 
 	// Delete the item
-	if err := s.DB.Delete(&item).Error; err != nil {
+	if err := s.DB.Delete(item).Error; err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]any{"success": false, "message": "Failed to delete collection item"})
 	}
 
@@ -889,7 +852,7 @@ func (s *Server) DeleteCollectionItem(c echo.Context) error {
 	s.DB.Where("collection_id = ?", collection.ID).Find(&remainingItems)
 	collection.Description = ptr(fmt.Sprintf("Collection with %d jobs", len(remainingItems)))
 	collection.UpdatedAt = s.TimezoneService.GetCurrentUTC()
-	s.DB.Save(&collection)
+	s.DB.Save(collection)
 
 	return c.JSON(http.StatusOK, map[string]any{"success": true, "message": "Collection item deleted successfully"})
 }
@@ -908,18 +871,9 @@ func (s *Server) DeleteCollectionItem(c echo.Context) error {
 // @Router /collection/:id/items [post]
 func (s *Server) AddCollectionItems(c echo.Context) error {
 	id, _ := strconv.Atoi(c.Param("id"))
-	var col models.Collection
-	if err := s.DB.First(&col, id).Error; err != nil || col.ID == 0 {
-		return c.JSON(http.StatusNotFound, map[string]any{"success": false, "message": "Collection not found"})
-	}
-
-	// Get user from context
-	user := c.Get("user").(*models.User)
-	userIDStr := user.Email
-
-	// Verify ownership
-	if col.UserID != userIDStr {
-		return c.JSON(http.StatusForbidden, map[string]any{"success": false, "message": "User not authorized to modify this collection"})
+	col, err := s.ownedCollection(c, id, accessWrite)
+	if err != nil {
+		return accessError(c, err, "Collection not found")
 	}
 
 	var req struct {
@@ -986,7 +940,7 @@ func (s *Server) AddCollectionItems(c echo.Context) error {
 	// Update collection description and updated_at
 	col.Description = ptr(fmt.Sprintf("Collection with %d jobs", len(existingItems)+addedCount))
 	col.UpdatedAt = s.TimezoneService.GetCurrentUTC()
-	if err := s.DB.Save(&col).Error; err != nil {
+	if err := s.DB.Save(col).Error; err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]any{"success": false, "message": "Failed to update collection"})
 	}
 
@@ -999,10 +953,11 @@ func (s *Server) AddCollectionItems(c echo.Context) error {
 
 // MySearches godoc
 // @Summary Get user's searches
-// @Description Retrieve all searches for a specific user with optional filtering
+// @Description Retrieve all searches for a specific user with optional filtering. With organization_id, list the organization's searches instead.
 // @Tags Searches
 // @Produce json
 // @Param userId query string true "User ID (email)"
+// @Param organization_id query int false "Organization ID"
 // @Param scheduled query string false "Filter by scheduled status (true/false)"
 // @Param location query string false "Filter by location"
 // @Param website query string false "Filter by website"
@@ -1014,16 +969,16 @@ func (s *Server) AddCollectionItems(c echo.Context) error {
 // @Failure 400 {object} map[string]interface{} "Bad request"
 // @Router /my-searches [get]
 func (s *Server) MySearches(c echo.Context) error {
-	// Get user info from authenticated context
-	user := c.Get("user").(*models.User)
-	userIDStr := user.Email // Use email as UserID for foreign key constraint
 	scheduledOnly := strings.TrimSpace(c.QueryParam("scheduled"))
 	locationFilter := strings.TrimSpace(c.QueryParam("location"))
 	websiteFilter := strings.TrimSpace(c.QueryParam("website"))
 	checkInStart := strings.TrimSpace(c.QueryParam("checkInStart"))
 	checkOutStart := strings.TrimSpace(c.QueryParam("checkOutStart"))
 
-	q := s.DB.Where("user_id = ?", userIDStr)
+	q, err := s.ownerScope(c)
+	if err != nil {
+		return orgError(c, err)
+	}
 	if scheduledOnly == "true" {
 		q = q.Where("scheduled = ?", true)
 	}
@@ -1089,6 +1044,7 @@ func (s *Server) MySearches(c echo.Context) error {
 			"status":              srec.Status,
 			"output":              srec.OutputFile,
 			"scheduled":           srec.Scheduled,
+			"organization_id":     srec.OrganizationID,
 			"filtered_item_count": len(filtered),
 		})
 	}
@@ -1097,9 +1053,9 @@ func (s *Server) MySearches(c echo.Context) error {
 
 func (s *Server) GetSearch(c echo.Context) error {
 	id, _ := strconv.Atoi(c.Param("id"))
-	var srec models.Search
-	if err := s.DB.First(&srec, id).Error; err != nil || srec.ID == 0 {
-		return c.JSON(http.StatusNotFound, map[string]any{"success": false, "message": "Search not found"})
+	srec, err := s.ownedSearch(c, id, accessRead)
+	if err != nil {
+		return accessError(c, err, "Search not found")
 	}
 	var items []models.SearchItem
 	_ = s.DB.Where("search_id = ?", srec.ID).Find(&items).Error
@@ -1146,9 +1102,9 @@ func (s *Server) UpdateSearchItem(c echo.Context) error {
 	if err := c.Bind(&body); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]any{"success": false, "message": "Invalid payload"})
 	}
-	var item models.SearchItem
-	if err := s.DB.First(&item, id).Error; err != nil || item.ID == 0 {
-		return c.JSON(http.StatusNotFound, map[string]any{"success": false, "message": "Search item not found"})
+	item, _, err := s.ownedSearchItem(c, id, accessWrite)
+	if err != nil {
+		return accessError(c, err, "Search item not found")
 	}
 	if body.Location != "" {
 		item.Location = body.Location
@@ -1178,7 +1134,7 @@ func (s *Server) UpdateSearchItem(c echo.Context) error {
 	if body.Amount > 0 {
 		item.Amount = body.Amount
 	}
-	if err := s.DB.Save(&item).Error; err != nil {
+	if err := s.DB.Save(item).Error; err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]any{"success": false, "message": "Failed to update search item"})
 	}
 	return c.JSON(http.StatusOK, map[string]any{"success": true, "message": "Search item updated successfully"})
@@ -1206,12 +1162,21 @@ func hasDuplicateJobs(jobs []jobData) bool {
 	return false
 }
 
-func hasDuplicateCollection(db *gorm.DB, userID string, jobs []jobData, excludeID uint) bool {
+// collectionScope limits a collection query to the organization's collections
+// when orgID is set, otherwise to the user's personal ones
+func collectionScope(db *gorm.DB, userID string, orgID *uint) *gorm.DB {
+	if orgID != nil {
+		return db.Where("organization_id = ?", *orgID)
+	}
+	return db.Where("user_id = ? AND organization_id IS NULL", userID)
+}
+
+func hasDuplicateCollection(db *gorm.DB, userID string, orgID *uint, jobs []jobData, excludeID uint) bool {
 	if userID == "" {
 		return false
 	}
 	var cols []models.Collection
-	if err := db.Where("user_id = ?", userID).Find(&cols).Error; err != nil {
+	if err := collectionScope(db, userID, orgID).Find(&cols).Error; err != nil {
 		return false
 	}
 	for _, col := range cols {
@@ -1318,8 +1283,8 @@ func (s *Server) RefreshJobStatus(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Search ID is required"})
 	}
 
-	var search models.Search
-	if err := s.DB.First(&search, searchID).Error; err != nil {
+	search, err := s.ownedSearch(c, searchID, accessRead)
+	if err != nil {
 		return c.JSON(http.StatusNotFound, simpleResponse{Success: false, Message: "Search not found"})
 	}
 
@@ -1380,7 +1345,7 @@ func (s *Server) RefreshJobStatus(c echo.Context) error {
 		processPayment = true
 	}
 
-	if err := s.updateSearchStatusFromRunData(&search, status, runID, processPayment); err != nil {
+	if err := s.updateSearchStatusFromRunData(search, status, runID, processPayment); err != nil {
 		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to update job status"})
 	}
 
@@ -1430,6 +1395,10 @@ func (s *Server) updateSearchStatusFromRunData(search *models.Search, status int
 		return fmt.Errorf("failed to update job status: %w", err)
 	}
 
+	if oldStatus != search.Status {
+		s.publishSearchStatus(search, oldStatus)
+	}
+
 	// Process payment if status changed to terminal state and processPayment is true
 	if processPayment && oldStatus != search.Status {
 		terminalStates := map[string]bool{
@@ -1459,12 +1428,32 @@ func (s *Server) updateSearchStatusFromRunData(search *models.Search, status int
 					fmt.Printf("Warning: failed to process search failure for search %d: %v\n", search.ID, err)
 				}
 			}
+
+			// Tell the owner, now that the charge is settled
+			s.notifySearchFinished(search)
 		}
 	}
 
 	return nil
 }
 
+// ql2SubmissionFailed fails the search of a QL2 job that could not be
+// submitted, which releases its frozen amount and tells the owner
+func (s *Server) ql2SubmissionFailed(sub models.QL2Submission, submitErr error) {
+	var search models.Search
+	if err := s.DB.First(&search, sub.SearchID).Error; err != nil {
+		fmt.Printf("ERROR: Failed to load search %d of failed QL2 submission: %v\n", sub.SearchID, err)
+		return
+	}
+	// QL2 may have reported on the job already if an earlier attempt got through
+	if search.Status != "Executing" {
+		return
+	}
+	if err := s.updateSearchStatusFromRunData(&search, 4, 0, true); err != nil {
+		fmt.Printf("ERROR: Failed to fail search %d after QL2 submission error: %v\n", search.ID, err)
+	}
+}
+
 // QL2JobStatusWebhook godoc
 // @Summary QL2 job status webhook
 // @Description Webhook endpoint for QL2 to notify when jobs reach terminal states (Completed/Error/Aborted)
@@ -20,8 +20,7 @@ import (
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /dashboard/stats [get]
 func (s *Server) DashboardStats(c echo.Context) error {
-	user := c.Get("user").(*models.User)
-	userIDStr := user.Email
+	userIDStr := currentUserEmail(c)
 
 	// Get search counts by status
 	var activeCount int64
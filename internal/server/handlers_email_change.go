@@ -0,0 +1,225 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+
+	"github.com/frontinsight/backend/internal/models"
+	"github.com/frontinsight/backend/internal/utils"
+)
+
+const (
+	emailChangeExpiry      = 15 * time.Minute
+	emailChangeRateWindow  = time.Hour
+	maxEmailChangeRequests = 5 // per user per window
+	maxEmailChangeAttempts = 5 // wrong codes before the request is void
+)
+
+var errEmailTaken = errors.New("email address already registered")
+
+// emailKeyedTables have a user_id column that holds the owner's email address
+var emailKeyedTables = []string{"searches", "collections", "schedules", "payment_orders", "transactions"}
+
+// changeUserEmail renames a user's email address and moves every record keyed
+// on it, so history and the wallet stay with the account. It must run inside
+// a transaction. transactions.user_id references users.email with ON UPDATE
+// CASCADE, so those rows have already followed when the loop reaches them.
+func (s *Server) changeUserEmail(tx *gorm.DB, user *models.User, newEmail string) error {
+	var taken int64
+	if err := tx.Model(&models.User{}).Where("email = ? AND id <> ?", newEmail, user.ID).Count(&taken).Error; err != nil {
+		return err
+	}
+	if taken > 0 {
+		return errEmailTaken
+	}
+
+	oldEmail := user.Email
+	if err := tx.Model(user).Update("email", newEmail).Error; err != nil {
+		return err
+	}
+	for _, table := range emailKeyedTables {
+		if err := tx.Table(table).Where("user_id = ?", oldEmail).Update("user_id", newEmail).Error; err != nil {
+			return fmt.Errorf("move %s: %w", table, err)
+		}
+	}
+
+	// Codes and links sent to the old address must not work any more
+	if err := tx.Where("email = ?", oldEmail).Delete(&models.EmailVerification{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("email = ?", oldEmail).Delete(&models.PasswordReset{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("user_id = ?", user.ID).Delete(&models.EmailChange{}).Error; err != nil {
+		return err
+	}
+
+	// Access tokens carry the email address, so every session has to start over
+	return s.revokeAllSessions(tx, user.ID)
+}
+
+type requestEmailChangeRequest struct {
+	NewEmail string `json:"new_email" example:"new@example.com" binding:"required"`
+	Password string `json:"password" example:"password123" binding:"required"`
+}
+
+// RequestEmailChange godoc
+// @Summary Request an email address change
+// @Description Send a confirmation code to the new address. The change takes effect once the code is confirmed at /auth/email/confirm.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body requestEmailChangeRequest true "New email address and current password"
+// @Success 200 {object} simpleResponse
+// @Failure 400 {object} simpleResponse
+// @Failure 401 {object} simpleResponse
+// @Failure 429 {object} simpleResponse
+// @Router /auth/email/change [post]
+func (s *Server) RequestEmailChange(c echo.Context) error {
+	var req requestEmailChangeRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Invalid payload"})
+	}
+	newEmail := strings.TrimSpace(strings.ToLower(req.NewEmail))
+	password := utils.SanitizeString(req.Password)
+	if newEmail == "" || password == "" {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "New email and password are required."})
+	}
+	if !utils.ValidateEmail(newEmail) {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Invalid email address format."})
+	}
+
+	user, err := s.currentUser(c)
+	if err != nil {
+		return unauthenticated(c)
+	}
+	if newEmail == user.Email {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "This is already your email address."})
+	}
+
+	ipAddress := s.getClientIP(c)
+	if s.CountAttemptsByEmail(models.AttemptTypeEmailChange, user.Email, emailChangeRateWindow) >= maxEmailChangeRequests {
+		return c.JSON(http.StatusTooManyRequests, simpleResponse{Success: false, Message: "Too many email change requests. Please try again later."})
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		s.RecordAttempt(models.AttemptTypeEmailChange, user.Email, ipAddress, false)
+		return c.JSON(http.StatusUnauthorized, simpleResponse{Success: false, Message: "Password is incorrect."})
+	}
+	s.RecordAttempt(models.AttemptTypeEmailChange, user.Email, ipAddress, true)
+
+	var existing models.User
+	if err := s.DB.Where("email = ?", newEmail).First(&existing).Error; err == nil && existing.ID != 0 {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Email already registered."})
+	}
+
+	// A new request replaces any earlier one
+	code := s.generateVerificationCode()
+	change := models.EmailChange{
+		UserID:    user.ID,
+		NewEmail:  newEmail,
+		CodeHash:  utils.HashToken(code),
+		ExpiresAt: time.Now().UTC().Add(emailChangeExpiry),
+	}
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.EmailChange{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&change).Error
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to start email change."})
+	}
+
+	if err := s.sendEmailChangeCode(newEmail, code); err != nil {
+		fmt.Printf("ERROR: Failed to send email change code to %s: %v\n", newEmail, err)
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to send the confirmation code."})
+	}
+
+	return c.JSON(http.StatusOK, simpleResponse{Success: true, Message: "We sent a confirmation code to your new email address."})
+}
+
+type confirmEmailChangeRequest struct {
+	Code string `json:"code" example:"123456" binding:"required"`
+}
+
+// ConfirmEmailChange godoc
+// @Summary Confirm an email address change
+// @Description Confirm the code sent to the new address. Searches, collections, schedules, payments and wallet history move to the new address, and every session is signed out.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body confirmEmailChangeRequest true "Code sent to the new address"
+// @Success 200 {object} simpleResponse
+// @Failure 400 {object} simpleResponse
+// @Failure 500 {object} simpleResponse
+// @Router /auth/email/confirm [post]
+func (s *Server) ConfirmEmailChange(c echo.Context) error {
+	var req confirmEmailChangeRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Invalid payload"})
+	}
+	code := strings.TrimSpace(req.Code)
+	if code == "" {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Confirmation code is required."})
+	}
+
+	user, err := s.currentUser(c)
+	if err != nil {
+		return unauthenticated(c)
+	}
+
+	var change models.EmailChange
+	if err := s.DB.Where("user_id = ?", user.ID).Order("id DESC").First(&change).Error; err != nil ||
+		change.ExpiresAt.Before(time.Now().UTC()) || change.Attempts >= maxEmailChangeAttempts {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Confirmation code expired. Please request a new one."})
+	}
+	if utils.HashToken(code) != change.CodeHash {
+		_ = s.DB.Model(&change).Update("attempts", gorm.Expr("attempts + 1")).Error
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Invalid confirmation code."})
+	}
+
+	oldEmail := user.Email
+	history := s.newProfileChange(c, user.ID, "email", &oldEmail, &change.NewEmail)
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := s.changeUserEmail(tx, user, change.NewEmail); err != nil {
+			return err
+		}
+		if err := tx.Create(history).Error; err != nil {
+			return err
+		}
+		// The user proved control of the new mailbox
+		return tx.Model(user).Update("is_verified", true).Error
+	})
+	if errors.Is(err, errEmailTaken) {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Email already registered."})
+	}
+	if err != nil {
+		fmt.Printf("ERROR: Failed to change email of user %d: %v\n", user.ID, err)
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to change email address."})
+	}
+
+	go func() {
+		if err := s.sendEmailChangedNotice(oldEmail, change.NewEmail); err != nil {
+			fmt.Printf("ERROR: Failed to send email change notice to %s: %v\n", oldEmail, err)
+		}
+	}()
+
+	return c.JSON(http.StatusOK, simpleResponse{Success: true, Message: "Email address changed. Please log in with your new email address."})
+}
+
+func (s *Server) sendEmailChangeCode(email, code string) error {
+	return s.sendEmail(email, "email_change_code", map[string]any{"Code": code, "Minutes": int(emailChangeExpiry.Minutes())})
+}
+
+func (s *Server) sendEmailChangedNotice(oldEmail, newEmail string) error {
+	return s.sendEmail(oldEmail, "email_changed", map[string]any{"NewEmail": newEmail})
+}
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/frontinsight/backend/internal/events"
+	"github.com/frontinsight/backend/internal/models"
+	"github.com/frontinsight/backend/internal/utils"
+)
+
+const (
+	// streamTokenExpiry is how long a stream token can be used to connect
+	streamTokenExpiry = time.Minute
+	// streamHeartbeat keeps proxies from closing an idle stream and is when
+	// the stream checks whether its session was revoked
+	streamHeartbeat = 25 * time.Second
+	// streamMaxDuration ends a stream so the client reconnects with a fresh
+	// token, signed by a session that is still valid
+	streamMaxDuration = time.Hour
+)
+
+// publishSearchStatus tells the owner's open tabs that a search changed status
+func (s *Server) publishSearchStatus(search *models.Search, oldStatus string) {
+	data := map[string]any{
+		"search_id":       search.ID,
+		"collection_name": search.CollectionName,
+		"old_status":      oldStatus,
+		"status":          search.Status,
+		"run_id":          search.RunID,
+	}
+	s.events.Publish(search.UserID, events.SearchStatus, data)
+}
+
+// CreateStreamToken godoc
+// @Summary Get a live event stream token
+// @Description Issue a one minute token for opening /events/stream. EventSource cannot send an Authorization header, so the token goes in the URL; it only opens a stream.
+// @Tags Events
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Stream token"
+// @Failure 401 {object} simpleResponse
+// @Router /events/token [post]
+func (s *Server) CreateStreamToken(c echo.Context) error {
+	claims := currentClaims(c)
+	if claims == nil {
+		return unauthenticated(c)
+	}
+	signer, err := s.keys.Signer()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to issue token."})
+	}
+	token, err := utils.GenerateStreamToken(claims.UserID, claims.Email, claims.SessionID, signer, streamTokenExpiry)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to issue token."})
+	}
+	return c.JSON(http.StatusOK, map[string]any{
+		"success":    true,
+		"token":      token,
+		"expires_in": int(streamTokenExpiry.Seconds()),
+	})
+}
+
+// validateStreamToken checks a stream token's signature, expiry, purpose and revocation
+func (s *Server) validateStreamToken(tokenString string) (*utils.Claims, error) {
+	claims, err := utils.ValidateJWT(tokenString, s.keys.Lookup)
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenUse != utils.TokenUseStream {
+		return nil, errors.New("not a stream token")
+	}
+	if s.revocations.IsRevoked(claims) {
+		return nil, errors.New("token revoked")
+	}
+	return claims, nil
+}
+
+// StreamEvents godoc
+// @Summary Live updates
+// @Description Server-Sent Events stream of the user's search status changes (search.status), scheduled runs starting and finishing (schedule.run) and wallet balance changes (wallet.balance). Each event's data is a JSON object. The stream closes after an hour or when the session ends; reconnect with a new token and reload state from the API.
+// @Tags Events
+// @Produce text/event-stream
+// @Param token query string true "Token from POST /events/token"
+// @Success 200 {string} string "Event stream"
+// @Failure 401 {object} simpleResponse
+// @Router /events/stream [get]
+func (s *Server) StreamEvents(c echo.Context) error {
+	claims, err := s.validateStreamToken(c.QueryParam("token"))
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, simpleResponse{Success: false, Message: "Invalid or expired stream token"})
+	}
+
+	sub := s.events.Subscribe(claims.Email)
+	defer sub.Close()
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set("Cache-Control", "no-cache")
+	res.Header().Set("Connection", "keep-alive")
+	res.Header().Set("X-Accel-Buffering", "no") // stop nginx from buffering the stream
+	res.WriteHeader(http.StatusOK)
+	// Clients wait this long before reconnecting
+	if _, err := fmt.Fprint(res, "retry: 5000\n\n"); err != nil {
+		return nil
+	}
+	res.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	deadline := time.NewTimer(streamMaxDuration)
+	defer deadline.Stop()
+	ctx := c.Request().Context()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-deadline.C:
+			return nil
+		case <-heartbeat.C:
+			if s.revocations.IsRevoked(claims) {
+				return nil
+			}
+			if _, err := fmt.Fprint(res, ": ping\n\n"); err != nil {
+				return nil
+			}
+			res.Flush()
+		case event, ok := <-sub.C:
+			if !ok {
+				// Fell too far behind; the client reconnects and reloads
+				return nil
+			}
+			data, err := json.Marshal(event.Data)
+			if err != nil {
+				fmt.Printf("ERROR: Failed to encode %s event: %v\n", event.Type, err)
+				continue
+			}
+			if _, err := fmt.Fprintf(res, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data); err != nil {
+				return nil
+			}
+			res.Flush()
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/frontinsight/backend/internal/models"
+	"github.com/frontinsight/backend/internal/utils"
+)
+
+// impersonationTokenExpiry bounds a support session; there is no refresh token
+const impersonationTokenExpiry = 15 * time.Minute
+
+// impersonatorID returns the admin acting as the user, or zero when the user
+// is signed in themselves
+func impersonatorID(c echo.Context) uint {
+	if claims := currentClaims(c); claims != nil {
+		return claims.ImpersonatorID
+	}
+	return 0
+}
+
+// NoImpersonationMiddleware closes a route to impersonation tokens. Support
+// can see what the user sees, but not move money or change credentials.
+func (s *Server) NoImpersonationMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if impersonatorID(c) != 0 {
+				return c.JSON(http.StatusForbidden, map[string]any{
+					"success": false,
+					"message": "Not available while impersonating a user",
+				})
+			}
+			return next(c)
+		}
+	}
+}
+
+// auditImpersonation runs an impersonated request and records it in the admin
+// activity log under the real admin's ID
+func (s *Server) auditImpersonation(c echo.Context, next echo.HandlerFunc) error {
+	claims := currentClaims(c)
+	c.Response().Header().Set("X-Impersonated-By", strconv.FormatUint(uint64(claims.ImpersonatorID), 10))
+
+	err := next(c)
+
+	status := c.Response().Status
+	if err != nil {
+		var he *echo.HTTPError
+		if errors.As(err, &he) {
+			status = he.Code
+		} else {
+			status = http.StatusInternalServerError
+		}
+	}
+	details, _ := json.Marshal(map[string]any{
+		"method": c.Request().Method,
+		"path":   c.Request().URL.RequestURI(),
+		"status": status,
+		"user":   claims.Email,
+	})
+	userID := claims.UserID
+	s.logAdminActivity(claims.ImpersonatorID, "impersonated_request", "user", &userID, string(details), c)
+	return err
+}
+
+type impersonateUserRequest struct {
+	Reason string `json:"reason" example:"Ticket #1234: collection won't submit" binding:"required"`
+}
+
+// ImpersonateUser godoc
+// @Summary Impersonate a user
+// @Description Needs users.impersonate, which only super admins hold by default. Issue a 15 minute access token that acts as the user, to see what they see. The token names the admin, cannot be refreshed and cannot reach admin routes, top up a wallet, change the profile, passwords, email, two-factor or API keys, or change organizations and their members. Every request made with it is written to the admin activity log.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param request body impersonateUserRequest true "Why support needs access"
+// @Success 200 {object} map[string]interface{} "Impersonation token"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "User not found"
+// @Router /admin/users/{id}/impersonate [post]
+func (s *Server) ImpersonateUser(c echo.Context) error {
+	admin, err := s.currentUser(c)
+	if err != nil {
+		return unauthenticated(c)
+	}
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{"success": false, "message": "Invalid user ID"})
+	}
+	var req impersonateUserRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{"success": false, "message": "Invalid payload"})
+	}
+	reason := strings.TrimSpace(utils.SanitizeString(req.Reason))
+	if reason == "" {
+		return c.JSON(http.StatusBadRequest, map[string]any{"success": false, "message": "A reason is required"})
+	}
+
+	var target models.User
+	if err := s.DB.First(&target, userID).Error; err != nil || target.AnonymizedAt != nil {
+		return c.JSON(http.StatusNotFound, map[string]any{"success": false, "message": "User not found"})
+	}
+	if target.ID == admin.ID {
+		return c.JSON(http.StatusBadRequest, map[string]any{"success": false, "message": "You cannot impersonate yourself"})
+	}
+	// Support views customer accounts; other admins' accounts stay off limits
+	if role := s.loadRole(target.Role); role != nil && role.HasPermission(models.PermAdminAccess) {
+		return c.JSON(http.StatusForbidden, map[string]any{"success": false, "message": "Admin accounts cannot be impersonated"})
+	}
+
+	signer, err := s.keys.Signer()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]any{"success": false, "message": "Failed to issue token"})
+	}
+	token, err := utils.GenerateImpersonationToken(target.ID, target.Email, admin.ID, signer, impersonationTokenExpiry)
+	if err != nil {
+		fmt.Printf("ERROR: Failed to issue impersonation token for user %d: %v\n", target.ID, err)
+		return c.JSON(http.StatusInternalServerError, map[string]any{"success": false, "message": "Failed to issue token"})
+	}
+
+	expiresAt := time.Now().UTC().Add(impersonationTokenExpiry)
+	details, _ := json.Marshal(map[string]any{
+		"user":       target.Email,
+		"reason":     reason,
+		"expires_at": expiresAt,
+	})
+	s.logAdminActivity(admin.ID, "impersonate", "user", &target.ID, string(details), c)
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"success":       true,
+		"token":         token,
+		"expires_in":    int(impersonationTokenExpiry.Seconds()),
+		"expires_at":    expiresAt,
+		"impersonating": true,
+		"user": map[string]any{
+			"id":    target.ID,
+			"email": target.Email,
+			"name":  target.Name,
+		},
+	})
+}
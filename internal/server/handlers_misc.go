@@ -8,13 +8,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/labstack/echo/v4"
 	"github.com/pkg/sftp"
 	"golang.org/x/crypto/ssh"
+	"gorm.io/gorm/clause"
 
+	"github.com/frontinsight/backend/internal/events"
 	"github.com/frontinsight/backend/internal/models"
 )
 
@@ -159,11 +162,14 @@ func (s *Server) ContactQuery(c echo.Context) error {
 // @Failure 401 {object} simpleResponse
 // @Router /wallet [get]
 func (s *Server) GetWallet(c echo.Context) error {
-	user := c.Get("user").(*models.User)
+	user, err := s.currentUser(c)
+	if err != nil {
+		return unauthenticated(c)
+	}
 
-	// Get transactions for the user
+	// Get transactions for the user's own wallet; organization wallets are listed per organization
 	var transactions []models.Transaction
-	if err := s.DB.Where("user_id = ?", user.Email).
+	if err := s.DB.Where("user_id = ? AND organization_id IS NULL", user.Email).
 		Order("created_at DESC").
 		Limit(100).
 		Find(&transactions).Error; err != nil {
@@ -221,7 +227,10 @@ func (s *Server) AddMoneyToWallet(c echo.Context) error {
 	}
 
 	// Get user from authenticated context
-	user := c.Get("user").(*models.User)
+	user, err := s.currentUser(c)
+	if err != nil {
+		return unauthenticated(c)
+	}
 
 	// Start transaction
 	tx := s.DB.Begin()
@@ -231,12 +240,18 @@ func (s *Server) AddMoneyToWallet(c echo.Context) error {
 		}
 	}()
 
+	// Lock the wallet so a concurrent freeze or refund is not overwritten
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(user, user.ID).Error; err != nil {
+		tx.Rollback()
+		return c.JSON(http.StatusInternalServerError, map[string]any{"success": false, "message": "Failed to update balance: " + err.Error()})
+	}
+
 	// Capture balance before update
 	balanceBefore := user.Balance
 
 	// Update user balance
 	user.Balance += req.Amount
-	if err := tx.Save(&user).Error; err != nil {
+	if err := tx.Model(user).Update("balance", user.Balance).Error; err != nil {
 		tx.Rollback()
 		return c.JSON(http.StatusInternalServerError, map[string]any{"success": false, "message": "Failed to update balance: " + err.Error()})
 	}
@@ -264,6 +279,11 @@ func (s *Server) AddMoneyToWallet(c echo.Context) error {
 	if err := tx.Commit().Error; err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]any{"success": false, "message": "Failed to commit transaction: " + err.Error()})
 	}
+	s.events.Publish(user.Email, events.WalletBalance, map[string]any{
+		"balance":         user.Balance,
+		"frozen_amount":   user.FrozenAmount,
+		"organization_id": nil,
+	})
 
 	return c.JSON(http.StatusOK, map[string]any{
 		"success": true,
@@ -285,8 +305,7 @@ func (s *Server) CreatePaymentOrder(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]any{"success": false, "message": "Amount is required."})
 	}
 	// Get user info from authenticated context
-	user := c.Get("user").(*models.User)
-	userIDStr := user.Email // Use email as UserID for foreign key constraint
+	userIDStr := currentUserEmail(c) // Use email as UserID for foreign key constraint
 	order := models.PaymentOrder{Amount: req.Amount, UserID: userIDStr, Status: "created"}
 	if err := s.DB.Create(&order).Error; err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]any{"success": false, "message": err.Error()})
@@ -372,6 +391,9 @@ func (s *Server) DownloadSampleData(c echo.Context) error {
 }
 
 func (s *Server) DownloadFile(c echo.Context) error {
+	if _, err := s.ownedSearchWhere(c, accessRead, "job_name = ?", c.Param("job_name")); err != nil {
+		return accessError(c, err, "File not found")
+	}
 	filename := fmt.Sprintf("out_%s.csv", c.Param("job_name"))
 	// Connect SFTP
 	cfg := &ssh.ClientConfig{
@@ -419,10 +441,16 @@ func (s *Server) DownloadFile(c echo.Context) error {
 // @Failure 500 {object} simpleResponse
 // @Router /download-by-run-id/{run_id} [get]
 func (s *Server) DownloadFileByRunID(c echo.Context) error {
-	runID := c.Param("run_id")
-	if runID == "" {
+	runIDNum, err := strconv.ParseInt(c.Param("run_id"), 10, 64)
+	if err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]any{"success": false, "message": "Run ID is required"})
 	}
+	if _, err := s.ownedSearchWhere(c, accessRead, "run_id = ?", runIDNum); err != nil {
+		return accessError(c, err, "File not found")
+	}
+	// Name the file after the checked number, not the raw parameter: "+12"
+	// and "0012" parse to the same run but name other files
+	runID := strconv.FormatInt(runIDNum, 10)
 
 	// Get format parameter (default to csv)
 	format := c.QueryParam("format")
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/frontinsight/backend/internal/models"
+)
+
+// unreadNotificationCount counts the user's notifications that are not read yet
+func (s *Server) unreadNotificationCount(userID uint) (int64, error) {
+	var count int64
+	err := s.DB.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
+
+// ListNotifications godoc
+// @Summary List notifications
+// @Description List the authenticated user's notifications, newest first
+// @Tags Notifications
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Param unread query bool false "Only unread notifications"
+// @Success 200 {object} map[string]interface{} "Notifications"
+// @Failure 401 {object} simpleResponse
+// @Router /notifications [get]
+func (s *Server) ListNotifications(c echo.Context) error {
+	userID := currentUserID(c)
+	if userID == 0 {
+		return unauthenticated(c)
+	}
+
+	page, _ := strconv.Atoi(c.QueryParam("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	query := s.DB.Model(&models.Notification{}).Where("user_id = ?", userID)
+	if c.QueryParam("unread") == "true" {
+		query = query.Where("read_at IS NULL")
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to load notifications."})
+	}
+	var notifications []models.Notification
+	if err := query.Order("created_at DESC, id DESC").Offset((page - 1) * limit).Limit(limit).Find(&notifications).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to load notifications."})
+	}
+	unread, err := s.unreadNotificationCount(userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to load notifications."})
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"success": true,
+		"data": map[string]any{
+			"notifications": notifications,
+			"unread_count":  unread,
+			"pagination": map[string]any{
+				"page":       page,
+				"limit":      limit,
+				"total":      total,
+				"totalPages": (total + int64(limit) - 1) / int64(limit),
+			},
+		},
+	})
+}
+
+// UnreadNotificationCount godoc
+// @Summary Unread notification count
+// @Description Number of the authenticated user's unread notifications, for the bell icon
+// @Tags Notifications
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Unread count"
+// @Failure 401 {object} simpleResponse
+// @Router /notifications/unread-count [get]
+func (s *Server) UnreadNotificationCount(c echo.Context) error {
+	userID := currentUserID(c)
+	if userID == 0 {
+		return unauthenticated(c)
+	}
+	unread, err := s.unreadNotificationCount(userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to count notifications."})
+	}
+	return c.JSON(http.StatusOK, map[string]any{"success": true, "unread_count": unread})
+}
+
+// MarkNotificationRead godoc
+// @Summary Mark a notification read
+// @Description Mark one of the authenticated user's notifications as read
+// @Tags Notifications
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Notification ID"
+// @Success 200 {object} map[string]interface{} "Notification marked read"
+// @Failure 400 {object} simpleResponse
+// @Failure 404 {object} simpleResponse
+// @Router /notifications/{id}/read [post]
+func (s *Server) MarkNotificationRead(c echo.Context) error {
+	userID := currentUserID(c)
+	if userID == 0 {
+		return unauthenticated(c)
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Invalid notification ID"})
+	}
+
+	var notification models.Notification
+	if err := s.DB.Where("id = ? AND user_id = ?", id, userID).First(&notification).Error; err != nil {
+		return c.JSON(http.StatusNotFound, simpleResponse{Success: false, Message: "Notification not found"})
+	}
+	if notification.ReadAt == nil {
+		now := time.Now().UTC()
+		if err := s.DB.Model(&notification).Update("read_at", now).Error; err != nil {
+			return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to update notification."})
+		}
+	}
+	unread, _ := s.unreadNotificationCount(userID)
+	return c.JSON(http.StatusOK, map[string]any{"success": true, "data": notification, "unread_count": unread})
+}
+
+// MarkAllNotificationsRead godoc
+// @Summary Mark all notifications read
+// @Description Mark every unread notification of the authenticated user as read
+// @Tags Notifications
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Number of notifications marked read"
+// @Failure 401 {object} simpleResponse
+// @Router /notifications/read-all [post]
+func (s *Server) MarkAllNotificationsRead(c echo.Context) error {
+	userID := currentUserID(c)
+	if userID == 0 {
+		return unauthenticated(c)
+	}
+	res := s.DB.Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now().UTC())
+	if res.Error != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to update notifications."})
+	}
+	return c.JSON(http.StatusOK, map[string]any{"success": true, "updated": res.RowsAffected, "unread_count": 0})
+}
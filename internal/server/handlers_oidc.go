@@ -0,0 +1,455 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+
+	"github.com/frontinsight/backend/internal/config"
+	"github.com/frontinsight/backend/internal/models"
+	"github.com/frontinsight/backend/internal/oidc"
+	"github.com/frontinsight/backend/internal/utils"
+)
+
+const (
+	// oidcStateExpiry is how long a user has to finish signing in at the identity provider
+	oidcStateExpiry = 10 * time.Minute
+	// oidcStateCookie ties a login state to the browser that started it, so a
+	// callback for someone else's login cannot be replayed in a victim's browser
+	oidcStateCookie = "fi_oidc_state"
+)
+
+// errSSOAccountExists is returned when a sign-in matches a verified account
+// that is not linked to the identity yet
+var errSSOAccountExists = errors.New("account exists and is not linked")
+
+// ssoProvider is an OIDC provider and the email domains it signs in
+type ssoProvider struct {
+	*oidc.Provider
+	domains []string
+}
+
+// newSSOProviders builds the configured single sign-on providers keyed by name
+func newSSOProviders(cfg config.AppConfig) map[string]*ssoProvider {
+	providers := make(map[string]*ssoProvider, len(cfg.OIDCProviders))
+	for _, pc := range cfg.OIDCProviders {
+		if pc.Name == "" || pc.Issuer == "" || pc.ClientID == "" || len(pc.Domains) == 0 {
+			fmt.Printf("ERROR: Skipping incomplete OIDC provider %q\n", pc.Name)
+			continue
+		}
+		domains := make([]string, 0, len(pc.Domains))
+		for _, d := range pc.Domains {
+			domains = append(domains, strings.ToLower(strings.TrimSpace(d)))
+		}
+		providers[pc.Name] = &ssoProvider{
+			Provider: oidc.NewProvider(oidc.Config{
+				Name:         pc.Name,
+				Issuer:       pc.Issuer,
+				ClientID:     pc.ClientID,
+				ClientSecret: pc.ClientSecret,
+				RedirectURL:  cfg.OIDCRedirectURL,
+				Scopes:       pc.Scopes,
+			}, nil),
+			domains: domains,
+		}
+	}
+	return providers
+}
+
+// handlesEmail reports whether the provider signs in users with this email address
+func (p *ssoProvider) handlesEmail(email string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(email[at+1:])
+	for _, d := range p.domains {
+		if d == domain {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) ssoProviderForEmail(email string) *ssoProvider {
+	for _, p := range s.ssoProviders {
+		if p.handlesEmail(email) {
+			return p
+		}
+	}
+	return nil
+}
+
+// OIDCStart godoc
+// @Summary Start single sign-on
+// @Description Look up the identity provider for the email's domain and return the URL to send the browser to. Returns 404 when the domain has no single sign-on.
+// @Tags Authentication
+// @Produce json
+// @Param email query string true "Work email address"
+// @Success 200 {object} map[string]interface{} "Authorization URL"
+// @Failure 400 {object} simpleResponse
+// @Failure 404 {object} simpleResponse
+// @Failure 502 {object} simpleResponse
+// @Router /auth/oidc/start [get]
+func (s *Server) OIDCStart(c echo.Context) error {
+	email := strings.TrimSpace(strings.ToLower(c.QueryParam("email")))
+	if email == "" || !utils.ValidateEmail(email) {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "A valid email address is required."})
+	}
+	provider := s.ssoProviderForEmail(email)
+	if provider == nil {
+		return c.JSON(http.StatusNotFound, simpleResponse{Success: false, Message: "Single sign-on is not available for this email domain."})
+	}
+	return s.beginOIDC(c, provider, email, nil)
+}
+
+// OIDCLinkStart godoc
+// @Summary Link single sign-on to my account
+// @Description Start a single sign-on flow that links the identity provider account to the signed-in user. The provider must confirm the user's email address.
+// @Tags Authentication
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Authorization URL"
+// @Failure 401 {object} simpleResponse
+// @Failure 404 {object} simpleResponse
+// @Failure 502 {object} simpleResponse
+// @Router /auth/oidc/link [post]
+func (s *Server) OIDCLinkStart(c echo.Context) error {
+	user, err := s.currentUser(c)
+	if err != nil {
+		return unauthenticated(c)
+	}
+	provider := s.ssoProviderForEmail(user.Email)
+	if provider == nil {
+		return c.JSON(http.StatusNotFound, simpleResponse{Success: false, Message: "Single sign-on is not available for this email domain."})
+	}
+	return s.beginOIDC(c, provider, user.Email, &user.ID)
+}
+
+// beginOIDC stores a login state and returns the provider's authorization
+// URL. The state is also set in a cookie that the callback checks.
+func (s *Server) beginOIDC(c echo.Context, provider *ssoProvider, email string, linkUserID *uint) error {
+	state, err := oidc.RandomString(32)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to start single sign-on."})
+	}
+	nonce, err := oidc.RandomString(32)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to start single sign-on."})
+	}
+	verifier, err := oidc.NewCodeVerifier()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to start single sign-on."})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 15*time.Second)
+	defer cancel()
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, oidc.CodeChallengeS256(verifier), email)
+	if err != nil {
+		fmt.Printf("ERROR: OIDC provider %s unavailable: %v\n", provider.Name(), err)
+		return c.JSON(http.StatusBadGateway, simpleResponse{Success: false, Message: "Your identity provider could not be reached. Please try again later."})
+	}
+
+	loginState := models.OIDCLoginState{
+		StateHash:    utils.HashToken(state),
+		Provider:     provider.Name(),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		LinkUserID:   linkUserID,
+		ExpiresAt:    time.Now().UTC().Add(oidcStateExpiry),
+	}
+	if err := s.DB.Create(&loginState).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to start single sign-on."})
+	}
+	c.SetCookie(s.oidcStateCookie(loginState.StateHash, oidcStateExpiry))
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"success":           true,
+		"provider":          provider.Name(),
+		"authorization_url": authURL,
+	})
+}
+
+// OIDCCallback godoc
+// @Summary Single sign-on callback
+// @Description Redirect target registered with identity providers. Completes the login and redirects to the frontend at /sso/callback with the tokens (or an error) in the URL fragment.
+// @Tags Authentication
+// @Param code query string false "Authorization code"
+// @Param state query string true "Login state"
+// @Success 302 "Redirect to the frontend"
+// @Router /auth/oidc/callback [get]
+func (s *Server) OIDCCallback(c echo.Context) error {
+	if errCode := c.QueryParam("error"); errCode != "" {
+		fmt.Printf("ERROR: OIDC provider returned %s: %s\n", errCode, c.QueryParam("error_description"))
+		return s.ssoRedirect(c, url.Values{"error": {"Sign-in was cancelled or rejected by your identity provider."}})
+	}
+	code, state := c.QueryParam("code"), c.QueryParam("state")
+	if code == "" || state == "" {
+		return s.ssoRedirect(c, url.Values{"error": {"Invalid sign-in response."}})
+	}
+	// The state must come back to the browser that started the sign-in
+	cookie, err := c.Cookie(oidcStateCookie)
+	c.SetCookie(s.clearOIDCStateCookie())
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(utils.HashToken(state))) != 1 {
+		return s.ssoRedirect(c, url.Values{"error": {"Sign-in link expired. Please try again."}})
+	}
+
+	// States are single use: claim the row by deleting it
+	var loginState models.OIDCLoginState
+	if err := s.DB.Where("state_hash = ?", utils.HashToken(state)).First(&loginState).Error; err != nil {
+		return s.ssoRedirect(c, url.Values{"error": {"Sign-in link expired. Please try again."}})
+	}
+	if res := s.DB.Delete(&models.OIDCLoginState{}, loginState.ID); res.Error != nil || res.RowsAffected != 1 {
+		return s.ssoRedirect(c, url.Values{"error": {"Sign-in link expired. Please try again."}})
+	}
+	provider, ok := s.ssoProviders[loginState.Provider]
+	if !ok || loginState.ExpiresAt.Before(time.Now().UTC()) {
+		return s.ssoRedirect(c, url.Values{"error": {"Sign-in link expired. Please try again."}})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 15*time.Second)
+	defer cancel()
+	tokens, err := provider.Exchange(ctx, code, loginState.CodeVerifier)
+	if err != nil {
+		fmt.Printf("ERROR: OIDC code exchange with %s failed: %v\n", provider.Name(), err)
+		return s.ssoRedirect(c, url.Values{"error": {"Sign-in failed. Please try again."}})
+	}
+	claims, err := provider.VerifyIDToken(ctx, tokens.IDToken, loginState.Nonce)
+	if err != nil {
+		fmt.Printf("ERROR: OIDC id token from %s rejected: %v\n", provider.Name(), err)
+		return s.ssoRedirect(c, url.Values{"error": {"Sign-in failed. Please try again."}})
+	}
+
+	// Only trust verified addresses in the provider's own domains
+	email := strings.TrimSpace(strings.ToLower(claims.Email))
+	if !claims.EmailVerified || !utils.ValidateEmail(email) || !provider.handlesEmail(email) {
+		return s.ssoRedirect(c, url.Values{"error": {"Your identity provider did not confirm an email address for this domain."}})
+	}
+
+	if loginState.LinkUserID != nil {
+		if err := s.linkOIDCIdentity(*loginState.LinkUserID, provider.Name(), claims.Subject, email); err != nil {
+			fmt.Printf("ERROR: Failed to link OIDC identity to user %d: %v\n", *loginState.LinkUserID, err)
+			return s.ssoRedirect(c, url.Values{"error": {"Single sign-on could not be linked to your account."}})
+		}
+		return s.ssoRedirect(c, url.Values{"linked": {"true"}})
+	}
+
+	ipAddress := s.getClientIP(c)
+	user, err := s.linkOIDCUser(provider.Name(), claims.Subject, email, claims.Name, ipAddress)
+	if errors.Is(err, errSSOAccountExists) {
+		return s.ssoRedirect(c, url.Values{"error": {"An account with this email already exists. Log in with your password and link single sign-on from your profile."}})
+	}
+	if err != nil {
+		fmt.Printf("ERROR: Failed to link OIDC user %s: %v\n", email, err)
+		return s.ssoRedirect(c, url.Values{"error": {"Sign-in failed. Please try again."}})
+	}
+
+	// The same account checks as a password login
+	if user.AnonymizedAt != nil {
+		return s.ssoRedirect(c, url.Values{"error": {"Sign-in failed. Please try again."}})
+	}
+	if accountRetryAfter(user) > 0 {
+		s.RecordLoginAttempt(user.Email, ipAddress, false)
+		return s.ssoRedirect(c, url.Values{"error": {"Too many failed login attempts. Please try again later."}})
+	}
+	if user.TOTPEnabled {
+		mfaToken, err := s.issueMFAToken(user)
+		if err != nil {
+			return s.ssoRedirect(c, url.Values{"error": {"Sign-in failed. Please try again."}})
+		}
+		return s.ssoRedirect(c, url.Values{"mfa_token": {mfaToken}})
+	}
+
+	pair, err := s.startSession(s.DB, user, c)
+	if err != nil {
+		return s.ssoRedirect(c, url.Values{"error": {"Sign-in failed. Please try again."}})
+	}
+	now := time.Now().UTC()
+	_ = s.DB.Model(user).Update("last_login_at", now).Error
+	s.RecordLoginAttempt(user.Email, ipAddress, true)
+
+	return s.ssoRedirect(c, url.Values{
+		"token":         {pair.AccessToken},
+		"refresh_token": {pair.RefreshToken},
+		"expires_in":    {fmt.Sprint(pair.ExpiresIn)},
+	})
+}
+
+// linkOIDCUser finds the user for a provider identity, or creates one on first
+// sign-in. An account that was registered with the address but never verified
+// is taken over: whoever registered it may not own the address, so its
+// password, second factor, sessions and API keys are dropped. A verified
+// account is not linked; its owner links it with OIDCLinkStart after logging in.
+func (s *Server) linkOIDCUser(provider, subject, email, name, ipAddress string) (*models.User, error) {
+	if subject == "" {
+		return nil, errors.New("id token has no subject")
+	}
+
+	var user models.User
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UTC()
+
+		var identity models.OIDCIdentity
+		err := tx.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error
+		if err == nil {
+			if err := tx.First(&user, identity.UserID).Error; err != nil {
+				return err
+			}
+			return tx.Model(&identity).Updates(map[string]any{"last_login_at": now, "email": email}).Error
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		err = tx.Where("email = ?", email).First(&user).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Just-in-time provisioning. The random password can only be
+			// replaced through the password reset flow.
+			randomPassword, err := utils.GenerateToken(32)
+			if err != nil {
+				return err
+			}
+			hash, err := bcrypt.GenerateFromPassword([]byte(randomPassword), 12)
+			if err != nil {
+				return err
+			}
+			if name == "" {
+				name = strings.SplitN(email, "@", 2)[0]
+			}
+			user = models.User{
+				Email:      email,
+				Name:       utils.SanitizeString(name),
+				Password:   string(hash),
+				IsVerified: true,
+				Role:       "user",
+				IPAddress:  &ipAddress,
+			}
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+		} else if err != nil {
+			return err
+		} else if user.IsVerified {
+			return errSSOAccountExists
+		} else if err := s.claimUnverifiedAccount(tx, &user); err != nil {
+			return err
+		}
+
+		return tx.Create(&models.OIDCIdentity{
+			UserID:      user.ID,
+			Provider:    provider,
+			Subject:     subject,
+			Email:       email,
+			LastLoginAt: &now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// claimUnverifiedAccount hands an unverified account to the identity provider
+// user who owns its address, removing every credential the registrant set
+func (s *Server) claimUnverifiedAccount(tx *gorm.DB, user *models.User) error {
+	randomPassword, err := utils.GenerateToken(32)
+	if err != nil {
+		return err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(randomPassword), 12)
+	if err != nil {
+		return err
+	}
+	if err := tx.Model(user).Updates(map[string]any{
+		"password":       string(hash),
+		"is_verified":    true,
+		"totp_enabled":   false,
+		"totp_secret":    nil,
+		"totp_last_step": 0,
+	}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.APIKey{}).Where("user_id = ? AND revoked_at IS NULL", user.ID).
+		Update("revoked_at", time.Now().UTC()).Error; err != nil {
+		return err
+	}
+	return s.revokeAllSessions(tx, user.ID)
+}
+
+// linkOIDCIdentity links a provider identity to a signed-in user. The provider
+// must have confirmed the user's own address.
+func (s *Server) linkOIDCIdentity(userID uint, provider, subject, email string) error {
+	if subject == "" {
+		return errors.New("id token has no subject")
+	}
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.First(&user, userID).Error; err != nil {
+			return err
+		}
+		if !strings.EqualFold(user.Email, email) {
+			return fmt.Errorf("provider confirmed %s, account is %s", email, user.Email)
+		}
+		var existing models.OIDCIdentity
+		err := tx.Where("provider = ? AND subject = ?", provider, subject).First(&existing).Error
+		if err == nil {
+			if existing.UserID != user.ID {
+				return errors.New("identity is linked to another account")
+			}
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		return tx.Create(&models.OIDCIdentity{
+			UserID:   user.ID,
+			Provider: provider,
+			Subject:  subject,
+			Email:    email,
+		}).Error
+	})
+}
+
+// oidcStateCookie holds the hash of the login state for the callback
+func (s *Server) oidcStateCookie(value string, maxAge time.Duration) *http.Cookie {
+	return &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    value,
+		Path:     "/auth/oidc",
+		MaxAge:   int(maxAge.Seconds()),
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(s.Cfg.OIDCRedirectURL, "https://"),
+		// Lax, so the cookie comes back on the provider's redirect
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// clearOIDCStateCookie deletes the state cookie; a negative MaxAge is sent as
+// Max-Age=0, which browsers treat as expired
+func (s *Server) clearOIDCStateCookie() *http.Cookie {
+	cookie := s.oidcStateCookie("", 0)
+	cookie.MaxAge = -1
+	return cookie
+}
+
+// ssoRedirect sends the browser back to the frontend with the result in the
+// URL fragment, which browsers do not send to servers or write to access logs
+func (s *Server) ssoRedirect(c echo.Context, values url.Values) error {
+	return c.Redirect(http.StatusFound, s.Cfg.FrontendURL+"/sso/callback#"+values.Encode())
+}
+
+func (s *Server) cleanupExpiredOIDCStates() {
+	_ = s.DB.Where("expires_at < ?", time.Now().UTC()).Delete(&models.OIDCLoginState{}).Error
+}
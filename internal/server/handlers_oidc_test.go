@@ -0,0 +1,307 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/frontinsight/backend/internal/config"
+	"github.com/frontinsight/backend/internal/models"
+	"github.com/frontinsight/backend/internal/oidc"
+	"github.com/frontinsight/backend/internal/oidc/oidctest"
+)
+
+// The single sign-on tests run the login flow against the stand-in provider
+// in oidctest and, like the tenant isolation tests, need TEST_DATABASE_URL.
+
+const (
+	ssoClientID     = "frontinsight-test"
+	ssoClientSecret = "sso-test-secret"
+)
+
+type ssoTest struct {
+	*testServer
+	idp          *oidctest.Provider
+	providerName string
+	domain       string
+}
+
+func newSSOTest(t *testing.T) *ssoTest {
+	t.Helper()
+	idp, srv, err := oidctest.NewServer(ssoClientID, ssoClientSecret)
+	if err != nil {
+		t.Fatalf("start identity provider: %v", err)
+	}
+	t.Cleanup(srv.Close)
+
+	tag := time.Now().UnixNano()
+	st := &ssoTest{
+		idp:          idp,
+		providerName: fmt.Sprintf("sso%d", tag),
+		domain:       fmt.Sprintf("sso%d.example.com", tag),
+	}
+	st.testServer = newTestServer(t, func(cfg *config.AppConfig) {
+		cfg.OIDCRedirectURL = "http://localhost:5001/auth/oidc/callback"
+		cfg.OIDCProviders = []config.OIDCProvider{{
+			Name:         st.providerName,
+			Issuer:       srv.URL,
+			ClientID:     ssoClientID,
+			ClientSecret: ssoClientSecret,
+			Domains:      []string{st.domain},
+		}}
+	})
+	t.Cleanup(func() {
+		var ids []uint
+		st.db.Model(&models.User{}).Where("email LIKE ?", "%@"+st.domain).Pluck("id", &ids)
+		if len(ids) > 0 {
+			st.db.Where("user_id IN ?", ids).Delete(&models.OIDCIdentity{})
+			st.db.Where("user_id IN ?", ids).Delete(&models.RefreshToken{})
+			st.db.Where("user_id IN ?", ids).Delete(&models.Session{})
+			st.db.Where("id IN ?", ids).Delete(&models.User{})
+		}
+		st.db.Where("email LIKE ?", "%@"+st.domain).Delete(&models.LoginAttempt{})
+		st.db.Where("provider = ?", st.providerName).Delete(&models.OIDCLoginState{})
+	})
+	return st
+}
+
+// request sends an unauthenticated request through the router
+func (st *ssoTest) request(path string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for _, c := range cookies {
+		req.AddCookie(&http.Cookie{Name: c.Name, Value: c.Value})
+	}
+	n := st.reqs.Add(1)
+	req.RemoteAddr = fmt.Sprintf("203.0.%d.%d:1234", n/250, n%250+1)
+	rec := httptest.NewRecorder()
+	st.e.ServeHTTP(rec, req)
+	return rec
+}
+
+// start begins a sign-in and returns the authorization URL and state cookie
+func (st *ssoTest) start(email string) (string, *http.Cookie) {
+	st.t.Helper()
+	rec := st.request("/auth/oidc/start?email=" + url.QueryEscape(email))
+	if rec.Code != http.StatusOK {
+		st.t.Fatalf("start sign-in: HTTP %d: %s", rec.Code, rec.Body)
+	}
+	var body struct {
+		AuthorizationURL string `json:"authorization_url"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.AuthorizationURL == "" {
+		st.t.Fatalf("start sign-in: no authorization URL in %s", rec.Body)
+	}
+	cookie := findCookie(rec.Result().Cookies(), oidcStateCookie)
+	if cookie == nil {
+		st.t.Fatal("start sign-in: no state cookie")
+	}
+	return body.AuthorizationURL, cookie
+}
+
+// authorize signs in at the provider and returns the callback path and query
+// it redirects the browser to
+func (st *ssoTest) authorize(authURL string) string {
+	st.t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		st.t.Fatalf("authorize: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		st.t.Fatalf("authorize: HTTP %d, want a redirect", resp.StatusCode)
+	}
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		st.t.Fatalf("authorize: %v", err)
+	}
+	return callback.RequestURI()
+}
+
+// callback completes the sign-in and returns the response and the result the
+// frontend receives in the URL fragment
+func (st *ssoTest) callback(path string, cookies ...*http.Cookie) (*httptest.ResponseRecorder, url.Values) {
+	st.t.Helper()
+	rec := st.request(path, cookies...)
+	if rec.Code != http.StatusFound {
+		st.t.Fatalf("callback: HTTP %d, want a redirect", rec.Code)
+	}
+	location, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil || location.Path != "/sso/callback" {
+		st.t.Fatalf("callback redirected to %q", rec.Header().Get("Location"))
+	}
+	result, err := url.ParseQuery(location.EscapedFragment())
+	if err != nil {
+		st.t.Fatalf("callback fragment: %v", err)
+	}
+	return rec, result
+}
+
+// signIn runs the whole flow for a provider user
+func (st *ssoTest) signIn(email string) url.Values {
+	st.t.Helper()
+	authURL, cookie := st.start(email)
+	_, result := st.callback(st.authorize(authURL), cookie)
+	return result
+}
+
+func findCookie(cookies []*http.Cookie, name string) *http.Cookie {
+	for _, c := range cookies {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func TestOIDCStateCookieRoundTrip(t *testing.T) {
+	st := newSSOTest(t)
+	email := "ana@" + st.domain
+	st.idp.AddUser(oidctest.User{Email: email, Name: "Ana", EmailVerified: true})
+
+	authURL, cookie := st.start(email)
+	if cookie.Path != "/auth/oidc" || !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode {
+		t.Errorf("state cookie = %+v, want HttpOnly, SameSite=Lax and path /auth/oidc", cookie)
+	}
+	if cookie.MaxAge != int(oidcStateExpiry.Seconds()) {
+		t.Errorf("state cookie Max-Age = %d, want %d", cookie.MaxAge, int(oidcStateExpiry.Seconds()))
+	}
+	auth, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := auth.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" || q.Get("nonce") == "" {
+		t.Errorf("authorization URL %s lacks PKCE or a nonce", authURL)
+	}
+	callback := st.authorize(authURL)
+
+	t.Run("missing cookie", func(t *testing.T) {
+		_, result := st.callback(callback)
+		if result.Get("error") == "" || result.Get("token") != "" {
+			t.Errorf("callback without the state cookie = %v, want an error", result)
+		}
+	})
+
+	t.Run("cookie for another sign-in", func(t *testing.T) {
+		_, other := st.start(email)
+		_, result := st.callback(callback, other)
+		if result.Get("error") == "" || result.Get("token") != "" {
+			t.Errorf("callback with another state cookie = %v, want an error", result)
+		}
+	})
+
+	t.Run("matching cookie", func(t *testing.T) {
+		rec, result := st.callback(callback, cookie)
+		if result.Get("token") == "" || result.Get("refresh_token") == "" {
+			t.Fatalf("callback = %v, want tokens", result)
+		}
+		// Max-Age=0 in the header is read back as a negative MaxAge
+		if cleared := findCookie(rec.Result().Cookies(), oidcStateCookie); cleared == nil || cleared.MaxAge >= 0 {
+			t.Errorf("callback Set-Cookie = %q, want the state cookie deleted", rec.Header().Values("Set-Cookie"))
+		}
+	})
+
+	t.Run("replayed state", func(t *testing.T) {
+		_, result := st.callback(callback, cookie)
+		if result.Get("error") == "" || result.Get("token") != "" {
+			t.Errorf("second callback with the same state = %v, want an error", result)
+		}
+	})
+}
+
+func TestOIDCRejectsBadIDTokens(t *testing.T) {
+	st := newSSOTest(t)
+	tests := []struct {
+		name string
+		edit func(claims *oidc.IDTokenClaims)
+	}{
+		{"wrong nonce", func(c *oidc.IDTokenClaims) { c.Nonce = "not-the-nonce" }},
+		{"missing nonce", func(c *oidc.IDTokenClaims) { c.Nonce = "" }},
+		{"expired", func(c *oidc.IDTokenClaims) {
+			c.IssuedAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+			c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-10 * time.Minute))
+		}},
+		{"foreign audience", func(c *oidc.IDTokenClaims) { c.Audience = jwt.ClaimStrings{"another-client"} }},
+		{"foreign issuer", func(c *oidc.IDTokenClaims) { c.Issuer = "https://idp.example.net" }},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			email := fmt.Sprintf("user%d@%s", i, st.domain)
+			st.idp.AddUser(oidctest.User{Email: email, EmailVerified: true})
+			st.idp.EditClaims = tt.edit
+			defer func() { st.idp.EditClaims = nil }()
+
+			result := st.signIn(email)
+			if result.Get("error") == "" || result.Get("token") != "" {
+				t.Errorf("sign-in = %v, want an error", result)
+			}
+			var n int64
+			st.db.Model(&models.User{}).Where("email = ?", email).Count(&n)
+			if n != 0 {
+				t.Errorf("an account was created for %s", email)
+			}
+		})
+	}
+}
+
+func TestOIDCProvisionsNewUser(t *testing.T) {
+	st := newSSOTest(t)
+	email := "new.user@" + st.domain
+	st.idp.AddUser(oidctest.User{Subject: "subject-1", Email: email, Name: "New User", EmailVerified: true})
+
+	result := st.signIn(email)
+	if result.Get("token") == "" {
+		t.Fatalf("sign-in = %v, want tokens", result)
+	}
+	var user models.User
+	if err := st.db.Where("email = ?", email).First(&user).Error; err != nil {
+		t.Fatalf("no account was created: %v", err)
+	}
+	if !user.IsVerified || user.Name != "New User" || user.Role != models.RoleUser {
+		t.Errorf("created user = %+v, want a verified customer named New User", user)
+	}
+	var identity models.OIDCIdentity
+	if err := st.db.Where("provider = ? AND subject = ?", st.providerName, "subject-1").First(&identity).Error; err != nil || identity.UserID != user.ID {
+		t.Errorf("identity = %+v (%v), want it linked to user %d", identity, err, user.ID)
+	}
+
+	// Signing in again finds the same account through the identity
+	if result := st.signIn(email); result.Get("token") == "" {
+		t.Fatalf("second sign-in = %v, want tokens", result)
+	}
+	var n int64
+	st.db.Model(&models.User{}).Where("email = ?", email).Count(&n)
+	if n != 1 {
+		t.Errorf("%d accounts for %s, want 1", n, email)
+	}
+}
+
+func TestOIDCDoesNotTakeOverVerifiedAccount(t *testing.T) {
+	st := newSSOTest(t)
+	email := "owner@" + st.domain
+	existing := &models.User{Email: email, Name: "Owner", Password: "not-a-hash", IsVerified: true}
+	st.create(existing)
+	st.idp.AddUser(oidctest.User{Email: email, EmailVerified: true})
+
+	result := st.signIn(email)
+	if result.Get("token") != "" || !strings.Contains(result.Get("error"), "already exists") {
+		t.Errorf("sign-in = %v, want the account exists error", result)
+	}
+	var n int64
+	st.db.Model(&models.OIDCIdentity{}).Where("user_id = ?", existing.ID).Count(&n)
+	if n != 0 {
+		t.Errorf("identity linked to the existing account")
+	}
+	var user models.User
+	if err := st.db.First(&user, existing.ID).Error; err != nil || user.Password != "not-a-hash" {
+		t.Errorf("existing account changed: %+v (%v)", user, err)
+	}
+}
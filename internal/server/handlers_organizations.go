@@ -0,0 +1,626 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/frontinsight/backend/internal/events"
+	"github.com/frontinsight/backend/internal/models"
+	"github.com/frontinsight/backend/internal/utils"
+)
+
+var (
+	errOrgNotFound  = errors.New("organization not found")
+	errOrgForbidden = errors.New("insufficient organization role")
+	errLastOwner    = errors.New("an organization must keep at least one owner")
+
+	// orgRoleRank orders organization roles; a higher rank includes the lower ones
+	orgRoleRank = map[string]int{
+		models.OrgRoleViewer:  1,
+		models.OrgRoleManager: 2,
+		models.OrgRoleOwner:   3,
+	}
+)
+
+func validOrgRole(role string) bool {
+	_, ok := orgRoleRank[role]
+	return ok
+}
+
+// orgMembership checks that the user belongs to the organization with at
+// least minRole. Non-members get errOrgNotFound so IDs cannot be probed.
+func (s *Server) orgMembership(userID, orgID uint, minRole string) (*models.OrganizationMember, error) {
+	var member models.OrganizationMember
+	if err := s.DB.Where("organization_id = ? AND user_id = ?", orgID, userID).First(&member).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errOrgNotFound
+		}
+		return nil, err
+	}
+	if orgRoleRank[member.Role] < orgRoleRank[minRole] {
+		return &member, errOrgForbidden
+	}
+	return &member, nil
+}
+
+// orgError writes the response for an orgMembership error
+func orgError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, errOrgNotFound):
+		return c.JSON(http.StatusNotFound, simpleResponse{Success: false, Message: "Organization not found."})
+	case errors.Is(err, errOrgForbidden):
+		return c.JSON(http.StatusForbidden, simpleResponse{Success: false, Message: "Your organization role does not allow this."})
+	case errors.Is(err, errLastOwner):
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "An organization must keep at least one owner."})
+	}
+	return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to load organization."})
+}
+
+// orgIDParam parses an organization ID from a path or query value; empty means none
+func orgIDParam(raw string) (*uint, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	id, err := strconv.ParseUint(raw, 10, 32)
+	if err != nil || id == 0 {
+		return nil, errOrgNotFound
+	}
+	orgID := uint(id)
+	return &orgID, nil
+}
+
+// ownerScope returns the query for records listed to the caller: an
+// organization's when ?organization_id is given and the caller is a member,
+// otherwise the caller's own
+func (s *Server) ownerScope(c echo.Context) (*gorm.DB, error) {
+	orgID, err := orgIDParam(c.QueryParam("organization_id"))
+	if err != nil {
+		return nil, err
+	}
+	if orgID == nil {
+		// Organization records stay with the organization, also when the
+		// member who created them leaves
+		return s.DB.Where("user_id = ? AND organization_id IS NULL", currentUserEmail(c)), nil
+	}
+	if _, err := s.orgMembership(currentUserID(c), *orgID, models.OrgRoleViewer); err != nil {
+		return nil, err
+	}
+	return s.DB.Where("organization_id = ?", *orgID), nil
+}
+
+// countOwners locks the organization row and returns how many owners it has.
+// The lock holds until tx ends, so two owners demoting or removing each other
+// at once cannot both see the other one and leave the organization without
+// an owner.
+func countOwners(tx *gorm.DB, orgID uint) (int64, error) {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.Organization{}, orgID).Error; err != nil {
+		return 0, err
+	}
+	var n int64
+	err := tx.Model(&models.OrganizationMember{}).Where("organization_id = ? AND role = ?", orgID, models.OrgRoleOwner).Count(&n).Error
+	return n, err
+}
+
+type organizationRequest struct {
+	Name string `json:"name" example:"Seaside Hotels" binding:"required"`
+}
+
+type organizationMemberRequest struct {
+	Email string `json:"email" example:"revenue@seaside.example"` // required when adding a member
+	Role  string `json:"role" example:"manager" enums:"owner,manager,viewer"`
+}
+
+// CreateOrganization godoc
+// @Summary Create an organization
+// @Description Create an organization with its own shared wallet. The creator becomes its owner.
+// @Tags Organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body organizationRequest true "Organization name"
+// @Success 201 {object} map[string]interface{} "Organization created"
+// @Failure 400 {object} simpleResponse
+// @Failure 401 {object} simpleResponse
+// @Router /organizations [post]
+func (s *Server) CreateOrganization(c echo.Context) error {
+	userID := currentUserID(c)
+	if userID == 0 {
+		return unauthenticated(c)
+	}
+	var req organizationRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Invalid request format"})
+	}
+	name := utils.SanitizeString(strings.TrimSpace(req.Name))
+	if name == "" || len(name) > 255 {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Organization name is required and cannot exceed 255 characters."})
+	}
+
+	org := models.Organization{Name: name, CreatedBy: userID}
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&org).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.OrganizationMember{OrganizationID: org.ID, UserID: userID, Role: models.OrgRoleOwner}).Error
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to create organization."})
+	}
+
+	return c.JSON(http.StatusCreated, map[string]any{"success": true, "organization": org, "role": models.OrgRoleOwner})
+}
+
+// ListOrganizations godoc
+// @Summary List my organizations
+// @Description List the organizations the authenticated user belongs to, with their role in each
+// @Tags Organizations
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Organizations"
+// @Failure 401 {object} simpleResponse
+// @Router /organizations [get]
+func (s *Server) ListOrganizations(c echo.Context) error {
+	userID := currentUserID(c)
+	if userID == 0 {
+		return unauthenticated(c)
+	}
+
+	var rows []struct {
+		models.Organization
+		Role string
+	}
+	if err := s.DB.Table("organizations").
+		Select("organizations.*, organization_members.role").
+		Joins("JOIN organization_members ON organization_members.organization_id = organizations.id").
+		Where("organization_members.user_id = ?", userID).
+		Order("organizations.name").
+		Scan(&rows).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to load organizations."})
+	}
+
+	orgs := make([]map[string]any, 0, len(rows))
+	for _, r := range rows {
+		orgs = append(orgs, map[string]any{
+			"id":            r.ID,
+			"name":          r.Name,
+			"balance":       r.Balance,
+			"frozen_amount": r.FrozenAmount,
+			"created_at":    r.CreatedAt.Format(time.RFC3339),
+			"role":          r.Role,
+		})
+	}
+	return c.JSON(http.StatusOK, map[string]any{"success": true, "organizations": orgs})
+}
+
+// GetOrganization godoc
+// @Summary Get an organization
+// @Description Get an organization and its members. Any member can view it.
+// @Tags Organizations
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Success 200 {object} map[string]interface{} "Organization with members"
+// @Failure 404 {object} simpleResponse
+// @Router /organizations/{id} [get]
+func (s *Server) GetOrganization(c echo.Context) error {
+	orgID, err := orgIDParam(c.Param("id"))
+	if err != nil || orgID == nil {
+		return orgError(c, errOrgNotFound)
+	}
+	member, err := s.orgMembership(currentUserID(c), *orgID, models.OrgRoleViewer)
+	if err != nil {
+		return orgError(c, err)
+	}
+
+	var org models.Organization
+	if err := s.DB.First(&org, *orgID).Error; err != nil {
+		return orgError(c, errOrgNotFound)
+	}
+	members, err := s.organizationMembers(org.ID)
+	if err != nil {
+		return orgError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"success":      true,
+		"organization": org,
+		"role":         member.Role,
+		"members":      members,
+	})
+}
+
+func (s *Server) organizationMembers(orgID uint) ([]map[string]any, error) {
+	var rows []struct {
+		UserID    uint
+		Email     string
+		Name      string
+		Role      string
+		CreatedAt time.Time
+	}
+	if err := s.DB.Table("organization_members").
+		Select("organization_members.user_id, users.email, users.name, organization_members.role, organization_members.created_at").
+		Joins("JOIN users ON users.id = organization_members.user_id").
+		Where("organization_members.organization_id = ?", orgID).
+		Order("organization_members.created_at").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	members := make([]map[string]any, 0, len(rows))
+	for _, r := range rows {
+		members = append(members, map[string]any{
+			"user_id":   r.UserID,
+			"email":     r.Email,
+			"name":      r.Name,
+			"role":      r.Role,
+			"joined_at": r.CreatedAt.Format(time.RFC3339),
+		})
+	}
+	return members, nil
+}
+
+// UpdateOrganization godoc
+// @Summary Rename an organization
+// @Description Rename an organization. Owners only.
+// @Tags Organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Param request body organizationRequest true "New name"
+// @Success 200 {object} map[string]interface{} "Organization updated"
+// @Failure 400 {object} simpleResponse
+// @Failure 403 {object} simpleResponse
+// @Failure 404 {object} simpleResponse
+// @Router /organizations/{id} [put]
+func (s *Server) UpdateOrganization(c echo.Context) error {
+	orgID, err := orgIDParam(c.Param("id"))
+	if err != nil || orgID == nil {
+		return orgError(c, errOrgNotFound)
+	}
+	if _, err := s.orgMembership(currentUserID(c), *orgID, models.OrgRoleOwner); err != nil {
+		return orgError(c, err)
+	}
+	var req organizationRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Invalid request format"})
+	}
+	name := utils.SanitizeString(strings.TrimSpace(req.Name))
+	if name == "" || len(name) > 255 {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Organization name is required and cannot exceed 255 characters."})
+	}
+
+	if err := s.DB.Model(&models.Organization{}).Where("id = ?", *orgID).
+		Updates(map[string]any{"name": name, "updated_at": time.Now().UTC()}).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to update organization."})
+	}
+	return c.JSON(http.StatusOK, simpleResponse{Success: true, Message: "Organization updated."})
+}
+
+// AddOrganizationMember godoc
+// @Summary Add an organization member
+// @Description Add an existing user to the organization by email. Owners only.
+// @Tags Organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Param request body organizationMemberRequest true "Member email and role"
+// @Success 201 {object} map[string]interface{} "Member added"
+// @Failure 400 {object} simpleResponse
+// @Failure 403 {object} simpleResponse
+// @Failure 404 {object} simpleResponse
+// @Failure 409 {object} simpleResponse
+// @Router /organizations/{id}/members [post]
+func (s *Server) AddOrganizationMember(c echo.Context) error {
+	orgID, err := orgIDParam(c.Param("id"))
+	if err != nil || orgID == nil {
+		return orgError(c, errOrgNotFound)
+	}
+	if _, err := s.orgMembership(currentUserID(c), *orgID, models.OrgRoleOwner); err != nil {
+		return orgError(c, err)
+	}
+	var req organizationMemberRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Invalid request format"})
+	}
+	email := strings.TrimSpace(strings.ToLower(req.Email))
+	if req.Role == "" {
+		req.Role = models.OrgRoleViewer
+	}
+	if !validOrgRole(req.Role) {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Role must be owner, manager or viewer."})
+	}
+
+	var user models.User
+	if err := s.DB.Where("email = ?", email).First(&user).Error; err != nil {
+		return c.JSON(http.StatusNotFound, simpleResponse{Success: false, Message: "No user with this email. They need to sign up first."})
+	}
+	var existing int64
+	s.DB.Model(&models.OrganizationMember{}).Where("organization_id = ? AND user_id = ?", *orgID, user.ID).Count(&existing)
+	if existing > 0 {
+		return c.JSON(http.StatusConflict, simpleResponse{Success: false, Message: "This user is already a member."})
+	}
+
+	member := models.OrganizationMember{OrganizationID: *orgID, UserID: user.ID, Role: req.Role}
+	if err := s.DB.Create(&member).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to add member."})
+	}
+	return c.JSON(http.StatusCreated, map[string]any{"success": true, "member": member})
+}
+
+// UpdateOrganizationMember godoc
+// @Summary Change a member's role
+// @Description Change a member's organization role. Owners only. The last owner cannot be demoted.
+// @Tags Organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Param userId path int true "Member user ID"
+// @Param request body organizationMemberRequest true "New role"
+// @Success 200 {object} simpleResponse
+// @Failure 400 {object} simpleResponse
+// @Failure 403 {object} simpleResponse
+// @Failure 404 {object} simpleResponse
+// @Router /organizations/{id}/members/{userId} [put]
+func (s *Server) UpdateOrganizationMember(c echo.Context) error {
+	orgID, err := orgIDParam(c.Param("id"))
+	if err != nil || orgID == nil {
+		return orgError(c, errOrgNotFound)
+	}
+	if _, err := s.orgMembership(currentUserID(c), *orgID, models.OrgRoleOwner); err != nil {
+		return orgError(c, err)
+	}
+	memberUserID, err := strconv.ParseUint(c.Param("userId"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Invalid user ID"})
+	}
+	var req organizationMemberRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Invalid request format"})
+	}
+	if !validOrgRole(req.Role) {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Role must be owner, manager or viewer."})
+	}
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		var member models.OrganizationMember
+		if err := tx.Where("organization_id = ? AND user_id = ?", *orgID, memberUserID).First(&member).Error; err != nil {
+			return err
+		}
+		if member.Role == models.OrgRoleOwner && req.Role != models.OrgRoleOwner {
+			owners, err := countOwners(tx, *orgID)
+			if err != nil {
+				return err
+			}
+			if owners <= 1 {
+				return errLastOwner
+			}
+		}
+		return tx.Model(&member).Update("role", req.Role).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.JSON(http.StatusNotFound, simpleResponse{Success: false, Message: "Member not found."})
+	}
+	if err != nil {
+		return orgError(c, err)
+	}
+	return c.JSON(http.StatusOK, simpleResponse{Success: true, Message: "Member role updated."})
+}
+
+// RemoveOrganizationMember godoc
+// @Summary Remove an organization member
+// @Description Remove a member from the organization. Owners can remove anyone; any member can remove themselves. The last owner cannot leave.
+// @Tags Organizations
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Param userId path int true "Member user ID"
+// @Success 200 {object} simpleResponse
+// @Failure 400 {object} simpleResponse
+// @Failure 403 {object} simpleResponse
+// @Failure 404 {object} simpleResponse
+// @Router /organizations/{id}/members/{userId} [delete]
+func (s *Server) RemoveOrganizationMember(c echo.Context) error {
+	orgID, err := orgIDParam(c.Param("id"))
+	if err != nil || orgID == nil {
+		return orgError(c, errOrgNotFound)
+	}
+	memberUserID, err := strconv.ParseUint(c.Param("userId"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Invalid user ID"})
+	}
+	minRole := models.OrgRoleOwner
+	if uint(memberUserID) == currentUserID(c) {
+		minRole = models.OrgRoleViewer
+	}
+	if _, err := s.orgMembership(currentUserID(c), *orgID, minRole); err != nil {
+		return orgError(c, err)
+	}
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		var member models.OrganizationMember
+		if err := tx.Where("organization_id = ? AND user_id = ?", *orgID, memberUserID).First(&member).Error; err != nil {
+			return err
+		}
+		if member.Role == models.OrgRoleOwner {
+			owners, err := countOwners(tx, *orgID)
+			if err != nil {
+				return err
+			}
+			if owners <= 1 {
+				return errLastOwner
+			}
+		}
+		return tx.Delete(&member).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.JSON(http.StatusNotFound, simpleResponse{Success: false, Message: "Member not found."})
+	}
+	if err != nil {
+		return orgError(c, err)
+	}
+	return c.JSON(http.StatusOK, simpleResponse{Success: true, Message: "Member removed."})
+}
+
+// GetOrganizationWallet godoc
+// @Summary Get an organization wallet
+// @Description Balance, frozen amount and the last 100 transactions of the organization's shared wallet. Any member can view it.
+// @Tags Organizations
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Success 200 {object} map[string]interface{} "Wallet"
+// @Failure 404 {object} simpleResponse
+// @Router /organizations/{id}/wallet [get]
+func (s *Server) GetOrganizationWallet(c echo.Context) error {
+	orgID, err := orgIDParam(c.Param("id"))
+	if err != nil || orgID == nil {
+		return orgError(c, errOrgNotFound)
+	}
+	if _, err := s.orgMembership(currentUserID(c), *orgID, models.OrgRoleViewer); err != nil {
+		return orgError(c, err)
+	}
+	var org models.Organization
+	if err := s.DB.First(&org, *orgID).Error; err != nil {
+		return orgError(c, errOrgNotFound)
+	}
+
+	var transactions []models.Transaction
+	if err := s.DB.Where("organization_id = ?", org.ID).
+		Order("created_at DESC").
+		Limit(100).
+		Find(&transactions).Error; err != nil {
+		transactions = []models.Transaction{}
+	}
+
+	formattedTransactions := make([]map[string]any, 0, len(transactions))
+	for _, txn := range transactions {
+		txnType := "debit"
+		if txn.TxnType == "credit" || txn.TxnType == "refund" {
+			txnType = "credit"
+		}
+		formattedTransactions = append(formattedTransactions, map[string]any{
+			"id":          txn.ID,
+			"type":        txnType,
+			"amount":      txn.Amount,
+			"description": txn.Description,
+			"timestamp":   txn.CreatedAt,
+			"status":      txn.Status,
+			"user_id":     txn.UserID,
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"success":       true,
+		"balance":       org.Balance,
+		"frozen_amount": org.FrozenAmount,
+		"transactions":  formattedTransactions,
+	})
+}
+
+// AddMoneyToOrganizationWallet godoc
+// @Summary Top up an organization wallet
+// @Description Add money to the organization's shared wallet (dummy payment). Owners and managers only.
+// @Tags Organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Success 200 {object} map[string]interface{} "New balance"
+// @Failure 400 {object} simpleResponse
+// @Failure 403 {object} simpleResponse
+// @Failure 404 {object} simpleResponse
+// @Router /organizations/{id}/wallet/add-money [post]
+func (s *Server) AddMoneyToOrganizationWallet(c echo.Context) error {
+	orgID, err := orgIDParam(c.Param("id"))
+	if err != nil || orgID == nil {
+		return orgError(c, errOrgNotFound)
+	}
+	if _, err := s.orgMembership(currentUserID(c), *orgID, models.OrgRoleManager); err != nil {
+		return orgError(c, err)
+	}
+	var req struct {
+		Amount float64 `json:"amount"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{"success": false, "message": "Invalid request"})
+	}
+	if req.Amount <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]any{"success": false, "message": "Amount must be greater than zero"})
+	}
+
+	var org models.Organization
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the shared wallet so concurrent freezes and refunds are not overwritten
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&org, *orgID).Error; err != nil {
+			return err
+		}
+		balanceBefore := org.Balance
+		org.Balance += req.Amount
+		if err := tx.Model(&org).Update("balance", org.Balance).Error; err != nil {
+			return err
+		}
+		description := "Organization wallet top-up (dummy payment)"
+		return tx.Create(&models.Transaction{
+			UserID:         currentUserEmail(c),
+			OrganizationID: &org.ID,
+			TxnType:        "credit",
+			Amount:         req.Amount,
+			BalanceBefore:  balanceBefore,
+			BalanceAfter:   org.Balance,
+			Description:    &description,
+			Status:         "completed",
+			CreatedAt:      time.Now(),
+			UpdatedAt:      time.Now(),
+		}).Error
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]any{"success": false, "message": "Failed to update balance: " + err.Error()})
+	}
+	s.events.Publish(currentUserEmail(c), events.WalletBalance, map[string]any{
+		"balance":         org.Balance,
+		"frozen_amount":   org.FrozenAmount,
+		"organization_id": org.ID,
+	})
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"success": true,
+		"message": fmt.Sprintf("Successfully added %s to the organization wallet", formatCurrency(req.Amount)),
+		"balance": org.Balance,
+	})
+}
+
+// GetOrganizationSchedules godoc
+// @Summary List organization schedules
+// @Description Active schedules that run the organization's collections. Any member can view them.
+// @Tags Organizations
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Success 200 {object} map[string]interface{} "Schedules"
+// @Failure 404 {object} simpleResponse
+// @Router /organizations/{id}/schedules [get]
+func (s *Server) GetOrganizationSchedules(c echo.Context) error {
+	orgID, err := orgIDParam(c.Param("id"))
+	if err != nil || orgID == nil {
+		return orgError(c, errOrgNotFound)
+	}
+	if _, err := s.orgMembership(currentUserID(c), *orgID, models.OrgRoleViewer); err != nil {
+		return orgError(c, err)
+	}
+	schedules, err := s.SchedulerService.GetSchedulesForOrganization(*orgID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to load schedules."})
+	}
+	return c.JSON(http.StatusOK, map[string]any{"success": true, "data": schedules})
+}
@@ -0,0 +1,187 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+
+	"github.com/frontinsight/backend/internal/models"
+	"github.com/frontinsight/backend/internal/utils"
+)
+
+const (
+	passwordResetExpiry = 30 * time.Minute
+
+	// Limits for reset requests within resetRateWindow
+	maxResetRequestsPerEmail = 3
+	maxResetRequestsPerIP    = 10
+	// Limit for invalid reset tokens submitted from one IP within resetRateWindow
+	maxResetFailuresPerIP = 10
+	resetRateWindow       = 1 * time.Hour
+)
+
+type forgotPasswordRequest struct {
+	Email string `json:"email" example:"user@example.com" binding:"required"`
+}
+
+// ForgotPassword godoc
+// @Summary Request a password reset
+// @Description Send a single-use password reset link to the given email address. The response is the same whether or not the account exists.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body forgotPasswordRequest true "Account email"
+// @Success 200 {object} simpleResponse
+// @Failure 400 {object} simpleResponse
+// @Failure 429 {object} simpleResponse
+// @Router /forgot-password [post]
+func (s *Server) ForgotPassword(c echo.Context) error {
+	var req forgotPasswordRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Invalid payload"})
+	}
+	email := strings.TrimSpace(strings.ToLower(req.Email))
+	if email == "" || !utils.ValidateEmail(email) {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "A valid email address is required."})
+	}
+
+	ipAddress := s.getClientIP(c)
+
+	// Rate limit per email and per IP
+	if s.CountAttemptsByEmail(models.AttemptTypePasswordResetRequest, email, resetRateWindow) >= maxResetRequestsPerEmail ||
+		s.CountAttemptsByIP(models.AttemptTypePasswordResetRequest, ipAddress, resetRateWindow) >= maxResetRequestsPerIP {
+		return c.JSON(http.StatusTooManyRequests, simpleResponse{Success: false, Message: "Too many password reset requests. Please try again later."})
+	}
+	s.RecordAttempt(models.AttemptTypePasswordResetRequest, email, ipAddress, true)
+
+	// Same response whether or not the account exists, to avoid leaking registered emails
+	genericResponse := simpleResponse{Success: true, Message: "If an account exists for this email, a password reset link has been sent."}
+
+	var user models.User
+	if err := s.DB.Where("email = ?", email).First(&user).Error; err != nil || user.ID == 0 {
+		return c.JSON(http.StatusOK, genericResponse)
+	}
+
+	s.cleanupExpiredResets()
+
+	token, err := utils.GenerateToken(32)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to create password reset."})
+	}
+	reset := models.PasswordReset{
+		Email:     email,
+		TokenHash: utils.HashToken(token),
+		IPAddress: ipAddress,
+		ExpiresAt: time.Now().UTC().Add(passwordResetExpiry),
+		IsUsed:    false,
+	}
+	if err := s.DB.Create(&reset).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to create password reset."})
+	}
+
+	if err := s.sendPasswordResetEmail(email, token); err != nil {
+		fmt.Printf("ERROR: Failed to send password reset email to %s: %v\n", email, err)
+		_ = s.DB.Delete(&reset).Error
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to send password reset email. Please try again."})
+	}
+
+	return c.JSON(http.StatusOK, genericResponse)
+}
+
+type resetPasswordRequest struct {
+	Token       string `json:"token" example:"4f9c2a..." binding:"required"`
+	NewPassword string `json:"new_password" example:"NewPassword@123" binding:"required"`
+}
+
+// ResetPassword godoc
+// @Summary Reset password with a token
+// @Description Set a new password using the token from a password reset email. The token can be used once, and all existing sessions are invalidated.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body resetPasswordRequest true "Reset token and new password"
+// @Success 200 {object} simpleResponse
+// @Failure 400 {object} simpleResponse
+// @Failure 429 {object} simpleResponse
+// @Failure 500 {object} simpleResponse
+// @Router /reset-password [post]
+func (s *Server) ResetPassword(c echo.Context) error {
+	var req resetPasswordRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Invalid payload"})
+	}
+	token := strings.TrimSpace(req.Token)
+	newPassword := utils.SanitizeString(req.NewPassword)
+	if token == "" || newPassword == "" {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Token and new password are required."})
+	}
+
+	ipAddress := s.getClientIP(c)
+	if s.CountFailedAttemptsByIP(models.AttemptTypePasswordResetConfirm, ipAddress, resetRateWindow) >= maxResetFailuresPerIP {
+		return c.JSON(http.StatusTooManyRequests, simpleResponse{Success: false, Message: "Too many invalid reset attempts. Please try again later."})
+	}
+
+	var reset models.PasswordReset
+	if err := s.DB.Where("token_hash = ? AND is_used = false", utils.HashToken(token)).First(&reset).Error; err != nil || reset.ID == 0 {
+		s.RecordAttempt(models.AttemptTypePasswordResetConfirm, "", ipAddress, false)
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Invalid or expired reset link."})
+	}
+	if reset.ExpiresAt.Before(time.Now().UTC()) {
+		s.RecordAttempt(models.AttemptTypePasswordResetConfirm, reset.Email, ipAddress, false)
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Reset link has expired. Please request a new one."})
+	}
+
+	// Validate password strength
+	if valid, msg := utils.ValidatePassword(newPassword); !valid {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: msg})
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), 12)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to reset password."})
+	}
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Where("email = ?", reset.Email).First(&user).Error; err != nil {
+			return err
+		}
+		// The user proved control of the mailbox, so the address counts as verified
+		user.Password = string(hash)
+		user.IsVerified = true
+		user.FailedLoginCount = 0
+		user.LockedUntil = nil
+		if err := tx.Save(&user).Error; err != nil {
+			return err
+		}
+		if err := s.revokeAllSessions(tx, user.ID); err != nil {
+			return err
+		}
+		// Burn this token and any other outstanding tokens for the same email
+		return tx.Model(&models.PasswordReset{}).
+			Where("email = ? AND is_used = false", reset.Email).
+			Update("is_used", true).Error
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to reset password."})
+	}
+
+	s.RecordAttempt(models.AttemptTypePasswordResetConfirm, reset.Email, ipAddress, true)
+
+	return c.JSON(http.StatusOK, simpleResponse{Success: true, Message: "Password has been reset. Please log in with your new password."})
+}
+
+func (s *Server) cleanupExpiredResets() {
+	_ = s.DB.Where("expires_at < ?", time.Now().UTC()).Delete(&models.PasswordReset{}).Error
+}
+
+func (s *Server) sendPasswordResetEmail(email, token string) error {
+	link := fmt.Sprintf("%s/reset-password?token=%s", s.Cfg.FrontendURL, url.QueryEscape(token))
+	return s.sendEmail(email, "password_reset", map[string]any{"Link": link, "Minutes": int(passwordResetExpiry.Minutes())})
+}
@@ -0,0 +1,214 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+
+	"github.com/frontinsight/backend/internal/models"
+	"github.com/frontinsight/backend/internal/utils"
+)
+
+// maxProfileFieldLength caps free-text profile fields such as city and company
+const maxProfileFieldLength = 100
+
+// profilePayload is the user object returned by GetProfile and UpdateProfile
+func (s *Server) profilePayload(c echo.Context, user *models.User) map[string]any {
+	permissions := []string{}
+	if role := s.currentRole(c); role != nil {
+		permissions = role.Permissions
+	}
+	payload := map[string]any{
+		"id":                    user.ID,
+		"email":                 user.Email,
+		"name":                  user.Name,
+		"role":                  user.Role,
+		"permissions":           permissions,
+		"is_verified":           user.IsVerified,
+		"totp_enabled":          user.TOTPEnabled,
+		"city":                  user.City,
+		"state":                 user.State,
+		"country":               user.Country,
+		"mobile_number":         user.MobileNumber,
+		"timezone":              user.Timezone,
+		"business_type":         user.BusinessType,
+		"company":               user.Company,
+		"balance":               user.Balance,
+		"frozen_amount":         user.FrozenAmount,
+		"last_login_at":         user.LastLoginAt,
+		"created_at":            user.CreatedAt,
+		"deletion_scheduled_at": user.DeletionScheduledAt,
+	}
+	// Lets the frontend show that support is viewing the account
+	if adminID := impersonatorID(c); adminID != 0 {
+		payload["impersonated_by"] = adminID
+	}
+	return payload
+}
+
+// newProfileChange builds a history entry when a field's value changes; an
+// unset field counts as empty
+func (s *Server) newProfileChange(c echo.Context, userID uint, field string, oldValue, newValue *string) *models.ProfileChange {
+	if stringValue(oldValue) == stringValue(newValue) {
+		return nil
+	}
+	return &models.ProfileChange{
+		UserID:    userID,
+		Field:     field,
+		OldValue:  copyString(oldValue),
+		NewValue:  copyString(newValue),
+		ChangedBy: currentUserID(c),
+		IPAddress: s.getClientIP(c),
+	}
+}
+
+func stringValue(p *string) string {
+	if p == nil {
+		return ""
+	}
+	return *p
+}
+
+func copyString(p *string) *string {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
+
+func validateTimezone(timezone string) (bool, string) {
+	if !isValidTimezone(timezone) {
+		return false, "Invalid timezone format"
+	}
+	return true, ""
+}
+
+// updateProfileRequest changes only the fields that are present. An empty
+// string clears an optional field; the name cannot be cleared.
+type updateProfileRequest struct {
+	Name         *string `json:"name" example:"John Doe"`
+	City         *string `json:"city" example:"Mumbai"`
+	State        *string `json:"state" example:"Maharashtra"`
+	Country      *string `json:"country" example:"India"`
+	MobileNumber *string `json:"mobile_number" example:"+91-9876543210"`
+	Timezone     *string `json:"timezone" example:"Asia/Kolkata"`
+	BusinessType *string `json:"business_type" example:"Hotel"`
+	Company      *string `json:"company" example:"ABC Hotels"`
+}
+
+// UpdateProfile godoc
+// @Summary Update profile
+// @Description Change the authenticated user's name, location, mobile number, timezone, business type or company. Only fields in the request change; an empty string clears an optional field. Every change is kept in the profile history.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body updateProfileRequest true "Fields to change"
+// @Success 200 {object} map[string]interface{} "Updated profile"
+// @Failure 400 {object} simpleResponse
+// @Failure 401 {object} simpleResponse
+// @Failure 500 {object} simpleResponse
+// @Router /auth/profile [put]
+func (s *Server) UpdateProfile(c echo.Context) error {
+	var req updateProfileRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Invalid payload"})
+	}
+	user, err := s.currentUser(c)
+	if err != nil {
+		return unauthenticated(c)
+	}
+
+	updates := map[string]any{}
+	var changes []models.ProfileChange
+	record := func(column string, oldValue, newValue *string) {
+		if change := s.newProfileChange(c, user.ID, column, oldValue, newValue); change != nil {
+			changes = append(changes, *change)
+			updates[column] = newValue
+		}
+	}
+
+	if req.Name != nil {
+		name := utils.SanitizeString(*req.Name)
+		if valid, msg := utils.ValidateName(name); !valid {
+			return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: msg})
+		}
+		record("name", &user.Name, &name)
+	}
+
+	optional := []struct {
+		column   string
+		label    string
+		value    *string
+		current  *string
+		validate func(string) (bool, string)
+	}{
+		{"city", "City", req.City, user.City, nil},
+		{"state", "State", req.State, user.State, nil},
+		{"country", "Country", req.Country, user.Country, nil},
+		{"mobile_number", "Mobile number", req.MobileNumber, user.MobileNumber, utils.ValidateMobileNumber},
+		{"timezone", "Timezone", req.Timezone, user.Timezone, validateTimezone},
+		{"business_type", "Business type", req.BusinessType, user.BusinessType, nil},
+		{"company", "Company", req.Company, user.Company, nil},
+	}
+	for _, f := range optional {
+		if f.value == nil {
+			continue
+		}
+		value := utils.SanitizeString(*f.value)
+		var next *string
+		if value != "" {
+			if len(value) > maxProfileFieldLength {
+				return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: fmt.Sprintf("%s must be less than %d characters", f.label, maxProfileFieldLength)})
+			}
+			if f.validate != nil {
+				if valid, msg := f.validate(value); !valid {
+					return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: msg})
+				}
+			}
+			next = &value
+		}
+		record(f.column, f.current, next)
+	}
+
+	if len(changes) > 0 {
+		err = s.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(user).Updates(updates).Error; err != nil {
+				return err
+			}
+			return tx.Create(&changes).Error
+		})
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to update profile."})
+		}
+		if err := s.DB.First(user, user.ID).Error; err != nil {
+			return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to update profile."})
+		}
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"success": true,
+		"message": "Profile updated.",
+		"user":    s.profilePayload(c, user),
+	})
+}
+
+// GetProfileHistory godoc
+// @Summary Profile change history
+// @Description List the latest changes to the authenticated user's profile, newest first
+// @Tags Authentication
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Profile changes"
+// @Failure 401 {object} simpleResponse
+// @Router /auth/profile/history [get]
+func (s *Server) GetProfileHistory(c echo.Context) error {
+	var changes []models.ProfileChange
+	if err := s.DB.Where("user_id = ?", currentUserID(c)).Order("id DESC").Limit(100).Find(&changes).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to load profile history."})
+	}
+	return c.JSON(http.StatusOK, map[string]any{"success": true, "data": changes})
+}
@@ -0,0 +1,254 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+
+	"github.com/frontinsight/backend/internal/models"
+)
+
+var (
+	roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,49}$`)
+	errRoleInUse    = errors.New("role is assigned to users")
+)
+
+// canGrant reports whether the authenticated user holds every permission in the list
+func (s *Server) canGrant(c echo.Context, permissions []string) bool {
+	role := s.currentRole(c)
+	if role == nil {
+		return false
+	}
+	for _, p := range permissions {
+		if !role.HasPermission(p) {
+			return false
+		}
+	}
+	return true
+}
+
+// canGrantRole reports whether the authenticated user holds every permission of the named role
+func (s *Server) canGrantRole(c echo.Context, roleName string) bool {
+	role := s.loadRole(roleName)
+	if role == nil {
+		return true
+	}
+	return s.canGrant(c, role.Permissions)
+}
+
+// normalizePermissions validates and de-duplicates permissions
+func normalizePermissions(permissions []string) ([]string, error) {
+	known := make(map[string]bool, len(models.AllPermissions))
+	for _, p := range models.AllPermissions {
+		known[p] = true
+	}
+	seen := make(map[string]bool, len(permissions))
+	out := make([]string, 0, len(permissions))
+	for _, p := range permissions {
+		p = strings.TrimSpace(p)
+		if !known[p] {
+			return nil, fmt.Errorf("unknown permission: %s", p)
+		}
+		if !seen[p] {
+			seen[p] = true
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+type roleRequest struct {
+	Name        string   `json:"name" example:"billing_support"` // required when creating, ignored on update
+	Description *string  `json:"description" example:"Handles wallet disputes"`
+	Permissions []string `json:"permissions" example:"admin.access,users.read,billing.adjust"`
+}
+
+// ListRoles godoc
+// @Summary List roles
+// @Description List role definitions with their permissions and how many users hold each
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Roles and known permissions"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Router /admin/roles [get]
+func (s *Server) ListRoles(c echo.Context) error {
+	var roles []models.Role
+	if err := s.DB.Order("name").Find(&roles).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]any{"success": false, "message": "Failed to load roles"})
+	}
+
+	var counts []struct {
+		Role  string
+		Count int64
+	}
+	s.DB.Model(&models.User{}).Select("role, count(*) as count").Group("role").Scan(&counts)
+	userCounts := make(map[string]int64, len(counts))
+	for _, rc := range counts {
+		userCounts[rc.Role] = rc.Count
+	}
+
+	data := make([]map[string]any, 0, len(roles))
+	for _, r := range roles {
+		data = append(data, map[string]any{
+			"name":        r.Name,
+			"description": r.Description,
+			"permissions": r.Permissions,
+			"system":      r.System,
+			"user_count":  userCounts[r.Name],
+			"updated_at":  r.UpdatedAt,
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"success":     true,
+		"data":        data,
+		"permissions": models.AllPermissions,
+	})
+}
+
+// CreateRole godoc
+// @Summary Create a role
+// @Description Create a role from known permissions. You can only grant permissions your own role has.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body roleRequest true "Role definition"
+// @Success 201 {object} map[string]interface{} "Role created"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 409 {object} map[string]string "Role exists"
+// @Router /admin/roles [post]
+func (s *Server) CreateRole(c echo.Context) error {
+	var req roleRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{"success": false, "message": "Invalid request body"})
+	}
+	name := strings.TrimSpace(strings.ToLower(req.Name))
+	if !roleNamePattern.MatchString(name) {
+		return c.JSON(http.StatusBadRequest, map[string]any{"success": false, "message": "Role name must be 2-50 lowercase letters, digits or underscores"})
+	}
+	permissions, err := normalizePermissions(req.Permissions)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{"success": false, "message": err.Error()})
+	}
+	if !s.canGrant(c, permissions) {
+		return c.JSON(http.StatusForbidden, map[string]any{"success": false, "message": "You cannot grant permissions you do not have"})
+	}
+	if s.loadRole(name) != nil {
+		return c.JSON(http.StatusConflict, map[string]any{"success": false, "message": "A role with this name already exists"})
+	}
+
+	role := models.Role{Name: name, Permissions: pq.StringArray(permissions)}
+	if req.Description != nil {
+		role.Description = strings.TrimSpace(*req.Description)
+	}
+	if err := s.DB.Create(&role).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]any{"success": false, "message": "Failed to create role"})
+	}
+
+	s.logAdminActivity(currentUserID(c), "create", "role", nil, fmt.Sprintf("Created role %s with permissions %v", role.Name, permissions), c)
+	return c.JSON(http.StatusCreated, map[string]any{"success": true, "data": role})
+}
+
+// UpdateRole godoc
+// @Summary Update a role
+// @Description Change a role's description or permissions. super_admin cannot be changed, and you can only grant or remove permissions your own role has.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "Role name"
+// @Param request body roleRequest true "New description and/or permissions"
+// @Success 200 {object} map[string]interface{} "Role updated"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Role not found"
+// @Router /admin/roles/{name} [put]
+func (s *Server) UpdateRole(c echo.Context) error {
+	role := s.loadRole(c.Param("name"))
+	if role == nil {
+		return c.JSON(http.StatusNotFound, map[string]any{"success": false, "message": "Role not found"})
+	}
+	if role.Name == models.RoleSuperAdmin {
+		return c.JSON(http.StatusForbidden, map[string]any{"success": false, "message": "The super_admin role always has every permission"})
+	}
+	var req roleRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{"success": false, "message": "Invalid request body"})
+	}
+
+	updates := map[string]any{"updated_at": time.Now().UTC()}
+	if req.Description != nil {
+		updates["description"] = strings.TrimSpace(*req.Description)
+	}
+	if req.Permissions != nil {
+		permissions, err := normalizePermissions(req.Permissions)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]any{"success": false, "message": err.Error()})
+		}
+		if !s.canGrant(c, permissions) || !s.canGrant(c, role.Permissions) {
+			return c.JSON(http.StatusForbidden, map[string]any{"success": false, "message": "You cannot change a role with permissions you do not have"})
+		}
+		updates["permissions"] = pq.StringArray(permissions)
+	}
+
+	if err := s.DB.Model(role).Updates(updates).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]any{"success": false, "message": "Failed to update role"})
+	}
+
+	s.logAdminActivity(currentUserID(c), "update", "role", nil, fmt.Sprintf("Updated role %s: %v", role.Name, updates), c)
+	return c.JSON(http.StatusOK, map[string]any{"success": true, "data": s.loadRole(role.Name)})
+}
+
+// DeleteRole godoc
+// @Summary Delete a role
+// @Description Delete a custom role. Built-in roles and roles still assigned to users cannot be deleted.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "Role name"
+// @Success 200 {object} map[string]interface{} "Role deleted"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 404 {object} map[string]string "Role not found"
+// @Router /admin/roles/{name} [delete]
+func (s *Server) DeleteRole(c echo.Context) error {
+	role := s.loadRole(c.Param("name"))
+	if role == nil {
+		return c.JSON(http.StatusNotFound, map[string]any{"success": false, "message": "Role not found"})
+	}
+	if role.System {
+		return c.JSON(http.StatusBadRequest, map[string]any{"success": false, "message": "Built-in roles cannot be deleted"})
+	}
+	if !s.canGrant(c, role.Permissions) {
+		return c.JSON(http.StatusForbidden, map[string]any{"success": false, "message": "You cannot delete a role with permissions you do not have"})
+	}
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var users int64
+		if err := tx.Model(&models.User{}).Where("role = ?", role.Name).Count(&users).Error; err != nil {
+			return err
+		}
+		if users > 0 {
+			return errRoleInUse
+		}
+		return tx.Delete(role).Error
+	})
+	if errors.Is(err, errRoleInUse) {
+		return c.JSON(http.StatusBadRequest, map[string]any{"success": false, "message": "Reassign the users holding this role before deleting it"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]any{"success": false, "message": "Failed to delete role"})
+	}
+
+	s.logAdminActivity(currentUserID(c), "delete", "role", nil, "Deleted role "+role.Name, c)
+	return c.JSON(http.StatusOK, map[string]any{"success": true, "message": "Role deleted"})
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -9,15 +10,18 @@ import (
 	"github.com/frontinsight/backend/internal/models"
 	"github.com/frontinsight/backend/internal/services"
 	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
 )
 
 type SchedulerHandler struct {
+	server           *Server // ownership checks
 	schedulerService *services.SchedulerService
 	timezoneService  *services.TimezoneService
 }
 
-func NewSchedulerHandler(schedulerService *services.SchedulerService, timezoneService *services.TimezoneService) *SchedulerHandler {
+func NewSchedulerHandler(server *Server, schedulerService *services.SchedulerService, timezoneService *services.TimezoneService) *SchedulerHandler {
 	return &SchedulerHandler{
+		server:           server,
 		schedulerService: schedulerService,
 		timezoneService:  timezoneService,
 	}
@@ -32,6 +36,7 @@ func NewSchedulerHandler(schedulerService *services.SchedulerService, timezoneSe
 // @Param request body CreateScheduleRequest true "Schedule creation data"
 // @Success 201 {object} models.Schedule "Schedule created successfully"
 // @Failure 400 {object} map[string]string "Bad request"
+// @Failure 404 {object} map[string]string "Collection or search not found"
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /schedules [post]
 func (h *SchedulerHandler) CreateSchedule(c echo.Context) error {
@@ -45,18 +50,27 @@ func (h *SchedulerHandler) CreateSchedule(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 	}
 
+	// Only collections and searches the caller can run may be scheduled
+	if req.CollectionID != nil {
+		if _, err := h.server.ownedCollection(c, *req.CollectionID, accessWrite); err != nil {
+			return accessError(c, err, "Collection not found")
+		}
+	}
+	if req.SearchID != nil {
+		if _, err := h.server.ownedSearch(c, *req.SearchID, accessWrite); err != nil {
+			return accessError(c, err, "Search not found")
+		}
+	}
+
 	// Get user from context
-	user := c.Get("user").(*models.User)
+	userEmail := currentUserEmail(c)
 
-	// Get user's timezone
-	userTimezone := "UTC" // Default fallback
-	if user.Timezone != nil && *user.Timezone != "" {
-		userTimezone = *user.Timezone
-	}
+	// Get user's timezone (falls back to UTC)
+	userTimezone, _ := h.timezoneService.GetUserTimezone(userEmail)
 
 	// Create schedule
 	schedule, err := h.schedulerService.CreateSchedule(
-		user.Email,
+		userEmail,
 		req.Name,
 		req.ScheduleType,
 		req.ScheduleData,
@@ -80,16 +94,16 @@ func (h *SchedulerHandler) CreateSchedule(c echo.Context) error {
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /schedules [get]
 func (h *SchedulerHandler) GetSchedules(c echo.Context) error {
-	user := c.Get("user").(*models.User)
+	userEmail := currentUserEmail(c)
 
-	fmt.Printf("Debug: Getting schedules for user: %s (ID: %d)\n", user.Email, user.ID)
-	schedules, err := h.schedulerService.GetSchedulesForUser(user.Email)
+	fmt.Printf("Debug: Getting schedules for user: %s (ID: %d)\n", userEmail, currentUserID(c))
+	schedules, err := h.schedulerService.GetSchedulesForUser(userEmail)
 	if err != nil {
 		fmt.Printf("Error getting schedules: %v\n", err)
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
 
-	fmt.Printf("Debug: Found %d schedules for user %s\n", len(schedules), user.Email)
+	fmt.Printf("Debug: Found %d schedules for user %s\n", len(schedules), userEmail)
 
 	// Always return UTC timestamps - frontend will handle timezone conversion
 	return c.JSON(http.StatusOK, map[string]any{"success": true, "data": schedules})
@@ -102,6 +116,7 @@ func (h *SchedulerHandler) GetSchedules(c echo.Context) error {
 // @Param id path int true "Schedule ID"
 // @Success 200 {object} map[string]string "Schedule deleted successfully"
 // @Failure 400 {object} map[string]string "Bad request"
+// @Failure 404 {object} map[string]string "Schedule not found"
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /schedules/{id} [delete]
 func (h *SchedulerHandler) DeleteSchedule(c echo.Context) error {
@@ -111,9 +126,10 @@ func (h *SchedulerHandler) DeleteSchedule(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid schedule ID"})
 	}
 
-	user := c.Get("user").(*models.User)
-
-	err = h.schedulerService.DeleteSchedule(uint(scheduleID), user.Email)
+	err = h.schedulerService.DeleteSchedule(uint(scheduleID), currentUserEmail(c))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Schedule not found"})
+	}
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
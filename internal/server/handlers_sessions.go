@@ -0,0 +1,233 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/frontinsight/backend/internal/models"
+)
+
+// sessionView is a session as shown to its owner or an admin
+type sessionView struct {
+	models.Session
+	Current bool `json:"current"`
+}
+
+// activeSessions returns the user's sessions that can still be refreshed, most recently used first
+func (s *Server) activeSessions(userID uint) ([]models.Session, error) {
+	var sessions []models.Session
+	err := s.DB.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now().UTC()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// ListSessions godoc
+// @Summary List active sessions
+// @Description List the devices currently signed in to the authenticated user's account
+// @Tags Authentication
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Active sessions"
+// @Failure 401 {object} simpleResponse
+// @Failure 500 {object} simpleResponse
+// @Router /auth/sessions [get]
+func (s *Server) ListSessions(c echo.Context) error {
+	userID := currentUserID(c)
+	if userID == 0 {
+		return unauthenticated(c)
+	}
+
+	sessions, err := s.activeSessions(userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to load sessions."})
+	}
+
+	var currentSessionID uint
+	if claims := currentClaims(c); claims != nil {
+		currentSessionID = claims.SessionID
+	}
+	views := make([]sessionView, 0, len(sessions))
+	for _, session := range sessions {
+		views = append(views, sessionView{Session: session, Current: session.ID == currentSessionID})
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{"success": true, "sessions": views})
+}
+
+// RevokeSession godoc
+// @Summary Revoke a session
+// @Description Sign out one of the authenticated user's devices
+// @Tags Authentication
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Session ID"
+// @Success 200 {object} simpleResponse
+// @Failure 400 {object} simpleResponse
+// @Failure 404 {object} simpleResponse
+// @Router /auth/sessions/{id} [delete]
+func (s *Server) RevokeSession(c echo.Context) error {
+	userID := currentUserID(c)
+	if userID == 0 {
+		return unauthenticated(c)
+	}
+	sessionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Invalid session ID"})
+	}
+
+	var session models.Session
+	if err := s.DB.Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).First(&session).Error; err != nil {
+		return c.JSON(http.StatusNotFound, simpleResponse{Success: false, Message: "Session not found"})
+	}
+	if err := s.revokeSession(s.DB, session.ID); err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to revoke session."})
+	}
+
+	return c.JSON(http.StatusOK, simpleResponse{Success: true, Message: "Session revoked."})
+}
+
+// RevokeAllSessions godoc
+// @Summary Revoke all sessions
+// @Description Sign out every other device. Pass include_current=true to sign out this device as well.
+// @Tags Authentication
+// @Produce json
+// @Security BearerAuth
+// @Param include_current query bool false "Also revoke the session making this request"
+// @Success 200 {object} simpleResponse
+// @Failure 401 {object} simpleResponse
+// @Failure 500 {object} simpleResponse
+// @Router /auth/sessions [delete]
+func (s *Server) RevokeAllSessions(c echo.Context) error {
+	userID := currentUserID(c)
+	if userID == 0 {
+		return unauthenticated(c)
+	}
+
+	var err error
+	claims := currentClaims(c)
+	if c.QueryParam("include_current") == "true" || claims == nil {
+		err = s.revokeAllSessions(s.DB, userID)
+	} else {
+		err = s.revokeOtherSessions(s.DB, userID, claims.SessionID)
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to revoke sessions."})
+	}
+
+	return c.JSON(http.StatusOK, simpleResponse{Success: true, Message: "Sessions revoked."})
+}
+
+// AdminRevokeUserSession godoc
+// @Summary Revoke a user's session
+// @Description Sign out one device of the given user
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param sessionId path int true "Session ID"
+// @Success 200 {object} map[string]interface{} "Session revoked"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 404 {object} map[string]string "Session not found"
+// @Router /admin/users/{id}/sessions/{sessionId} [delete]
+func (s *Server) AdminRevokeUserSession(c echo.Context) error {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{"success": false, "message": "Invalid user ID"})
+	}
+	sessionID, err := strconv.Atoi(c.Param("sessionId"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{"success": false, "message": "Invalid session ID"})
+	}
+
+	var session models.Session
+	if err := s.DB.Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).First(&session).Error; err != nil {
+		return c.JSON(http.StatusNotFound, map[string]any{"success": false, "message": "Session not found"})
+	}
+	if err := s.revokeSession(s.DB, session.ID); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]any{"success": false, "message": "Failed to revoke session"})
+	}
+
+	targetID := uint(userID)
+	s.logAdminActivity(currentUserID(c), "revoke_session", "user", &targetID, fmt.Sprintf("Revoked session %d (%s)", session.ID, session.Device), c)
+
+	return c.JSON(http.StatusOK, map[string]any{"success": true, "message": "Session revoked"})
+}
+
+// AdminRevokeUserSessions godoc
+// @Summary Revoke all sessions of a user
+// @Description Sign the given user out of every device
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} map[string]interface{} "Sessions revoked"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 404 {object} map[string]string "User not found"
+// @Router /admin/users/{id}/sessions [delete]
+func (s *Server) AdminRevokeUserSessions(c echo.Context) error {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{"success": false, "message": "Invalid user ID"})
+	}
+
+	var targetUser models.User
+	if err := s.DB.First(&targetUser, userID).Error; err != nil {
+		return c.JSON(http.StatusNotFound, map[string]any{"success": false, "message": "User not found"})
+	}
+	if err := s.revokeAllSessions(s.DB, targetUser.ID); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]any{"success": false, "message": "Failed to revoke sessions"})
+	}
+
+	s.logAdminActivity(currentUserID(c), "revoke_sessions", "user", &targetUser.ID, fmt.Sprintf("Revoked all sessions of user: %s", targetUser.Email), c)
+
+	return c.JSON(http.StatusOK, map[string]any{"success": true, "message": "All sessions revoked"})
+}
+
+// describeDevice turns a user agent into a short label such as "Chrome on Windows"
+func describeDevice(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	if ua == "" {
+		return "Unknown device"
+	}
+
+	browser := "Unknown browser"
+	switch {
+	case strings.Contains(ua, "edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "opr/") || strings.Contains(ua, "opera"):
+		browser = "Opera"
+	case strings.Contains(ua, "firefox/") || strings.Contains(ua, "fxios/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "chrome/") || strings.Contains(ua, "crios/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "safari/"):
+		browser = "Safari"
+	case strings.Contains(ua, "curl/"), strings.Contains(ua, "postman"), strings.Contains(ua, "python"), strings.Contains(ua, "go-http-client"):
+		browser = "API client"
+	}
+
+	platform := ""
+	switch {
+	case strings.Contains(ua, "iphone") || strings.Contains(ua, "ipad"):
+		platform = "iOS"
+	case strings.Contains(ua, "android"):
+		platform = "Android"
+	case strings.Contains(ua, "windows"):
+		platform = "Windows"
+	case strings.Contains(ua, "mac os") || strings.Contains(ua, "macintosh"):
+		platform = "macOS"
+	case strings.Contains(ua, "linux"):
+		platform = "Linux"
+	}
+
+	if platform == "" {
+		return browser
+	}
+	return browser + " on " + platform
+}
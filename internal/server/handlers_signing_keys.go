@@ -0,0 +1,81 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/frontinsight/backend/internal/models"
+)
+
+// rotateSigningKeys replaces the signing key when it is due and picks up keys
+// rotated by other instances
+func (s *Server) rotateSigningKeys() {
+	if !s.keys.asymmetric() {
+		return
+	}
+	if key, err := s.keys.Rotate(false); err != nil {
+		fmt.Printf("ERROR: Failed to rotate signing keys: %v\n", err)
+	} else if key != nil {
+		fmt.Printf("Created token signing key %s (%s)\n", key.KID, key.Algorithm)
+	}
+}
+
+// JWKS godoc
+// @Summary Token verification keys
+// @Description Public keys (JWKS, RFC 7517) that verify Front Insight access tokens. Match a token's kid header to a key. Empty when tokens are signed with a shared secret.
+// @Tags Authentication
+// @Produce json
+// @Success 200 {object} map[string]interface{} "JSON Web Key Set"
+// @Router /.well-known/jwks.json [get]
+func (s *Server) JWKS(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "public, max-age=300")
+	return c.JSON(http.StatusOK, s.keys.JWKS())
+}
+
+// ListSigningKeys godoc
+// @Summary List token signing keys
+// @Description Keys that sign or still verify access tokens, newest first. Private keys are never returned.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Signing keys"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Router /admin/signing-keys [get]
+func (s *Server) ListSigningKeys(c echo.Context) error {
+	var keys []models.SigningKey
+	if err := s.DB.Order("created_at DESC, id DESC").Find(&keys).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]any{"success": false, "message": "Failed to load signing keys"})
+	}
+	return c.JSON(http.StatusOK, map[string]any{
+		"success":   true,
+		"algorithm": s.Cfg.JWTAlgorithm,
+		"rotation":  s.Cfg.JWTKeyRotation.String(),
+		"data":      keys,
+	})
+}
+
+// RotateSigningKey godoc
+// @Summary Rotate the token signing key
+// @Description Start signing with a new key now, for example after a suspected leak. The old key keeps verifying until the tokens it signed expire.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "New signing key"
+// @Failure 400 {object} map[string]string "Tokens are signed with a shared secret"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Router /admin/signing-keys/rotate [post]
+func (s *Server) RotateSigningKey(c echo.Context) error {
+	if !s.keys.asymmetric() {
+		return c.JSON(http.StatusBadRequest, map[string]any{"success": false, "message": "Key rotation needs JWT_ALGORITHM set to RS256 or EdDSA"})
+	}
+	key, err := s.keys.Rotate(true)
+	if err != nil {
+		fmt.Printf("ERROR: Failed to rotate signing key: %v\n", err)
+		return c.JSON(http.StatusInternalServerError, map[string]any{"success": false, "message": "Failed to rotate signing key"})
+	}
+
+	s.logAdminActivity(currentUserID(c), "rotate", "signing_key", &key.ID, fmt.Sprintf("Rotated token signing key to %s (%s)", key.KID, key.Algorithm), c)
+	return c.JSON(http.StatusOK, map[string]any{"success": true, "data": key})
+}
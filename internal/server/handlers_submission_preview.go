@@ -0,0 +1,109 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/frontinsight/backend/internal/models"
+	"github.com/frontinsight/backend/internal/ql2"
+	"github.com/frontinsight/backend/internal/services"
+)
+
+// previewSubmission describes what submitting jobs would send to QL2 and
+// cost, without creating a search or freezing money
+func (s *Server) previewSubmission(c echo.Context, userID, collectionName string, jobs []jobData) error {
+	amount, err := services.CalculateSearchAmountFromJobs(jobs, s.DB)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]any{"success": false, "message": "Failed to calculate search amount: " + err.Error()})
+	}
+
+	rows, skipped := ql2.BuildRows(jobs, userID, collectionName)
+	fields := make([][]string, 0, len(rows))
+	for _, r := range rows {
+		fields = append(fields, r.Fields())
+	}
+	if skipped == nil {
+		skipped = []ql2.SkippedJob{}
+	}
+	resp := map[string]any{
+		"success": true,
+		"jobs":    len(jobs),
+		"columns": ql2.InputColumns,
+		"rows":    fields,
+		"skipped": skipped,
+		"amount":  amount,
+	}
+	csv, err := ql2.EncodeInput(rows)
+	resp["csv"] = csv
+	switch {
+	case len(skipped) > 0:
+		// Submitting is refused rather than charging for jobs QL2 cannot run
+		resp["message"] = "Submitting would be refused until these jobs are fixed: " + ql2.DescribeSkipped(skipped)
+	case err != nil:
+		resp["message"] = "No rows would be sent to QL2; submitting these jobs would fail"
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// PreviewSaveMultiForm godoc
+// @Summary Preview a search submission
+// @Description Show the QL2 rows that starting these jobs would send, the jobs QL2 cannot run and why (submitting is refused until they are fixed), and the amount that would be frozen. Nothing is saved or charged.
+// @Tags Searches
+// @Accept json
+// @Produce json
+// @Param request body saveMultiFormRequest true "Search job data; action and scheduleTs are ignored"
+// @Success 200 {object} map[string]interface{} "Preview"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /save-multi-form/preview [post]
+func (s *Server) PreviewSaveMultiForm(c echo.Context) error {
+	var req saveMultiFormRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{"success": false, "message": "Invalid payload"})
+	}
+	if len(req.Jobs) == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]any{"success": false, "message": "No jobs provided"})
+	}
+	if req.OrganizationID != nil {
+		if _, err := s.orgMembership(currentUserID(c), *req.OrganizationID, models.OrgRoleManager); err != nil {
+			return orgError(c, err)
+		}
+	}
+	collectionName := ""
+	if req.CollectionName != nil {
+		collectionName = strings.TrimSpace(*req.CollectionName)
+	}
+	return s.previewSubmission(c, currentUserEmail(c), collectionName, req.Jobs)
+}
+
+// PreviewCollection godoc
+// @Summary Preview a collection submission
+// @Description Show the QL2 rows that submitting the collection would send, the items QL2 cannot run and why (submitting is refused until they are fixed), and the amount that would be frozen. Nothing is saved or charged.
+// @Tags Collections
+// @Produce json
+// @Param id path int true "Collection ID"
+// @Success 200 {object} map[string]interface{} "Preview"
+// @Failure 404 {object} map[string]interface{} "Collection not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /collection/:id/preview [post]
+func (s *Server) PreviewCollection(c echo.Context) error {
+	id, _ := strconv.Atoi(c.Param("id"))
+	col, err := s.ownedCollection(c, id, accessWrite)
+	if err != nil {
+		return accessError(c, err, "Collection not found")
+	}
+	// Same submitter as SubmitCollection, whose email goes into the rows
+	submitter := col.UserID
+	if col.OrganizationID != nil {
+		submitter = currentUserEmail(c)
+	}
+
+	var items []models.CollectionItem
+	if err := s.DB.Where("collection_id = ?", col.ID).Find(&items).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]any{"success": false, "message": "Failed to load collection items"})
+	}
+	return s.previewSubmission(c, submitter, col.Name, toJobsFromItems(items))
+}
@@ -0,0 +1,421 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+
+	"github.com/frontinsight/backend/internal/models"
+	"github.com/frontinsight/backend/internal/utils"
+)
+
+const (
+	totpIssuer = "Front Insight"
+
+	// mfaTokenExpiry is how long a user has to enter the second factor after the password
+	mfaTokenExpiry = 5 * time.Minute
+
+	// Limit for wrong second-factor codes per account within twoFactorRateWindow
+	maxTwoFactorFailures = 5
+	twoFactorRateWindow  = 15 * time.Minute
+
+	recoveryCodeCount = 10
+)
+
+type loginTwoFactorRequest struct {
+	MFAToken string `json:"mfa_token" example:"eyJhbGciOi..." binding:"required"`
+	Code     string `json:"code" example:"123456" binding:"required"` // authenticator code or recovery code
+}
+
+// LoginTwoFactor godoc
+// @Summary Complete a two-factor login
+// @Description Finish logging in by submitting the mfa_token returned by /login together with an authenticator or recovery code
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body loginTwoFactorRequest true "MFA token and code"
+// @Success 200 {object} map[string]interface{} "Login successful"
+// @Failure 400 {object} simpleResponse
+// @Failure 401 {object} simpleResponse
+// @Failure 429 {object} simpleResponse
+// @Router /login/2fa [post]
+func (s *Server) LoginTwoFactor(c echo.Context) error {
+	var req loginTwoFactorRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Invalid payload"})
+	}
+	if strings.TrimSpace(req.MFAToken) == "" || strings.TrimSpace(req.Code) == "" {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "MFA token and code are required."})
+	}
+
+	claims, err := utils.ValidateJWT(req.MFAToken, s.keys.Lookup)
+	if err != nil || claims.TokenUse != utils.TokenUseMFA {
+		return c.JSON(http.StatusUnauthorized, simpleResponse{Success: false, Message: "Login expired. Please log in again."})
+	}
+
+	ipAddress := s.getClientIP(c)
+	if s.twoFactorThrottled(claims.Email) {
+		return tooManyTwoFactorAttempts(c)
+	}
+
+	var user models.User
+	if err := s.DB.First(&user, claims.UserID).Error; err != nil || !user.TOTPEnabled {
+		return c.JSON(http.StatusUnauthorized, simpleResponse{Success: false, Message: "Login expired. Please log in again."})
+	}
+	// The account may have been locked since the password step
+	if wait := accountRetryAfter(&user); wait > 0 {
+		return tooManyLoginAttempts(c, wait)
+	}
+
+	ok, err := s.verifySecondFactor(&user, req.Code)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to verify code."})
+	}
+	if !ok {
+		s.RecordAttempt(models.AttemptTypeTwoFactor, user.Email, ipAddress, false)
+		return c.JSON(http.StatusUnauthorized, simpleResponse{Success: false, Message: "Invalid authentication code."})
+	}
+	s.RecordAttempt(models.AttemptTypeTwoFactor, user.Email, ipAddress, true)
+
+	return s.completeLogin(c, &user, ipAddress)
+}
+
+// SetupTwoFactor godoc
+// @Summary Start two-factor enrolment
+// @Description Generate a new TOTP secret and otpauth URI for the authenticated user. Two-factor authentication is not active until confirmed with /auth/2fa/enable.
+// @Tags Authentication
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Secret and otpauth URI"
+// @Failure 400 {object} simpleResponse
+// @Failure 401 {object} simpleResponse
+// @Router /auth/2fa/setup [post]
+func (s *Server) SetupTwoFactor(c echo.Context) error {
+	user, err := s.currentUser(c)
+	if err != nil {
+		return unauthenticated(c)
+	}
+	if user.TOTPEnabled {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Two-factor authentication is already enabled."})
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to start two-factor setup."})
+	}
+	if err := s.DB.Model(user).Update("totp_secret", secret).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to start two-factor setup."})
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"success":     true,
+		"secret":      secret,
+		"otpauth_uri": utils.TOTPURI(totpIssuer, user.Email, secret),
+	})
+}
+
+type twoFactorCodeRequest struct {
+	Code string `json:"code" example:"123456" binding:"required"`
+}
+
+// EnableTwoFactor godoc
+// @Summary Confirm two-factor enrolment
+// @Description Activate two-factor authentication by submitting a code from the authenticator app. Returns one-time recovery codes and signs out other sessions.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body twoFactorCodeRequest true "Authenticator code"
+// @Success 200 {object} map[string]interface{} "Recovery codes"
+// @Failure 400 {object} simpleResponse
+// @Failure 401 {object} simpleResponse
+// @Router /auth/2fa/enable [post]
+func (s *Server) EnableTwoFactor(c echo.Context) error {
+	var req twoFactorCodeRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Invalid payload"})
+	}
+	user, err := s.currentUser(c)
+	if err != nil {
+		return unauthenticated(c)
+	}
+	if user.TOTPEnabled {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Two-factor authentication is already enabled."})
+	}
+	if user.TOTPSecret == nil || *user.TOTPSecret == "" {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Start two-factor setup first."})
+	}
+
+	step, ok := utils.VerifyTOTP(*user.TOTPSecret, req.Code, time.Now())
+	if !ok {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Invalid authentication code."})
+	}
+
+	var codes []string
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]any{"totp_enabled": true, "totp_last_step": step}).Error; err != nil {
+			return err
+		}
+		var err error
+		if codes, err = s.replaceRecoveryCodes(tx, user.ID); err != nil {
+			return err
+		}
+		// Sessions started with only a password must not outlive enrolment
+		var keepSessionID uint
+		if claims := currentClaims(c); claims != nil {
+			keepSessionID = claims.SessionID
+		}
+		return s.revokeOtherSessions(tx, user.ID, keepSessionID)
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to enable two-factor authentication."})
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"success":        true,
+		"message":        "Two-factor authentication enabled. Store these recovery codes somewhere safe; each can be used once.",
+		"recovery_codes": codes,
+	})
+}
+
+type disableTwoFactorRequest struct {
+	Password string `json:"password" example:"Password@123" binding:"required"`
+	Code     string `json:"code" example:"123456" binding:"required"` // authenticator code or recovery code
+}
+
+// DisableTwoFactor godoc
+// @Summary Disable two-factor authentication
+// @Description Turn off two-factor authentication. Requires the account password and a current authenticator or recovery code.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body disableTwoFactorRequest true "Password and code"
+// @Success 200 {object} simpleResponse
+// @Failure 400 {object} simpleResponse
+// @Failure 403 {object} simpleResponse
+// @Failure 429 {object} simpleResponse
+// @Router /auth/2fa/disable [post]
+func (s *Server) DisableTwoFactor(c echo.Context) error {
+	var req disableTwoFactorRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Invalid payload"})
+	}
+	user, err := s.currentUser(c)
+	if err != nil {
+		return unauthenticated(c)
+	}
+	if !user.TOTPEnabled {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Two-factor authentication is not enabled."})
+	}
+	if s.hasPermission(c, models.PermAdminAccess) && s.boolSetting(settingRequireAdmin2FA) {
+		return c.JSON(http.StatusForbidden, simpleResponse{Success: false, Message: "Two-factor authentication is required for admin accounts."})
+	}
+	// A stolen session must not be able to guess its way past the second factor
+	ipAddress := s.getClientIP(c)
+	if s.twoFactorThrottled(user.Email) {
+		return tooManyTwoFactorAttempts(c)
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		s.RecordAttempt(models.AttemptTypeTwoFactor, user.Email, ipAddress, false)
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Password is incorrect."})
+	}
+	ok, err := s.verifySecondFactor(user, req.Code)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to verify code."})
+	}
+	if !ok {
+		s.RecordAttempt(models.AttemptTypeTwoFactor, user.Email, ipAddress, false)
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Invalid authentication code."})
+	}
+	s.RecordAttempt(models.AttemptTypeTwoFactor, user.Email, ipAddress, true)
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]any{"totp_enabled": false, "totp_secret": nil, "totp_last_step": 0}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to disable two-factor authentication."})
+	}
+
+	return c.JSON(http.StatusOK, simpleResponse{Success: true, Message: "Two-factor authentication disabled."})
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Regenerate recovery codes
+// @Description Replace all recovery codes with a new set. Requires a current authenticator code.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body twoFactorCodeRequest true "Authenticator code"
+// @Success 200 {object} map[string]interface{} "Recovery codes"
+// @Failure 400 {object} simpleResponse
+// @Failure 429 {object} simpleResponse
+// @Router /auth/2fa/recovery-codes [post]
+func (s *Server) RegenerateRecoveryCodes(c echo.Context) error {
+	var req twoFactorCodeRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Invalid payload"})
+	}
+	user, err := s.currentUser(c)
+	if err != nil {
+		return unauthenticated(c)
+	}
+	if !user.TOTPEnabled {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Two-factor authentication is not enabled."})
+	}
+	ipAddress := s.getClientIP(c)
+	if s.twoFactorThrottled(user.Email) {
+		return tooManyTwoFactorAttempts(c)
+	}
+	ok, err := s.verifySecondFactor(user, req.Code)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to verify code."})
+	}
+	if !ok {
+		s.RecordAttempt(models.AttemptTypeTwoFactor, user.Email, ipAddress, false)
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Invalid authentication code."})
+	}
+	s.RecordAttempt(models.AttemptTypeTwoFactor, user.Email, ipAddress, true)
+
+	codes, err := s.replaceRecoveryCodes(s.DB, user.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to generate recovery codes."})
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{"success": true, "recovery_codes": codes})
+}
+
+// twoFactorThrottled reports whether an account has entered too many wrong
+// second-factor codes within twoFactorRateWindow. Login, disabling
+// two-factor and regenerating recovery codes share the limit.
+func (s *Server) twoFactorThrottled(email string) bool {
+	return s.CountFailedAttemptsByEmail(models.AttemptTypeTwoFactor, email, twoFactorRateWindow) >= maxTwoFactorFailures
+}
+
+func tooManyTwoFactorAttempts(c echo.Context) error {
+	return c.JSON(http.StatusTooManyRequests, simpleResponse{Success: false, Message: "Too many invalid codes. Please try again later."})
+}
+
+// verifySecondFactor checks an authenticator code or an unused recovery code.
+// Each authenticator code and each recovery code is accepted only once.
+func (s *Server) verifySecondFactor(user *models.User, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+	if user.TOTPSecret == nil || code == "" {
+		return false, nil
+	}
+
+	if step, ok := utils.VerifyTOTP(*user.TOTPSecret, code, time.Now()); ok {
+		res := s.DB.Model(&models.User{}).
+			Where("id = ? AND totp_last_step < ?", user.ID, step).
+			Update("totp_last_step", step)
+		if res.Error != nil {
+			return false, res.Error
+		}
+		return res.RowsAffected == 1, nil
+	}
+
+	res := s.DB.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, utils.HashToken(normalizeRecoveryCode(code))).
+		Update("used_at", time.Now().UTC())
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+// replaceRecoveryCodes deletes the user's recovery codes and stores a new set,
+// returning the raw codes for display
+func (s *Server) replaceRecoveryCodes(db *gorm.DB, userID uint) ([]string, error) {
+	if err := db.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+	codes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw, err := utils.GenerateToken(5)
+		if err != nil {
+			return nil, err
+		}
+		code := raw[:5] + "-" + raw[5:]
+		record := models.RecoveryCode{UserID: userID, CodeHash: utils.HashToken(normalizeRecoveryCode(code))}
+		if err := db.Create(&record).Error; err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
+
+// GetSecuritySettings godoc
+// @Summary Get security settings
+// @Description Get system-wide security settings
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Security settings"
+// @Router /admin/settings/security [get]
+func (s *Server) GetSecuritySettings(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]any{
+		"success": true,
+		"data": map[string]any{
+			settingRequireAdmin2FA: s.boolSetting(settingRequireAdmin2FA),
+		},
+	})
+}
+
+type securitySettingsRequest struct {
+	RequireAdmin2FA *bool `json:"require_admin_2fa" example:"true"`
+}
+
+// UpdateSecuritySettings godoc
+// @Summary Update security settings
+// @Description Update system-wide security settings. When require_admin_2fa is on, admin accounts without two-factor authentication cannot use the admin API.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body securitySettingsRequest true "Settings to change"
+// @Success 200 {object} map[string]interface{} "Settings updated"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Router /admin/settings/security [put]
+func (s *Server) UpdateSecuritySettings(c echo.Context) error {
+	var req securitySettingsRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{"success": false, "message": "Invalid request body"})
+	}
+	adminUser, err := s.currentUser(c)
+	if err != nil {
+		return unauthenticated(c)
+	}
+
+	if req.RequireAdmin2FA != nil {
+		// Prevent the admin from locking themselves out of the admin API
+		if *req.RequireAdmin2FA && !adminUser.TOTPEnabled {
+			return c.JSON(http.StatusBadRequest, map[string]any{
+				"success": false,
+				"message": "Enable two-factor authentication on your own account first",
+			})
+		}
+		if err := s.setSetting(settingRequireAdmin2FA, strconv.FormatBool(*req.RequireAdmin2FA), adminUser.ID); err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]any{"success": false, "message": "Failed to update settings"})
+		}
+		s.logAdminActivity(adminUser.ID, "update", "settings", nil, settingRequireAdmin2FA+"="+strconv.FormatBool(*req.RequireAdmin2FA), c)
+	}
+
+	return s.GetSecuritySettings(c)
+}
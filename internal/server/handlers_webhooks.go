@@ -0,0 +1,489 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+
+	"github.com/frontinsight/backend/internal/models"
+	"github.com/frontinsight/backend/internal/utils"
+	"github.com/frontinsight/backend/internal/webhooks"
+)
+
+const maxWebhookEndpointsPerOwner = 10
+
+var (
+	errWebhookNotFound = errors.New("webhook endpoint not found")
+	errNoWebhookEvents = errors.New("at least one event is required")
+)
+
+// dispatchWebhook queues an event for the webhook endpoints of the user and,
+// when organizationID is set, of the organization. Failures are logged, never
+// returned: a lost webhook must not fail the caller.
+func (s *Server) dispatchWebhook(userID uint, organizationID *uint, event string, data map[string]any) {
+	if err := s.webhooks.Enqueue(s.DB, userID, organizationID, event, data); err != nil {
+		fmt.Printf("ERROR: Failed to queue %s webhook for user %d: %v\n", event, userID, err)
+	}
+}
+
+// newWebhookSecret creates the key an endpoint's payloads are signed with
+func newWebhookSecret() (string, error) {
+	secret, err := utils.GenerateToken(24)
+	if err != nil {
+		return "", err
+	}
+	return webhooks.SecretPrefix + secret, nil
+}
+
+// normalizeWebhookEvents validates an event filter and removes duplicates
+func normalizeWebhookEvents(requested []string) ([]string, error) {
+	seen := make(map[string]bool)
+	events := make([]string, 0, len(requested))
+	for _, event := range requested {
+		event = strings.ToLower(strings.TrimSpace(event))
+		valid := false
+		for _, allowed := range models.WebhookEvents {
+			if event == allowed {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("unknown event %q", event)
+		}
+		if !seen[event] {
+			seen[event] = true
+			events = append(events, event)
+		}
+	}
+	if len(events) == 0 {
+		return nil, errNoWebhookEvents
+	}
+	return events, nil
+}
+
+// webhookEventsMessage turns a normalizeWebhookEvents error into the message
+// shown to the user
+func webhookEventsMessage(err error) string {
+	if errors.Is(err, errNoWebhookEvents) {
+		return "At least one event is required."
+	}
+	return "Invalid events: " + err.Error() + ". Allowed events: " + strings.Join(models.WebhookEvents, ", ") + "."
+}
+
+// webhookEndpoint loads the endpoint in the :id path parameter if the user
+// may see it: their own endpoints, and their organizations' endpoints with
+// at least minRole. Others get errWebhookNotFound so IDs cannot be probed.
+func (s *Server) webhookEndpoint(c echo.Context, minRole string) (*models.WebhookEndpoint, error) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return nil, errWebhookNotFound
+	}
+	var endpoint models.WebhookEndpoint
+	if err := s.DB.First(&endpoint, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errWebhookNotFound
+		}
+		return nil, err
+	}
+	userID := currentUserID(c)
+	if endpoint.OrganizationID == nil {
+		if endpoint.UserID != userID {
+			return nil, errWebhookNotFound
+		}
+		return &endpoint, nil
+	}
+	if _, err := s.orgMembership(userID, *endpoint.OrganizationID, minRole); err != nil {
+		if errors.Is(err, errOrgNotFound) {
+			return nil, errWebhookNotFound
+		}
+		return nil, err
+	}
+	return &endpoint, nil
+}
+
+// webhookError writes the response for an error from webhookEndpoint
+func webhookError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, errWebhookNotFound):
+		return c.JSON(http.StatusNotFound, simpleResponse{Success: false, Message: "Webhook endpoint not found."})
+	case errors.Is(err, errOrgForbidden):
+		return c.JSON(http.StatusForbidden, simpleResponse{Success: false, Message: "Your organization role does not allow this."})
+	default:
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to load webhook endpoint."})
+	}
+}
+
+// ListWebhookEndpoints godoc
+// @Summary List webhook endpoints
+// @Description List the authenticated user's webhook endpoints, or an organization's with organization_id. Secrets are never returned after creation.
+// @Tags Webhooks
+// @Produce json
+// @Security BearerAuth
+// @Param organization_id query int false "Organization ID"
+// @Success 200 {object} map[string]interface{} "Webhook endpoints"
+// @Failure 401 {object} simpleResponse
+// @Failure 404 {object} simpleResponse
+// @Router /webhook-endpoints [get]
+func (s *Server) ListWebhookEndpoints(c echo.Context) error {
+	userID := currentUserID(c)
+	if userID == 0 {
+		return unauthenticated(c)
+	}
+	orgID, err := orgIDParam(c.QueryParam("organization_id"))
+	if err != nil {
+		return orgError(c, err)
+	}
+
+	query := s.DB.Where("organization_id IS NULL AND user_id = ?", userID)
+	if orgID != nil {
+		if _, err := s.orgMembership(userID, *orgID, models.OrgRoleViewer); err != nil {
+			return orgError(c, err)
+		}
+		query = s.DB.Where("organization_id = ?", *orgID)
+	}
+	var endpoints []models.WebhookEndpoint
+	if err := query.Order("created_at DESC").Find(&endpoints).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to load webhook endpoints."})
+	}
+	return c.JSON(http.StatusOK, map[string]any{"success": true, "data": endpoints, "events": models.WebhookEvents})
+}
+
+type createWebhookEndpointRequest struct {
+	URL            string   `json:"url" example:"https://example.com/hooks/front-insight" binding:"required"`
+	Description    string   `json:"description" example:"Load finished searches into the warehouse"`
+	Events         []string `json:"events" example:"search.completed,schedule.failed" binding:"required"`
+	OrganizationID *uint    `json:"organization_id" example:"3"` // omit for a personal endpoint
+}
+
+// CreateWebhookEndpoint godoc
+// @Summary Register a webhook endpoint
+// @Description Register a URL to receive events: search.completed, search.failed, search.aborted, schedule.failed. Organization endpoints receive events of the organization's searches and schedules and need the manager role. Payloads are signed with the returned secret, which is shown once.
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body createWebhookEndpointRequest true "URL, events and optional organization"
+// @Success 201 {object} map[string]interface{} "Endpoint and its signing secret"
+// @Failure 400 {object} simpleResponse
+// @Failure 401 {object} simpleResponse
+// @Failure 403 {object} simpleResponse
+// @Router /webhook-endpoints [post]
+func (s *Server) CreateWebhookEndpoint(c echo.Context) error {
+	userID := currentUserID(c)
+	if userID == 0 {
+		return unauthenticated(c)
+	}
+	var req createWebhookEndpointRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Invalid payload"})
+	}
+
+	url := strings.TrimSpace(req.URL)
+	if err := webhooks.ValidateURL(url, s.Cfg.WebhookAllowInsecure); err != nil {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: err.Error()})
+	}
+	events, err := normalizeWebhookEvents(req.Events)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: webhookEventsMessage(err)})
+	}
+	description := utils.SanitizeString(req.Description)
+	if len(description) > 200 {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Description must be at most 200 characters."})
+	}
+
+	count := s.DB.Model(&models.WebhookEndpoint{})
+	if req.OrganizationID != nil {
+		if _, err := s.orgMembership(userID, *req.OrganizationID, models.OrgRoleManager); err != nil {
+			return orgError(c, err)
+		}
+		count = count.Where("organization_id = ?", *req.OrganizationID)
+	} else {
+		count = count.Where("organization_id IS NULL AND user_id = ?", userID)
+	}
+	var existing int64
+	count.Count(&existing)
+	if existing >= maxWebhookEndpointsPerOwner {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Webhook endpoint limit reached. Delete an unused endpoint first."})
+	}
+
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to create webhook endpoint."})
+	}
+	endpoint := models.WebhookEndpoint{
+		UserID:         userID,
+		OrganizationID: req.OrganizationID,
+		URL:            url,
+		Description:    description,
+		Secret:         secret,
+		Events:         events,
+		Active:         true,
+	}
+	if err := s.DB.Create(&endpoint).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to create webhook endpoint."})
+	}
+
+	return c.JSON(http.StatusCreated, map[string]any{
+		"success":          true,
+		"message":          "Webhook endpoint created. Copy the secret now; it will not be shown again.",
+		"secret":           secret,
+		"webhook_endpoint": endpoint,
+	})
+}
+
+type updateWebhookEndpointRequest struct {
+	URL         *string  `json:"url" example:"https://example.com/hooks/front-insight"`
+	Description *string  `json:"description" example:"Load finished searches into the warehouse"`
+	Events      []string `json:"events" example:"search.completed"`
+	Active      *bool    `json:"active" example:"false"`
+}
+
+// UpdateWebhookEndpoint godoc
+// @Summary Update a webhook endpoint
+// @Description Change an endpoint's URL, description or events, or pause it with active=false. Fields left out are unchanged. Deliveries to a paused endpoint fail without retrying.
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Webhook endpoint ID"
+// @Param request body updateWebhookEndpointRequest true "Fields to change"
+// @Success 200 {object} map[string]interface{} "Updated endpoint"
+// @Failure 400 {object} simpleResponse
+// @Failure 404 {object} simpleResponse
+// @Router /webhook-endpoints/{id} [put]
+func (s *Server) UpdateWebhookEndpoint(c echo.Context) error {
+	endpoint, err := s.webhookEndpoint(c, models.OrgRoleManager)
+	if err != nil {
+		return webhookError(c, err)
+	}
+	var req updateWebhookEndpointRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Invalid payload"})
+	}
+
+	updates := map[string]any{}
+	if req.URL != nil {
+		url := strings.TrimSpace(*req.URL)
+		if err := webhooks.ValidateURL(url, s.Cfg.WebhookAllowInsecure); err != nil {
+			return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: err.Error()})
+		}
+		updates["url"] = url
+	}
+	if req.Description != nil {
+		description := utils.SanitizeString(*req.Description)
+		if len(description) > 200 {
+			return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Description must be at most 200 characters."})
+		}
+		updates["description"] = description
+	}
+	if req.Events != nil {
+		events, err := normalizeWebhookEvents(req.Events)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: webhookEventsMessage(err)})
+		}
+		updates["events"] = pq.StringArray(events)
+	}
+	if req.Active != nil {
+		updates["active"] = *req.Active
+	}
+	if len(updates) > 0 {
+		if err := s.DB.Model(endpoint).Updates(updates).Error; err != nil {
+			return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to update webhook endpoint."})
+		}
+	}
+	if err := s.DB.First(endpoint, endpoint.ID).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to load webhook endpoint."})
+	}
+	return c.JSON(http.StatusOK, map[string]any{"success": true, "data": endpoint})
+}
+
+// DeleteWebhookEndpoint godoc
+// @Summary Delete a webhook endpoint
+// @Description Delete an endpoint and its delivery log. Queued deliveries are dropped.
+// @Tags Webhooks
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Webhook endpoint ID"
+// @Success 200 {object} simpleResponse
+// @Failure 404 {object} simpleResponse
+// @Router /webhook-endpoints/{id} [delete]
+func (s *Server) DeleteWebhookEndpoint(c echo.Context) error {
+	endpoint, err := s.webhookEndpoint(c, models.OrgRoleManager)
+	if err != nil {
+		return webhookError(c, err)
+	}
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("endpoint_id = ?", endpoint.ID).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(endpoint).Error
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to delete webhook endpoint."})
+	}
+	return c.JSON(http.StatusOK, simpleResponse{Success: true, Message: "Webhook endpoint deleted."})
+}
+
+// RotateWebhookSecret godoc
+// @Summary Rotate a webhook secret
+// @Description Replace the endpoint's signing secret. Deliveries from now on are signed with the new secret, which is shown once.
+// @Tags Webhooks
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Webhook endpoint ID"
+// @Success 200 {object} map[string]interface{} "New signing secret"
+// @Failure 404 {object} simpleResponse
+// @Router /webhook-endpoints/{id}/rotate-secret [post]
+func (s *Server) RotateWebhookSecret(c echo.Context) error {
+	endpoint, err := s.webhookEndpoint(c, models.OrgRoleManager)
+	if err != nil {
+		return webhookError(c, err)
+	}
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to rotate secret."})
+	}
+	if err := s.DB.Model(endpoint).Update("secret", secret).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to rotate secret."})
+	}
+	return c.JSON(http.StatusOK, map[string]any{
+		"success": true,
+		"message": "Secret rotated. Copy it now; it will not be shown again.",
+		"secret":  secret,
+	})
+}
+
+// PingWebhookEndpoint godoc
+// @Summary Send a test event
+// @Description Queue a ping event for the endpoint, to check that it receives deliveries and verifies signatures. The outcome appears in the delivery log.
+// @Tags Webhooks
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Webhook endpoint ID"
+// @Success 202 {object} map[string]interface{} "Queued delivery"
+// @Failure 400 {object} simpleResponse
+// @Failure 404 {object} simpleResponse
+// @Router /webhook-endpoints/{id}/ping [post]
+func (s *Server) PingWebhookEndpoint(c echo.Context) error {
+	endpoint, err := s.webhookEndpoint(c, models.OrgRoleManager)
+	if err != nil {
+		return webhookError(c, err)
+	}
+	if !endpoint.Active {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Webhook endpoint is paused."})
+	}
+	delivery, err := s.webhooks.Ping(s.DB, endpoint)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to queue test event."})
+	}
+	return c.JSON(http.StatusAccepted, map[string]any{"success": true, "data": delivery})
+}
+
+// ListWebhookDeliveries godoc
+// @Summary Webhook delivery log
+// @Description Deliveries to the endpoint, newest first, with the response code and the start of the response body of their latest attempt. Kept for 30 days.
+// @Tags Webhooks
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Webhook endpoint ID"
+// @Param status query string false "pending, delivered or failed"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Success 200 {object} map[string]interface{} "Deliveries"
+// @Failure 404 {object} simpleResponse
+// @Router /webhook-endpoints/{id}/deliveries [get]
+func (s *Server) ListWebhookDeliveries(c echo.Context) error {
+	endpoint, err := s.webhookEndpoint(c, models.OrgRoleViewer)
+	if err != nil {
+		return webhookError(c, err)
+	}
+
+	page, _ := strconv.Atoi(c.QueryParam("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	query := s.DB.Model(&models.WebhookDelivery{}).Where("endpoint_id = ?", endpoint.ID)
+	if status := c.QueryParam("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to load deliveries."})
+	}
+	var deliveries []models.WebhookDelivery
+	if err := query.Order("id DESC").Offset((page - 1) * limit).Limit(limit).Find(&deliveries).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to load deliveries."})
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"success": true,
+		"data": map[string]any{
+			"deliveries": deliveries,
+			"pagination": map[string]any{
+				"page":       page,
+				"limit":      limit,
+				"total":      total,
+				"totalPages": (total + int64(limit) - 1) / int64(limit),
+			},
+		},
+	})
+}
+
+// RedeliverWebhook godoc
+// @Summary Redeliver a webhook
+// @Description Queue an earlier delivery's payload again. The new delivery keeps the event ID, so receivers can deduplicate, and is signed with the current secret.
+// @Tags Webhooks
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Webhook endpoint ID"
+// @Param deliveryId path int true "Delivery ID"
+// @Success 202 {object} map[string]interface{} "Queued delivery"
+// @Failure 400 {object} simpleResponse
+// @Failure 404 {object} simpleResponse
+// @Router /webhook-endpoints/{id}/deliveries/{deliveryId}/redeliver [post]
+func (s *Server) RedeliverWebhook(c echo.Context) error {
+	endpoint, err := s.webhookEndpoint(c, models.OrgRoleManager)
+	if err != nil {
+		return webhookError(c, err)
+	}
+	if !endpoint.Active {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Webhook endpoint is paused."})
+	}
+	deliveryID, err := strconv.Atoi(c.Param("deliveryId"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Invalid delivery ID"})
+	}
+	var original models.WebhookDelivery
+	if err := s.DB.Where("id = ? AND endpoint_id = ?", deliveryID, endpoint.ID).First(&original).Error; err != nil {
+		return c.JSON(http.StatusNotFound, simpleResponse{Success: false, Message: "Delivery not found"})
+	}
+	if original.Status == models.WebhookStatusPending {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Delivery is still being retried."})
+	}
+	delivery, err := s.webhooks.Redeliver(s.DB, &original)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to queue redelivery."})
+	}
+	return c.JSON(http.StatusAccepted, map[string]any{"success": true, "data": delivery})
+}
+
+// webhookData adds the owner to event data, so organization endpoints know
+// which member's search or schedule it was
+func webhookData(user *models.User, data map[string]any) map[string]any {
+	data = maps.Clone(data)
+	data["user_email"] = user.Email
+	return data
+}
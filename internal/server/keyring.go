@@ -0,0 +1,288 @@
+package server
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+
+	"github.com/frontinsight/backend/internal/models"
+	"github.com/frontinsight/backend/internal/oidc"
+	"github.com/frontinsight/backend/internal/utils"
+)
+
+const (
+	// keyRingRefresh is how often every instance reloads the keys and rotates
+	// when due. Another instance's new key may not be used here until then.
+	keyRingRefresh = time.Hour
+	// minKeyReload limits how often an unknown kid triggers a reload
+	minKeyReload = time.Minute
+	// signingKeyLock serialises rotation across instances (pg_advisory_xact_lock)
+	signingKeyLock = 7290412
+)
+
+var errNoSigningKey = errors.New("no signing key available")
+
+// keyRing holds the keys that sign and verify access and MFA tokens.
+//
+// With JWT_ALGORITHM=HS256 every token is signed with the shared JWT_SECRET,
+// as before key rotation existed. With RS256 or EdDSA the keys live in the
+// signing_keys table so every instance uses the same ones: the newest key
+// signs, is replaced after the rotation period, and keeps verifying until
+// every token it signed has expired. Public keys are published as a JWKS.
+type keyRing struct {
+	db        *gorm.DB
+	algorithm string
+	secret    []byte
+	rotation  time.Duration // how long a key signs before it is replaced
+	overlap   time.Duration // how long a retired key keeps verifying
+
+	mu       sync.RWMutex
+	signer   *utils.Signer
+	keys     map[string]verificationKey
+	loadedAt time.Time
+}
+
+type verificationKey struct {
+	key any
+	jwk oidc.JSONWebKey
+}
+
+// newKeyRing builds the key ring. maxTokenLifetime is the longest lifetime of
+// any token the keys sign.
+func newKeyRing(db *gorm.DB, algorithm, secret string, rotation, maxTokenLifetime time.Duration) *keyRing {
+	return &keyRing{
+		db:        db,
+		algorithm: algorithm,
+		secret:    []byte(secret),
+		rotation:  rotation,
+		overlap:   maxTokenLifetime + keyRingRefresh,
+		keys:      make(map[string]verificationKey),
+	}
+}
+
+func (k *keyRing) asymmetric() bool {
+	return k.algorithm != "HS256"
+}
+
+// Signer returns the key new tokens are signed with
+func (k *keyRing) Signer() (utils.Signer, error) {
+	if !k.asymmetric() {
+		return utils.Signer{Method: jwt.SigningMethodHS256, Key: k.secret}, nil
+	}
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if k.signer == nil {
+		return utils.Signer{}, errNoSigningKey
+	}
+	return *k.signer, nil
+}
+
+// Lookup finds the verification key for a token's kid. It implements utils.KeyLookup.
+func (k *keyRing) Lookup(kid string) (any, string, error) {
+	if !k.asymmetric() {
+		if kid != "" {
+			return nil, "", fmt.Errorf("unknown signing key %q", kid)
+		}
+		return k.secret, "HS256", nil
+	}
+	if kid == "" {
+		return nil, "", errors.New("token has no key id")
+	}
+	if key, ok := k.lookup(kid); ok {
+		return key.key, key.jwk.Alg, nil
+	}
+	k.mu.RLock()
+	stale := time.Since(k.loadedAt) >= minKeyReload
+	k.mu.RUnlock()
+	if stale {
+		if err := k.Reload(); err != nil {
+			return nil, "", err
+		}
+		if key, ok := k.lookup(kid); ok {
+			return key.key, key.jwk.Alg, nil
+		}
+	}
+	return nil, "", fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (k *keyRing) lookup(kid string) (verificationKey, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	key, ok := k.keys[kid]
+	return key, ok
+}
+
+// JWKS returns the public keys that currently verify tokens
+func (k *keyRing) JWKS() oidc.JSONWebKeySet {
+	set := oidc.JSONWebKeySet{Keys: []oidc.JSONWebKey{}}
+	if !k.asymmetric() {
+		return set
+	}
+	k.mu.RLock()
+	stale := time.Since(k.loadedAt) >= minKeyReload
+	k.mu.RUnlock()
+	if stale {
+		if err := k.Reload(); err != nil {
+			fmt.Printf("ERROR: Failed to reload signing keys: %v\n", err)
+		}
+	}
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	for _, key := range k.keys {
+		set.Keys = append(set.Keys, key.jwk)
+	}
+	return set
+}
+
+// Reload reads the unexpired keys from the database
+func (k *keyRing) Reload() error {
+	var rows []models.SigningKey
+	if err := k.db.Where("expires_at IS NULL OR expires_at > ?", time.Now().UTC()).
+		Order("created_at DESC, id DESC").Find(&rows).Error; err != nil {
+		return err
+	}
+
+	var signer *utils.Signer
+	keys := make(map[string]verificationKey, len(rows))
+	for _, row := range rows {
+		private, public, err := parseSigningKey(row)
+		if err != nil {
+			fmt.Printf("ERROR: Skipping unreadable signing key %s: %v\n", row.KID, err)
+			continue
+		}
+		keys[row.KID] = public
+		if signer == nil && row.RetiredAt == nil {
+			signer = &utils.Signer{KeyID: row.KID, Method: jwt.GetSigningMethod(row.Algorithm), Key: private}
+		}
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.signer = signer
+	k.keys = keys
+	k.loadedAt = time.Now()
+	return nil
+}
+
+// Rotate replaces the signing key when it is older than the rotation period,
+// or right away when force is set. Replaced keys keep verifying for the
+// overlap period; expired keys are deleted. The ring is reloaded afterwards.
+func (k *keyRing) Rotate(force bool) (*models.SigningKey, error) {
+	if !k.asymmetric() {
+		return nil, errors.New("key rotation needs an asymmetric JWT_ALGORITHM")
+	}
+	var created *models.SigningKey
+	err := k.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", signingKeyLock).Error; err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+
+		var current models.SigningKey
+		err := tx.Where("retired_at IS NULL AND algorithm = ?", k.algorithm).Order("created_at DESC, id DESC").First(&current).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err == nil && !force && now.Before(current.CreatedAt.Add(k.rotation)) {
+			return nil
+		}
+
+		key, err := generateSigningKey(k.algorithm)
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(key).Error; err != nil {
+			return err
+		}
+		expiresAt := now.Add(k.overlap)
+		if err := tx.Model(&models.SigningKey{}).
+			Where("retired_at IS NULL AND id <> ?", key.ID).
+			Updates(map[string]any{"retired_at": now, "expires_at": expiresAt}).Error; err != nil {
+			return err
+		}
+		created = key
+		return tx.Where("expires_at < ?", now).Delete(&models.SigningKey{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, k.Reload()
+}
+
+// generateSigningKey creates a new key pair for the algorithm
+func generateSigningKey(algorithm string) (*models.SigningKey, error) {
+	var private crypto.Signer
+	switch algorithm {
+	case "RS256":
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, err
+		}
+		private = key
+	case "EdDSA":
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		private = key
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(private.Public())
+	if err != nil {
+		return nil, err
+	}
+	kid := make([]byte, 8)
+	if _, err := rand.Read(kid); err != nil {
+		return nil, err
+	}
+	return &models.SigningKey{
+		KID:        hex.EncodeToString(kid),
+		Algorithm:  algorithm,
+		PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER})),
+		PublicKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})),
+		CreatedAt:  time.Now().UTC(),
+	}, nil
+}
+
+// parseSigningKey decodes a stored key pair
+func parseSigningKey(row models.SigningKey) (crypto.Signer, verificationKey, error) {
+	block, _ := pem.Decode([]byte(row.PrivateKey))
+	if block == nil {
+		return nil, verificationKey{}, errors.New("invalid private key PEM")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, verificationKey{}, err
+	}
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		if row.Algorithm != "RS256" {
+			break
+		}
+		return key, verificationKey{key: &key.PublicKey, jwk: oidc.RSAPublicJWK(row.KID, &key.PublicKey)}, nil
+	case ed25519.PrivateKey:
+		if row.Algorithm != "EdDSA" {
+			break
+		}
+		public := key.Public().(ed25519.PublicKey)
+		return key, verificationKey{key: public, jwk: oidc.Ed25519PublicJWK(row.KID, public)}, nil
+	}
+	return nil, verificationKey{}, fmt.Errorf("key does not match algorithm %q", row.Algorithm)
+}
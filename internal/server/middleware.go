@@ -1,6 +1,7 @@
 package server
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 
@@ -9,7 +10,16 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
-// JWTMiddleware validates JWT tokens and sets user context
+var errNotAuthenticated = errors.New("user not authenticated")
+
+// JWTMiddleware validates access tokens and sets the token claims on the context.
+// Validation is stateless: the signature, expiry and the in-memory revocation
+// list are checked without a database round trip. Handlers that need the full
+// user record load it with currentUser.
+//
+// An API key (fi_...) is accepted in place of the access token. Routes limit
+// what a key can do with RequireScope, and SessionOnlyMiddleware keeps keys
+// out of account and admin routes.
 func (s *Server) JWTMiddleware() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
@@ -30,8 +40,20 @@ func (s *Server) JWTMiddleware() echo.MiddlewareFunc {
 				})
 			}
 
-			tokenString := tokenParts[1]
-			claims, err := utils.ValidateJWT(tokenString, s.Cfg.JWTSecret)
+			// Scripts authenticate with an API key instead of a login token
+			if strings.HasPrefix(tokenParts[1], apiKeyPrefix) {
+				key, user, err := s.authenticateAPIKey(tokenParts[1], s.getClientIP(c))
+				if err != nil {
+					return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+						"success": false,
+						"message": "Invalid, expired or revoked API key",
+					})
+				}
+				setAPIKeyAuth(c, key, user)
+				return next(c)
+			}
+
+			claims, err := s.validateAccessToken(tokenParts[1])
 			if err != nil {
 				return c.JSON(http.StatusUnauthorized, map[string]interface{}{
 					"success": false,
@@ -39,22 +61,11 @@ func (s *Server) JWTMiddleware() echo.MiddlewareFunc {
 				})
 			}
 
-			// Check if user still exists and session is valid
-			var user models.User
-			if err := s.DB.Where("id = ? AND session_token = ?", claims.UserID, tokenString).First(&user).Error; err != nil {
-				return c.JSON(http.StatusUnauthorized, map[string]interface{}{
-					"success": false,
-					"message": "Session expired or invalid",
-				})
+			setClaims(c, claims)
+			if claims.ImpersonatorID != 0 {
+				return s.auditImpersonation(c, next)
 			}
 
-			// Set user context
-			c.Set("user", &user)
-			c.Set("user_id", claims.UserID)
-			c.Set("user_email", claims.Email)
-
-			// Session tracking removed - no longer needed for polling
-
 			return next(c)
 		}
 	}
@@ -75,24 +86,87 @@ func (s *Server) OptionalJWTMiddleware() echo.MiddlewareFunc {
 				return next(c)
 			}
 
-			tokenString := tokenParts[1]
-			claims, err := utils.ValidateJWT(tokenString, s.Cfg.JWTSecret)
-			if err != nil {
+			if strings.HasPrefix(tokenParts[1], apiKeyPrefix) {
+				if key, user, err := s.authenticateAPIKey(tokenParts[1], s.getClientIP(c)); err == nil {
+					setAPIKeyAuth(c, key, user)
+				}
 				return next(c)
 			}
 
-			// Check if user still exists and session is valid
-			var user models.User
-			if err := s.DB.Where("id = ? AND session_token = ?", claims.UserID, tokenString).First(&user).Error; err != nil {
+			claims, err := s.validateAccessToken(tokenParts[1])
+			if err != nil {
 				return next(c)
 			}
 
-			// Set user context
-			c.Set("user", &user)
-			c.Set("user_id", claims.UserID)
-			c.Set("user_email", claims.Email)
+			setClaims(c, claims)
+			if claims.ImpersonatorID != 0 {
+				return s.auditImpersonation(c, next)
+			}
 
 			return next(c)
 		}
 	}
 }
+
+// validateAccessToken checks an access token's signature, expiry, purpose and revocation
+func (s *Server) validateAccessToken(tokenString string) (*utils.Claims, error) {
+	claims, err := utils.ValidateJWT(tokenString, s.keys.Lookup)
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenUse != utils.TokenUseAccess {
+		return nil, errors.New("not an access token")
+	}
+	if s.revocations.IsRevoked(claims) {
+		return nil, errors.New("token revoked")
+	}
+	return claims, nil
+}
+
+func setClaims(c echo.Context, claims *utils.Claims) {
+	c.Set("claims", claims)
+	c.Set("user_id", claims.UserID)
+	c.Set("user_email", claims.Email)
+}
+
+// currentUserID returns the authenticated user's ID from the access token
+func currentUserID(c echo.Context) uint {
+	id, _ := c.Get("user_id").(uint)
+	return id
+}
+
+// currentUserEmail returns the authenticated user's email from the access token.
+// Email is the owner key for searches, collections, schedules and transactions.
+func currentUserEmail(c echo.Context) string {
+	email, _ := c.Get("user_email").(string)
+	return email
+}
+
+// currentClaims returns the validated access token claims
+func currentClaims(c echo.Context) *utils.Claims {
+	claims, _ := c.Get("claims").(*utils.Claims)
+	return claims
+}
+
+// currentUser returns the authenticated user record, loading it from the
+// database on first use within a request
+func (s *Server) currentUser(c echo.Context) (*models.User, error) {
+	if user, ok := c.Get("user").(*models.User); ok && user != nil {
+		return user, nil
+	}
+	userID := currentUserID(c)
+	if userID == 0 {
+		return nil, errNotAuthenticated
+	}
+	var user models.User
+	if err := s.DB.First(&user, userID).Error; err != nil {
+		return nil, errNotAuthenticated
+	}
+	c.Set("user", &user)
+	return &user, nil
+}
+
+// unauthenticated is the response for requests whose user can no longer be loaded
+func unauthenticated(c echo.Context) error {
+	return c.JSON(http.StatusUnauthorized, map[string]any{"success": false, "message": "User not authenticated"})
+}
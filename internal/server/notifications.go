@@ -0,0 +1,309 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm/clause"
+
+	"github.com/frontinsight/backend/internal/models"
+)
+
+// notificationPreferences returns the user's channels for every event,
+// filling in the defaults for events without a saved preference
+func (s *Server) notificationPreferences(userID uint) ([]models.NotificationPreference, error) {
+	var saved []models.NotificationPreference
+	if err := s.DB.Where("user_id = ?", userID).Find(&saved).Error; err != nil {
+		return nil, err
+	}
+	prefs := make([]models.NotificationPreference, 0, len(models.NotificationEvents))
+	for _, event := range models.NotificationEvents {
+		pref := models.NotificationPreference{UserID: userID, Event: event, InApp: true}
+		for _, p := range saved {
+			if p.Event == event {
+				pref = p
+			}
+		}
+		prefs = append(prefs, pref)
+	}
+	return prefs, nil
+}
+
+// notify tells a user about an event on the channels they chose. link is a
+// page in the web app; data is kept with the in-app notification. Failures
+// are logged, never returned: a lost notification must not fail the caller.
+func (s *Server) notify(user *models.User, event, title, body, link string, data map[string]any) {
+	prefs, err := s.notificationPreferences(user.ID)
+	if err != nil {
+		fmt.Printf("ERROR: Failed to load notification preferences of user %d: %v\n", user.ID, err)
+		return
+	}
+	i := slices.IndexFunc(prefs, func(p models.NotificationPreference) bool { return p.Event == event })
+	if i < 0 {
+		fmt.Printf("ERROR: Unknown notification event %q\n", event)
+		return
+	}
+	pref := prefs[i]
+
+	if pref.InApp {
+		encoded, err := json.Marshal(data)
+		if err != nil || data == nil {
+			encoded = []byte("{}")
+		}
+		n := models.Notification{
+			UserID: user.ID,
+			Event:  event,
+			Title:  title,
+			Body:   body,
+			Data:   encoded,
+		}
+		if link != "" {
+			n.Link = &link
+		}
+		if err := s.DB.Create(&n).Error; err != nil {
+			fmt.Printf("ERROR: Failed to save notification for user %d: %v\n", user.ID, err)
+		}
+	}
+	if pref.Email {
+		if err := s.sendEmail(user.Email, "notification", map[string]any{"Title": title, "Body": body, "Link": link}); err != nil {
+			fmt.Printf("ERROR: Failed to queue notification email to %s: %v\n", user.Email, err)
+		}
+	}
+}
+
+// notifySearchFinished tells the owner of a search that its job reached a
+// terminal state, with what was charged and refunded. It runs after the
+// frozen amount has been settled.
+func (s *Server) notifySearchFinished(search *models.Search) {
+	var user models.User
+	if err := s.DB.Where("email = ?", search.UserID).First(&user).Error; err != nil {
+		fmt.Printf("ERROR: Failed to load owner of search %d for notification: %v\n", search.ID, err)
+		return
+	}
+
+	var totals []struct {
+		TxnType string
+		Total   float64
+	}
+	if err := s.DB.Model(&models.Transaction{}).
+		Select("txn_type, COALESCE(SUM(amount), 0) AS total").
+		Where("search_id = ? AND txn_type IN ?", search.ID, []string{"debit", "refund"}).
+		Group("txn_type").Scan(&totals).Error; err != nil {
+		fmt.Printf("ERROR: Failed to total charges of search %d: %v\n", search.ID, err)
+	}
+	var charged, refunded float64
+	for _, t := range totals {
+		switch t.TxnType {
+		case "debit":
+			charged = t.Total
+		case "refund":
+			refunded = t.Total
+		}
+	}
+
+	name := fmt.Sprintf("#%d", search.ID)
+	if search.CollectionName != nil && *search.CollectionName != "" {
+		name = *search.CollectionName
+	}
+	runID := int64(0)
+	if search.RunID != nil {
+		runID = *search.RunID
+	}
+
+	var event, title, body string
+	switch search.Status {
+	case "Completed":
+		event = models.NotificationSearchCompleted
+		title = fmt.Sprintf("Search %q completed", name)
+		body = fmt.Sprintf("Run %d finished. Charged %.2f, refunded %.2f. Your results are ready to download.", runID, charged, refunded)
+	case "Error occured":
+		event = models.NotificationSearchFailed
+		title = fmt.Sprintf("Search %q failed", name)
+		body = fmt.Sprintf("Run %d ended with an error. Charged %.2f, refunded %.2f.", runID, charged, refunded)
+	case "Aborted":
+		event = models.NotificationSearchAborted
+		title = fmt.Sprintf("Search %q was aborted", name)
+		body = fmt.Sprintf("Run %d was aborted. Charged %.2f, refunded %.2f.", runID, charged, refunded)
+	default:
+		return
+	}
+
+	data := map[string]any{
+		"search_id":       search.ID,
+		"collection_name": name,
+		"run_id":          runID,
+		"status":          search.Status,
+		"charged":         charged,
+		"refunded":        refunded,
+	}
+	if search.Status == "Completed" && runID != 0 {
+		data["download_url"] = fmt.Sprintf("/download-by-run-id/%d", runID)
+	}
+	link := fmt.Sprintf("%s/search/%d", s.Cfg.FrontendURL, search.ID)
+	s.notify(&user, event, title, body, link, data)
+	s.dispatchWebhook(user.ID, search.OrganizationID, event, webhookData(&user, data))
+}
+
+// GetNotificationPreferences godoc
+// @Summary Notification preferences
+// @Description List every notification event with the channels it is delivered on. In-app notifications are on and email is off until changed.
+// @Tags Notifications
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Preferences per event"
+// @Failure 401 {object} simpleResponse
+// @Router /auth/notification-preferences [get]
+func (s *Server) GetNotificationPreferences(c echo.Context) error {
+	prefs, err := s.notificationPreferences(currentUserID(c))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to load notification preferences."})
+	}
+	return c.JSON(http.StatusOK, map[string]any{"success": true, "data": prefs})
+}
+
+type notificationPreferenceInput struct {
+	Event string `json:"event" example:"search.completed"`
+	InApp *bool  `json:"in_app" example:"true"`
+	Email *bool  `json:"email" example:"true"`
+}
+
+type updateNotificationPreferencesRequest struct {
+	Preferences []notificationPreferenceInput `json:"preferences"`
+}
+
+// UpdateNotificationPreferences godoc
+// @Summary Update notification preferences
+// @Description Choose per event whether it is delivered in the app and by email. Channels left out keep their current setting.
+// @Tags Notifications
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body updateNotificationPreferencesRequest true "Preferences to change"
+// @Success 200 {object} map[string]interface{} "Preferences per event"
+// @Failure 400 {object} simpleResponse
+// @Failure 401 {object} simpleResponse
+// @Router /auth/notification-preferences [put]
+func (s *Server) UpdateNotificationPreferences(c echo.Context) error {
+	var req updateNotificationPreferencesRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: "Invalid payload"})
+	}
+	userID := currentUserID(c)
+	prefs, err := s.notificationPreferences(userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to load notification preferences."})
+	}
+
+	changed := make(map[string]bool)
+	for _, in := range req.Preferences {
+		i := slices.IndexFunc(prefs, func(p models.NotificationPreference) bool { return p.Event == in.Event })
+		if i < 0 {
+			return c.JSON(http.StatusBadRequest, simpleResponse{Success: false, Message: fmt.Sprintf("Unknown notification event %q", in.Event)})
+		}
+		if in.InApp != nil {
+			prefs[i].InApp = *in.InApp
+		}
+		if in.Email != nil {
+			prefs[i].Email = *in.Email
+		}
+		prefs[i].UpdatedAt = time.Now().UTC()
+		changed[in.Event] = true
+	}
+
+	var rows []models.NotificationPreference
+	for _, p := range prefs {
+		if changed[p.Event] {
+			rows = append(rows, p)
+		}
+	}
+	if len(rows) > 0 {
+		err := s.DB.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "event"}},
+			DoUpdates: clause.AssignmentColumns([]string{"in_app", "email", "updated_at"}),
+		}).Create(&rows).Error
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, simpleResponse{Success: false, Message: "Failed to save notification preferences."})
+		}
+	}
+	return c.JSON(http.StatusOK, map[string]any{"success": true, "data": prefs})
+}
+
+// notificationHooks turns events reported by the services package into
+// notifications and webhooks. It implements services.Notifier.
+type notificationHooks struct {
+	s *Server
+}
+
+// userByEmail loads the user a service event is about; searches, schedules
+// and wallets are keyed on the owner's email
+func (h notificationHooks) userByEmail(email string) (*models.User, bool) {
+	var user models.User
+	if err := h.s.DB.Where("email = ?", email).First(&user).Error; err != nil {
+		fmt.Printf("ERROR: Failed to load user %s for notification: %v\n", email, err)
+		return nil, false
+	}
+	return &user, true
+}
+
+func (h notificationHooks) ScheduleFailed(schedule models.Schedule, reason string) {
+	user, ok := h.userByEmail(schedule.UserID)
+	if !ok {
+		return
+	}
+	data := map[string]any{"schedule_id": schedule.ID, "name": schedule.Name, "reason": reason}
+	h.s.notify(user, models.NotificationScheduleFailed,
+		fmt.Sprintf("Schedule %q failed", schedule.Name),
+		fmt.Sprintf("The scheduled run could not be submitted: %s", reason),
+		h.s.Cfg.FrontendURL+"/schedules", data)
+	h.s.dispatchWebhook(user.ID, schedule.OrganizationID, models.WebhookScheduleFailed, webhookData(user, data))
+}
+
+// BalanceChanged notifies once, when a charge takes the wallet below the
+// low balance threshold
+func (h notificationHooks) BalanceChanged(userID string, organizationID *uint, before, after float64) {
+	threshold := h.s.Cfg.LowBalanceThreshold
+	if before < threshold || after >= threshold {
+		return
+	}
+	user, ok := h.userByEmail(userID)
+	if !ok {
+		return
+	}
+	title := "Your wallet balance is low"
+	data := map[string]any{"balance": after, "threshold": threshold}
+	if organizationID != nil {
+		var org models.Organization
+		if err := h.s.DB.First(&org, *organizationID).Error; err == nil {
+			title = fmt.Sprintf("The wallet of %s is low", org.Name)
+		}
+		data["organization_id"] = *organizationID
+	}
+	h.s.notify(user, models.NotificationLowBalance, title,
+		fmt.Sprintf("The balance is %.2f after the last search. Add money to keep searches and schedules running.", after),
+		h.s.Cfg.FrontendURL+"/wallet", data)
+}
+
+func (h notificationHooks) RefundIssued(search models.Search, amount float64) {
+	user, ok := h.userByEmail(search.UserID)
+	if !ok {
+		return
+	}
+	name := fmt.Sprintf("#%d", search.ID)
+	if search.CollectionName != nil && *search.CollectionName != "" {
+		name = *search.CollectionName
+	}
+	h.s.notify(user, models.NotificationRefundIssued,
+		fmt.Sprintf("Refund of %.2f issued", amount),
+		fmt.Sprintf("%.2f was returned to the wallet for search %q.", amount, name),
+		h.s.Cfg.FrontendURL+"/wallet",
+		map[string]any{"search_id": search.ID, "amount": amount})
+}
+
+// cleanupOldNotifications removes read notifications after three months
+func (s *Server) cleanupOldNotifications() {
+	_ = s.DB.Where("read_at < ?", time.Now().UTC().Add(-90*24*time.Hour)).Delete(&models.Notification{}).Error
+}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"github.com/labstack/echo/v4"
+
+	"github.com/frontinsight/backend/internal/models"
+)
+
+// loadRole returns the role with this name, or nil when it does not exist
+func (s *Server) loadRole(name string) *models.Role {
+	var role models.Role
+	if err := s.DB.Where("name = ?", name).First(&role).Error; err != nil {
+		return nil
+	}
+	return &role
+}
+
+// currentRole returns the authenticated user's role, loading it once per request
+func (s *Server) currentRole(c echo.Context) *models.Role {
+	if role, ok := c.Get("role").(*models.Role); ok {
+		return role
+	}
+	user, err := s.currentUser(c)
+	if err != nil {
+		return nil
+	}
+	role := s.loadRole(user.Role)
+	if role != nil {
+		c.Set("role", role)
+	}
+	return role
+}
+
+// hasPermission reports whether the authenticated user's role grants the permission
+func (s *Server) hasPermission(c echo.Context, permission string) bool {
+	role := s.currentRole(c)
+	return role != nil && role.HasPermission(permission)
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/frontinsight/backend/internal/utils"
+)
+
+// revocationList tracks access tokens that were revoked before they expired.
+// Access tokens are validated without a database lookup, so revoking a session
+// or all of a user's sessions is mirrored here to take effect immediately.
+// Entries only need to outlive the access token lifetime.
+//
+// The list is held in process memory; when running several instances the
+// short access token lifetime bounds how long a revoked token stays usable.
+type revocationList struct {
+	mu       sync.RWMutex
+	ttl      time.Duration
+	users    map[uint]revokedEntry // tokens issued before `at` are revoked
+	sessions map[uint]revokedEntry // every token of the session is revoked
+}
+
+type revokedEntry struct {
+	at        time.Time
+	expiresAt time.Time
+}
+
+func newRevocationList(ttl time.Duration) *revocationList {
+	return &revocationList{
+		ttl:      ttl,
+		users:    make(map[uint]revokedEntry),
+		sessions: make(map[uint]revokedEntry),
+	}
+}
+
+// RevokeUser revokes every access token issued to the user until now
+func (r *revocationList) RevokeUser(userID uint) {
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// Token issue times have second precision, so a token issued in the same
+	// second as the revocation cannot be ordered against it. Every such token
+	// stays valid, including ones issued just before the revocation: revoking
+	// them would also reject the tokens a password change issues right after.
+	r.users[userID] = revokedEntry{at: now.Truncate(time.Second), expiresAt: now.Add(r.ttl)}
+}
+
+// RevokeSession revokes every access token issued for a session
+func (r *revocationList) RevokeSession(sessionID uint) {
+	if sessionID == 0 {
+		return
+	}
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sessions[sessionID] = revokedEntry{at: now, expiresAt: now.Add(r.ttl)}
+}
+
+// IsRevoked reports whether an otherwise valid access token has been revoked
+func (r *revocationList) IsRevoked(claims *utils.Claims) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if claims.SessionID != 0 {
+		if _, ok := r.sessions[claims.SessionID]; ok {
+			return true
+		}
+	}
+	if entry, ok := r.users[claims.UserID]; ok {
+		if claims.IssuedAt == nil || claims.IssuedAt.Time.Before(entry.at) {
+			return true
+		}
+	}
+	return false
+}
+
+// Cleanup drops entries that outlived every token they could apply to
+func (r *revocationList) Cleanup() {
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, entry := range r.users {
+		if now.After(entry.expiresAt) {
+			delete(r.users, id)
+		}
+	}
+	for id, entry := range r.sessions {
+		if now.After(entry.expiresAt) {
+			delete(r.sessions, id)
+		}
+	}
+}
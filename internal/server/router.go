@@ -1,7 +1,7 @@
 package server
 
 import (
-	"strings"
+	"fmt"
 	"time"
 
 	"github.com/labstack/echo/v4"
@@ -9,8 +9,13 @@ import (
 	"gorm.io/gorm"
 
 	"github.com/frontinsight/backend/internal/config"
+	database "github.com/frontinsight/backend/internal/db"
+	"github.com/frontinsight/backend/internal/events"
+	"github.com/frontinsight/backend/internal/mailer"
 	"github.com/frontinsight/backend/internal/models"
+	"github.com/frontinsight/backend/internal/ql2"
 	"github.com/frontinsight/backend/internal/services"
+	"github.com/frontinsight/backend/internal/webhooks"
 )
 
 type Server struct {
@@ -19,6 +24,14 @@ type Server struct {
 	SchedulerService *services.SchedulerService
 	SchedulerRunner  *services.SchedulerRunner
 	TimezoneService  *services.TimezoneService
+
+	revocations  *revocationList
+	keys         *keyRing
+	ssoProviders map[string]*ssoProvider
+	mail         *mailer.Outbox
+	events       *events.Bus
+	webhooks     *webhooks.Dispatcher
+	submissions  *ql2.Outbox
 }
 
 func New(e *echo.Echo, db *gorm.DB, cfg config.AppConfig) *Server {
@@ -26,9 +39,26 @@ func New(e *echo.Echo, db *gorm.DB, cfg config.AppConfig) *Server {
 	_ = db.AutoMigrate(
 		&models.User{},
 		&models.EmailVerification{},
+		&models.PasswordReset{},
+		&models.EmailChange{},
+		&models.EmailOutbox{},
+		&models.ProfileChange{},
+		&models.Notification{},
+		&models.NotificationPreference{},
+		&models.WebhookEndpoint{},
+		&models.WebhookDelivery{},
+		&models.Session{},
+		&models.RefreshToken{},
+		&models.RecoveryCode{},
+		&models.APIKey{},
+		&models.OIDCLoginState{},
+		&models.OIDCIdentity{},
 		&models.LoginAttempt{},
+		&models.Organization{},
+		&models.OrganizationMember{},
 		&models.Search{},
 		&models.SearchItem{},
+		&models.QL2Submission{},
 		&models.Collection{},
 		&models.CollectionItem{},
 		&models.PaymentOrder{},
@@ -41,9 +71,38 @@ func New(e *echo.Echo, db *gorm.DB, cfg config.AppConfig) *Server {
 		&models.Schedule{},
 		&models.ScheduleRun{},
 		&models.AdminActivity{},
+		&models.SystemSetting{},
 		&models.SystemStats{},
+		&models.Role{},
+		&models.SigningKey{},
 	)
 
+	if err := database.SeedRoles(db); err != nil {
+		fmt.Printf("ERROR: Failed to seed roles: %v\n", err)
+	}
+
+	// Sessions replaced the single users.session_token column and the
+	// refresh token family ID
+	if db.Migrator().HasColumn(&models.User{}, "session_token") {
+		_ = db.Migrator().DropColumn(&models.User{}, "session_token")
+	}
+	if db.Migrator().HasColumn(&models.RefreshToken{}, "family_id") {
+		_ = db.Migrator().DropColumn(&models.RefreshToken{}, "family_id")
+	}
+
+	// Email changes rename users.email; the transactions foreign key has to
+	// follow instead of blocking the update
+	_ = db.Exec(`
+		DO $$
+		BEGIN
+			IF EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_transactions_user' AND confupdtype <> 'c') THEN
+				ALTER TABLE transactions DROP CONSTRAINT fk_transactions_user;
+				ALTER TABLE transactions ADD CONSTRAINT fk_transactions_user
+					FOREIGN KEY (user_id) REFERENCES users(email) ON UPDATE CASCADE;
+			END IF;
+		END $$
+	`).Error
+
 	// Create optimized index for scheduler queries
 	// Partial index on next_run_at where is_active = true for faster lookups
 	_ = db.Exec(`
@@ -55,31 +114,51 @@ func New(e *echo.Echo, db *gorm.DB, cfg config.AppConfig) *Server {
 	// Initialize scheduler services
 	schedulerService := services.NewSchedulerService(db)
 	timezoneService := services.NewTimezoneService(db)
-	schedulerRunner := services.NewSchedulerRunner(db, schedulerService, func(jobName string, jobs []models.JobData, userID string, opts ...models.SubmitOption) error {
+	if err := ql2.CheckBaseURL(cfg.QL2BaseURL); err != nil {
+		fmt.Printf("ERROR: QL2 submissions will fail: %v\n", err)
+	}
+	submissions := ql2.NewOutbox(db, ql2.NewHTTPClient(cfg.QL2BaseURL, cfg.QL2Username, cfg.QL2Password))
+	schedulerRunner := services.NewSchedulerRunner(db, schedulerService, func(tx *gorm.DB, search *models.Search, jobs []models.JobData, opts ...models.SubmitOption) error {
 		// Create a temporary server instance to access the submission method
-		tempServer := &Server{DB: db, Cfg: cfg}
-		// Look up the search record to get the original collection name (preserves special characters)
-		var search models.Search
-		collectionName := ""
-		if err := db.Where("job_name = ? AND user_id = ?", jobName, userID).First(&search).Error; err == nil && search.CollectionName != nil {
-			collectionName = *search.CollectionName
-		} else {
-			// Fallback: try to extract from jobName if search not found yet
-			if parts := strings.SplitN(jobName, "_scheduled_", 2); len(parts) > 0 {
-				collectionName = parts[0]
-			}
-		}
-		return tempServer.submitCollectionToQL2(jobName, jobs, userID, collectionName, opts...)
+		tempServer := &Server{DB: db, Cfg: cfg, submissions: submissions}
+		return tempServer.queueQL2Submission(tx, search, jobs, opts...)
 	}, cfg)
 
+	// Templates are embedded in the binary, so a parse error is a build mistake
+	templates, err := mailer.LoadTemplates()
+	if err != nil {
+		panic(fmt.Sprintf("invalid email templates: %v", err))
+	}
+
 	s := &Server{
 		DB:               db,
 		Cfg:              cfg,
 		SchedulerService: schedulerService,
 		SchedulerRunner:  schedulerRunner,
 		TimezoneService:  timezoneService,
+		revocations:      newRevocationList(cfg.AccessTokenExpiry),
+		keys:             newKeyRing(db, cfg.JWTAlgorithm, cfg.JWTSecret, cfg.JWTKeyRotation, max(cfg.AccessTokenExpiry, mfaTokenExpiry)),
+		ssoProviders:     newSSOProviders(cfg),
+		mail:             mailer.NewOutbox(db, mailer.New(cfg), templates),
+		events:           events.NewBus(),
+		webhooks:         webhooks.NewDispatcher(db, cfg.WebhookAllowInsecure),
+		submissions:      submissions,
 	}
 
+	// Create the first signing key, or replace one that is due
+	s.rotateSigningKeys()
+
+	// Scheduler and payment events become notifications
+	services.SetNotifier(notificationHooks{s: s})
+	// and are pushed to the user's open tabs
+	services.SetEventBus(s.events)
+	// QL2 jobs that cannot be submitted fail their search and refund it
+	s.submissions.OnGiveUp = s.ql2SubmissionFailed
+
+	// Resolve client IPs through trusted proxies only; rate limits and login
+	// throttling are keyed on them
+	e.IPExtractor = newIPExtractor(cfg.TrustedProxies)
+
 	// Security middleware
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
@@ -90,6 +169,9 @@ func New(e *echo.Echo, db *gorm.DB, cfg config.AppConfig) *Server {
 	// Health
 	e.GET("/health", s.Health)
 
+	// Public keys for verifying our access tokens
+	e.GET("/.well-known/jwks.json", s.JWKS)
+
 	// Webhooks (public routes, authenticated via API key)
 	e.POST("/webhooks/ql2-job-status", s.QL2JobStatusWebhook)
 
@@ -98,39 +180,75 @@ func New(e *echo.Echo, db *gorm.DB, cfg config.AppConfig) *Server {
 	e.POST("/verify-email", s.VerifyEmail)
 	e.POST("/resend-verification", s.ResendVerification)
 	e.POST("/login", s.Login)
+	e.POST("/login/2fa", s.LoginTwoFactor)
+	e.POST("/forgot-password", s.ForgotPassword)
+	e.POST("/reset-password", s.ResetPassword)
+	e.POST("/auth/refresh", s.RefreshToken)
+	e.GET("/auth/oidc/start", s.OIDCStart)
+	e.GET("/auth/oidc/callback", s.OIDCCallback)
 
-	// Auth (protected routes)
+	// Auth (protected routes). Account management needs a login session; API keys are rejected.
+	// Routes that change credentials, money, the profile or organization membership are closed to impersonation tokens.
 	authGroup := e.Group("/auth")
 	authGroup.Use(s.JWTMiddleware())
+	authGroup.Use(s.SessionOnlyMiddleware())
+	noImpersonation := s.NoImpersonationMiddleware()
 	authGroup.POST("/logout", s.Logout)
 	authGroup.GET("/profile", s.GetProfile)
+	authGroup.PUT("/profile", s.UpdateProfile, noImpersonation)
+	authGroup.GET("/profile/history", s.GetProfileHistory)
 	authGroup.PUT("/timezone", s.UpdateTimezone)
+	authGroup.GET("/notification-preferences", s.GetNotificationPreferences)
+	authGroup.PUT("/notification-preferences", s.UpdateNotificationPreferences, noImpersonation)
+	authGroup.POST("/change-password", s.ChangePassword, noImpersonation)
+	authGroup.POST("/email/change", s.RequestEmailChange, noImpersonation)
+	authGroup.POST("/email/confirm", s.ConfirmEmailChange, noImpersonation)
+	authGroup.GET("/account/export", s.ExportAccountData, noImpersonation)
+	authGroup.POST("/account/deletion", s.RequestAccountDeletion, noImpersonation)
+	authGroup.DELETE("/account/deletion", s.CancelAccountDeletion, noImpersonation)
+	authGroup.GET("/sessions", s.ListSessions)
+	authGroup.DELETE("/sessions", s.RevokeAllSessions, noImpersonation)
+	authGroup.DELETE("/sessions/:id", s.RevokeSession, noImpersonation)
+	authGroup.POST("/2fa/setup", s.SetupTwoFactor, noImpersonation)
+	authGroup.POST("/2fa/enable", s.EnableTwoFactor, noImpersonation)
+	authGroup.POST("/2fa/disable", s.DisableTwoFactor, noImpersonation)
+	authGroup.POST("/2fa/recovery-codes", s.RegenerateRecoveryCodes, noImpersonation)
+	authGroup.GET("/api-keys", s.ListAPIKeys)
+	authGroup.POST("/api-keys", s.CreateAPIKey, noImpersonation)
+	authGroup.DELETE("/api-keys/:id", s.RevokeAPIKey, noImpersonation)
+	authGroup.POST("/oidc/link", s.OIDCLinkStart, noImpersonation)
 
-	// change-password kept for parity
-	e.POST("/change-password", s.ChangePassword)
-
-	// Protected routes (require authentication)
+	// Protected routes (require authentication). Each route declares the
+	// scope an API key needs; login sessions can use all of them.
 	protectedGroup := e.Group("")
 	protectedGroup.Use(s.JWTMiddleware())
+	readScope := s.RequireScope(models.APIKeyScopeRead)
+	submitScope := s.RequireScope(models.APIKeyScopeSubmit)
+	billingScope := s.RequireScope(models.APIKeyScopeBilling)
+
+	// change-password kept at the root path for parity with the current frontend
+	protectedGroup.POST("/change-password", s.ChangePassword, s.SessionOnlyMiddleware(), noImpersonation)
 
 	// Dashboard
-	protectedGroup.GET("/dashboard/stats", s.DashboardStats)
+	protectedGroup.GET("/dashboard/stats", s.DashboardStats, readScope)
 
 	// Searches and collections
-	protectedGroup.POST("/save-multi-form", s.SaveMultiForm)
-	protectedGroup.GET("/my-searches", s.MySearches)
-	protectedGroup.GET("/search/:id", s.GetSearch)
-	protectedGroup.PUT("/search-item/:id", s.UpdateSearchItem)
-	protectedGroup.POST("/refresh-job-status/:id", s.RefreshJobStatus)
-
-	protectedGroup.GET("/my-collections", s.MyCollections)
-	protectedGroup.GET("/collection/:id", s.GetCollection)
-	protectedGroup.PUT("/collection/:id", s.UpdateCollection)
-	protectedGroup.DELETE("/collection/:id", s.DeleteCollection)
-	protectedGroup.POST("/collection/:id/submit", s.SubmitCollection)
-	protectedGroup.POST("/collection/:id/items", s.AddCollectionItems)
-	protectedGroup.PUT("/collection-item/:id", s.UpdateCollectionItem)
-	protectedGroup.DELETE("/collection-item/:id", s.DeleteCollectionItem)
+	protectedGroup.POST("/save-multi-form", s.SaveMultiForm, submitScope)
+	protectedGroup.POST("/save-multi-form/preview", s.PreviewSaveMultiForm, submitScope)
+	protectedGroup.GET("/my-searches", s.MySearches, readScope)
+	protectedGroup.GET("/search/:id", s.GetSearch, readScope)
+	protectedGroup.PUT("/search-item/:id", s.UpdateSearchItem, submitScope)
+	protectedGroup.POST("/refresh-job-status/:id", s.RefreshJobStatus, readScope)
+
+	protectedGroup.GET("/my-collections", s.MyCollections, readScope)
+	protectedGroup.GET("/collection/:id", s.GetCollection, readScope)
+	protectedGroup.PUT("/collection/:id", s.UpdateCollection, submitScope)
+	protectedGroup.DELETE("/collection/:id", s.DeleteCollection, submitScope)
+	protectedGroup.POST("/collection/:id/submit", s.SubmitCollection, submitScope)
+	protectedGroup.POST("/collection/:id/preview", s.PreviewCollection, submitScope)
+	protectedGroup.POST("/collection/:id/items", s.AddCollectionItems, submitScope)
+	protectedGroup.PUT("/collection-item/:id", s.UpdateCollectionItem, submitScope)
+	protectedGroup.DELETE("/collection-item/:id", s.DeleteCollectionItem, submitScope)
 
 	// Reference
 	e.GET("/locations", s.GetLocations)
@@ -139,52 +257,117 @@ func New(e *echo.Echo, db *gorm.DB, cfg config.AppConfig) *Server {
 
 	// Contact & payments
 	e.POST("/contact-query", s.ContactQuery)
-	protectedGroup.GET("/wallet", s.GetWallet)
-	protectedGroup.POST("/wallet/add-money", s.AddMoneyToWallet)
-	protectedGroup.POST("/create-payment-order", s.CreatePaymentOrder)
+	protectedGroup.GET("/wallet", s.GetWallet, billingScope)
+	protectedGroup.POST("/wallet/add-money", s.AddMoneyToWallet, billingScope, noImpersonation)
+	protectedGroup.POST("/create-payment-order", s.CreatePaymentOrder, billingScope, noImpersonation)
+
+	// Notifications
+	protectedGroup.GET("/notifications", s.ListNotifications, readScope)
+	protectedGroup.GET("/notifications/unread-count", s.UnreadNotificationCount, readScope)
+	protectedGroup.POST("/notifications/read-all", s.MarkAllNotificationsRead, readScope)
+	protectedGroup.POST("/notifications/:id/read", s.MarkNotificationRead, readScope)
+
+	// Live updates. EventSource cannot send headers, so the stream is opened
+	// with a short-lived token from a logged-in session.
+	protectedGroup.POST("/events/token", s.CreateStreamToken, s.SessionOnlyMiddleware())
+	e.GET("/events/stream", s.StreamEvents)
+
+	// Outbound webhooks. Secrets are only handed to login sessions.
+	protectedGroup.GET("/webhook-endpoints", s.ListWebhookEndpoints, s.SessionOnlyMiddleware())
+	protectedGroup.POST("/webhook-endpoints", s.CreateWebhookEndpoint, s.SessionOnlyMiddleware(), noImpersonation)
+	protectedGroup.PUT("/webhook-endpoints/:id", s.UpdateWebhookEndpoint, s.SessionOnlyMiddleware(), noImpersonation)
+	protectedGroup.DELETE("/webhook-endpoints/:id", s.DeleteWebhookEndpoint, s.SessionOnlyMiddleware(), noImpersonation)
+	protectedGroup.POST("/webhook-endpoints/:id/rotate-secret", s.RotateWebhookSecret, s.SessionOnlyMiddleware(), noImpersonation)
+	protectedGroup.POST("/webhook-endpoints/:id/ping", s.PingWebhookEndpoint, s.SessionOnlyMiddleware())
+	protectedGroup.GET("/webhook-endpoints/:id/deliveries", s.ListWebhookDeliveries, s.SessionOnlyMiddleware())
+	protectedGroup.POST("/webhook-endpoints/:id/deliveries/:deliveryId/redeliver", s.RedeliverWebhook, s.SessionOnlyMiddleware())
+
+	// Organizations
+	protectedGroup.POST("/organizations", s.CreateOrganization, s.SessionOnlyMiddleware(), noImpersonation)
+	protectedGroup.GET("/organizations", s.ListOrganizations, readScope)
+	protectedGroup.GET("/organizations/:id", s.GetOrganization, readScope)
+	protectedGroup.PUT("/organizations/:id", s.UpdateOrganization, s.SessionOnlyMiddleware(), noImpersonation)
+	protectedGroup.POST("/organizations/:id/members", s.AddOrganizationMember, s.SessionOnlyMiddleware(), noImpersonation)
+	protectedGroup.PUT("/organizations/:id/members/:userId", s.UpdateOrganizationMember, s.SessionOnlyMiddleware(), noImpersonation)
+	protectedGroup.DELETE("/organizations/:id/members/:userId", s.RemoveOrganizationMember, s.SessionOnlyMiddleware(), noImpersonation)
+	protectedGroup.GET("/organizations/:id/wallet", s.GetOrganizationWallet, billingScope)
+	protectedGroup.POST("/organizations/:id/wallet/add-money", s.AddMoneyToOrganizationWallet, billingScope, noImpersonation)
+	protectedGroup.GET("/organizations/:id/schedules", s.GetOrganizationSchedules, readScope)
 
 	// Scheduler routes
-	schedulerHandler := NewSchedulerHandler(schedulerService, timezoneService)
-	protectedGroup.POST("/schedules", schedulerHandler.CreateSchedule)
-	protectedGroup.GET("/schedules", schedulerHandler.GetSchedules)
-	protectedGroup.DELETE("/schedules/:id", schedulerHandler.DeleteSchedule)
+	schedulerHandler := NewSchedulerHandler(s, schedulerService, timezoneService)
+	protectedGroup.POST("/schedules", schedulerHandler.CreateSchedule, submitScope)
+	protectedGroup.GET("/schedules", schedulerHandler.GetSchedules, readScope)
+	protectedGroup.DELETE("/schedules/:id", schedulerHandler.DeleteSchedule, submitScope)
 
 	// Admin routes (require admin authentication)
 	adminGroup := e.Group("/admin")
 	adminGroup.Use(s.JWTMiddleware())
+	adminGroup.Use(s.SessionOnlyMiddleware())
 	adminGroup.Use(s.AdminMiddleware())
 
 	// Admin dashboard
 	adminGroup.GET("/dashboard", s.AdminDashboard)
 
 	// Admin user management
-	adminGroup.GET("/users", s.AdminUsers)
-	adminGroup.GET("/users/:id", s.AdminUserDetails)
-	adminGroup.PUT("/users/:id", s.AdminUpdateUser)
+	usersRead := s.RequirePermission(models.PermUsersRead)
+	usersWrite := s.RequirePermission(models.PermUsersWrite)
+	adminGroup.GET("/users", s.AdminUsers, usersRead)
+	adminGroup.GET("/users/:id", s.AdminUserDetails, usersRead)
+	adminGroup.PUT("/users/:id", s.AdminUpdateUser, usersWrite)
+	adminGroup.POST("/users/:id/unlock", s.AdminUnlockUser, usersWrite)
+	adminGroup.POST("/users/:id/impersonate", s.ImpersonateUser, s.RequirePermission(models.PermUsersImpersonate))
+	adminGroup.POST("/users/:id/wallet/adjust", s.AdminAdjustWallet, s.RequirePermission(models.PermBillingAdjust))
+	adminGroup.DELETE("/users/:id/sessions", s.AdminRevokeUserSessions, usersWrite)
+	adminGroup.DELETE("/users/:id/sessions/:sessionId", s.AdminRevokeUserSession, usersWrite)
 
 	// Admin data management
-	adminGroup.GET("/searches", s.AdminSearches)
-	adminGroup.GET("/collections", s.AdminCollections)
-	adminGroup.GET("/schedules", s.AdminSchedules)
-	adminGroup.GET("/activities", s.AdminActivities)
+	adminGroup.GET("/searches", s.AdminSearches, s.RequirePermission(models.PermSearchesRead))
+	adminGroup.GET("/collections", s.AdminCollections, s.RequirePermission(models.PermSearchesRead))
+	adminGroup.GET("/schedules", s.AdminSchedules, s.RequirePermission(models.PermSchedulesManage))
+	adminGroup.GET("/activities", s.AdminActivities, s.RequirePermission(models.PermActivitiesRead))
+
+	// Security settings
+	adminGroup.GET("/settings/security", s.GetSecuritySettings, s.RequirePermission(models.PermSettingsRead))
+	adminGroup.PUT("/settings/security", s.UpdateSecuritySettings, s.RequirePermission(models.PermSettingsWrite))
+
+	// Roles and permissions
+	rolesManage := s.RequirePermission(models.PermRolesManage)
+	adminGroup.GET("/roles", s.ListRoles, rolesManage)
+	adminGroup.POST("/roles", s.CreateRole, rolesManage)
+	adminGroup.PUT("/roles/:name", s.UpdateRole, rolesManage)
+	adminGroup.DELETE("/roles/:name", s.DeleteRole, rolesManage)
+
+	// Token signing keys
+	adminGroup.GET("/signing-keys", s.ListSigningKeys, s.RequirePermission(models.PermSettingsRead))
+	adminGroup.POST("/signing-keys/rotate", s.RotateSigningKey, s.RequirePermission(models.PermSettingsWrite))
 
 	// Files
 	e.GET("/download-sample-data", s.DownloadSampleData)
-	protectedGroup.GET("/download/:timestamp/:job_name", s.DownloadFile)
+	protectedGroup.GET("/download/:timestamp/:job_name", s.DownloadFile, readScope)
 	// Back-compat: frontend might call download-search-output
-	protectedGroup.GET("/download-search-output/:timestamp/:job_name", s.DownloadFile)
+	protectedGroup.GET("/download-search-output/:timestamp/:job_name", s.DownloadFile, readScope)
 	// New route using run_id
-	protectedGroup.GET("/download-by-run-id/:run_id", s.DownloadFileByRunID)
+	protectedGroup.GET("/download-by-run-id/:run_id", s.DownloadFileByRunID, readScope)
 
 	// Reports
-	protectedGroup.GET("/reports/competitor-rate-tracker", s.GetCompetitorRateTrackerDashboard)
-	protectedGroup.GET("/reports/market-view", s.GetMarketViewDashboard)
-	protectedGroup.GET("/reports/star-rating-trend", s.GetStarRatingTrendDashboard)
-	protectedGroup.GET("/reports/price-suggestion", s.GetPriceSuggestionDashboard)
+	protectedGroup.GET("/reports/competitor-rate-tracker", s.GetCompetitorRateTrackerDashboard, readScope)
+	protectedGroup.GET("/reports/market-view", s.GetMarketViewDashboard, readScope)
+	protectedGroup.GET("/reports/star-rating-trend", s.GetStarRatingTrendDashboard, readScope)
+	protectedGroup.GET("/reports/price-suggestion", s.GetPriceSuggestionDashboard, readScope)
 
 	// Start scheduler runner
 	go s.SchedulerRunner.StartScheduler()
 
+	// Deliver queued emails
+	go s.mail.Start()
+
+	// Deliver queued webhooks
+	go s.webhooks.Start()
+
+	// Submit queued QL2 jobs
+	go s.submissions.Start()
+
 	// Start cleanup job for old login attempts
 	go func() {
 		ticker := time.NewTicker(1 * time.Hour) // Run every hour
@@ -193,6 +376,15 @@ func New(e *echo.Echo, db *gorm.DB, cfg config.AppConfig) *Server {
 			select {
 			case <-ticker.C:
 				s.CleanupOldAttempts()
+				s.cleanupExpiredResets()
+				s.cleanupExpiredRefreshTokens()
+				s.cleanupExpiredOIDCStates()
+				s.rotateSigningKeys()
+				s.purgeDeletedAccounts()
+				s.mail.Cleanup()
+				s.cleanupOldNotifications()
+				s.webhooks.Cleanup()
+				s.submissions.Cleanup()
 			}
 		}
 	}()
@@ -0,0 +1,39 @@
+package server
+
+import (
+	"strconv"
+	"time"
+
+	"gorm.io/gorm/clause"
+
+	"github.com/frontinsight/backend/internal/models"
+)
+
+// System setting keys
+const (
+	settingRequireAdmin2FA = "require_admin_2fa"
+)
+
+// boolSetting reads a boolean system setting, treating a missing row as false
+func (s *Server) boolSetting(key string) bool {
+	var setting models.SystemSetting
+	if err := s.DB.Where("key = ?", key).First(&setting).Error; err != nil {
+		return false
+	}
+	value, _ := strconv.ParseBool(setting.Value)
+	return value
+}
+
+// setSetting creates or updates a system setting
+func (s *Server) setSetting(key, value string, adminID uint) error {
+	setting := models.SystemSetting{
+		Key:       key,
+		Value:     value,
+		UpdatedBy: adminID,
+		UpdatedAt: time.Now().UTC(),
+	}
+	return s.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_by", "updated_at"}),
+	}).Create(&setting).Error
+}
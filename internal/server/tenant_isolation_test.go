@@ -0,0 +1,309 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/frontinsight/backend/internal/config"
+	"github.com/frontinsight/backend/internal/models"
+)
+
+// The tenant isolation tests run the real router against the Postgres
+// database in TEST_DATABASE_URL. They create their own users and records
+// with unique names and remove them afterwards.
+
+type testServer struct {
+	t    *testing.T
+	s    *Server
+	e    *echo.Echo
+	db   *gorm.DB
+	tag  string
+	reqs atomic.Int32
+}
+
+// newTestServer builds the server; configure functions adjust its config
+func newTestServer(t *testing.T, configure ...func(cfg *config.AppConfig)) *testServer {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	cfg := config.AppConfig{
+		MailDriver:         "log",
+		FrontendURL:        "http://localhost:3000",
+		JWTSecret:          "tenant-isolation-test-secret",
+		JWTAlgorithm:       "HS256",
+		JWTKeyRotation:     30 * 24 * time.Hour,
+		AccessTokenExpiry:  15 * time.Minute,
+		RefreshTokenExpiry: 24 * time.Hour,
+	}
+	for _, f := range configure {
+		f(&cfg)
+	}
+	e := echo.New()
+	return &testServer{
+		t:   t,
+		s:   New(e, db, cfg),
+		e:   e,
+		db:  db,
+		tag: fmt.Sprintf("tenant%d", time.Now().UnixNano()),
+	}
+}
+
+// create inserts a record and deletes it when the test ends
+func (ts *testServer) create(value any) {
+	ts.t.Helper()
+	if err := ts.db.Create(value).Error; err != nil {
+		ts.t.Fatalf("create %T: %v", value, err)
+	}
+	ts.t.Cleanup(func() { ts.db.Delete(value) })
+}
+
+// user creates a verified account and a login session for it
+func (ts *testServer) user(name string) (*models.User, string) {
+	ts.t.Helper()
+	u := &models.User{Email: name + "." + ts.tag + "@example.com", Name: name, Password: "not-a-hash", IsVerified: true}
+	ts.create(u)
+	ts.t.Cleanup(func() {
+		ts.db.Where("user_id = ?", u.ID).Delete(&models.RefreshToken{})
+		ts.db.Where("user_id = ?", u.ID).Delete(&models.Session{})
+		ts.db.Where("user_id = ?", u.Email).Delete(&models.Schedule{})
+	})
+	c := ts.e.NewContext(httptest.NewRequest(http.MethodPost, "/login", nil), httptest.NewRecorder())
+	pair, err := ts.s.startSession(ts.db, u, c)
+	if err != nil {
+		ts.t.Fatalf("start session for %s: %v", name, err)
+	}
+	return u, pair.AccessToken
+}
+
+// do sends a request through the router as the holder of token
+func (ts *testServer) do(token, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	// A fresh address per request keeps the per-IP rate limiter out of the way
+	n := ts.reqs.Add(1)
+	req.RemoteAddr = fmt.Sprintf("198.51.%d.%d:1234", n/250, n%250+1)
+	rec := httptest.NewRecorder()
+	ts.e.ServeHTTP(rec, req)
+	return rec
+}
+
+// collection creates a collection with one item
+func (ts *testServer) collection(owner *models.User, orgID *uint) (*models.Collection, *models.CollectionItem) {
+	ts.t.Helper()
+	col := &models.Collection{UserID: owner.Email, Name: fmt.Sprintf("col-%s-%d", ts.tag, ts.reqs.Add(1)), Status: "saved", OrganizationID: orgID}
+	ts.create(col)
+	item := &models.CollectionItem{
+		CollectionID: col.ID,
+		Location:     "Paris, France",
+		CheckInDate:  time.Date(2030, 1, 10, 0, 0, 0, 0, time.UTC),
+		CheckOutDate: time.Date(2030, 1, 12, 0, 0, 0, 0, time.UTC),
+		Adults:       2,
+		StarRating:   "4",
+		Website:      "Expedia",
+		POS:          pq.StringArray{"US"},
+	}
+	ts.create(item)
+	return col, item
+}
+
+// search creates a finished search with one item
+func (ts *testServer) search(owner *models.User, orgID *uint) (*models.Search, *models.SearchItem) {
+	ts.t.Helper()
+	n := ts.reqs.Add(1)
+	jobName := fmt.Sprintf("job_%s_%d", ts.tag, n)
+	runID := time.Now().UnixNano()/1000 + int64(n)
+	s := &models.Search{UserID: owner.Email, JobName: &jobName, RunID: &runID, Timestamp: "01-01-2030 00:00:00", Status: "Completed", OrganizationID: orgID}
+	ts.create(s)
+	item := &models.SearchItem{
+		SearchID:     s.ID,
+		Location:     "Paris, France",
+		CheckInDate:  time.Date(2030, 1, 10, 0, 0, 0, 0, time.UTC),
+		CheckOutDate: time.Date(2030, 1, 12, 0, 0, 0, 0, time.UTC),
+		Adults:       2,
+		StarRating:   "4",
+		Website:      "Expedia",
+		POS:          pq.StringArray{"US"},
+	}
+	ts.create(item)
+	return s, item
+}
+
+// organization creates an organization owned by owner with the given members
+func (ts *testServer) organization(owner *models.User, members map[*models.User]string) *models.Organization {
+	ts.t.Helper()
+	org := &models.Organization{Name: "org-" + ts.tag, CreatedBy: owner.ID}
+	ts.create(org)
+	ts.create(&models.OrganizationMember{OrganizationID: org.ID, UserID: owner.ID, Role: models.OrgRoleOwner})
+	for u, role := range members {
+		ts.create(&models.OrganizationMember{OrganizationID: org.ID, UserID: u.ID, Role: role})
+	}
+	return org
+}
+
+type routeCase struct {
+	name   string
+	method string
+	path   string
+	body   string
+}
+
+func scheduleBody(field string, id uint) string {
+	return fmt.Sprintf(`{"name":"nightly","schedule_type":"once","schedule_data":{"date_time":"2030-01-01T10:00:00Z","timezone":"UTC"},%q:%d}`, field, id)
+}
+
+// TestCrossTenantAccessIsNotFound has one user call every route that takes a
+// record ID with another user's IDs. Each must answer 404, as if the record
+// did not exist, and leave it untouched.
+func TestCrossTenantAccessIsNotFound(t *testing.T) {
+	ts := newTestServer(t)
+	_, tokenA := ts.user("alice")
+	userB, _ := ts.user("bob")
+
+	colB, colItemB := ts.collection(userB, nil)
+	searchB, searchItemB := ts.search(userB, nil)
+	scheduleB := &models.Schedule{UserID: userB.Email, Name: "bob nightly", ScheduleType: "once", ScheduleData: "{}", IsActive: true, CollectionID: &colB.ID}
+	ts.create(scheduleB)
+	orgB := ts.organization(userB, nil)
+	orgColB, _ := ts.collection(userB, &orgB.ID)
+
+	item := `{"location":"Paris, France","check_in_date":"2030-01-10","check_out_date":"2030-01-12","adults":2,"star_rating":"4","website":"Expedia","pos":["US"]}`
+	tests := []routeCase{
+		{"get collection", http.MethodGet, fmt.Sprintf("/collection/%d", colB.ID), ""},
+		{"update collection", http.MethodPut, fmt.Sprintf("/collection/%d", colB.ID), `{"jobs":[]}`},
+		{"delete collection", http.MethodDelete, fmt.Sprintf("/collection/%d", colB.ID), ""},
+		{"submit collection", http.MethodPost, fmt.Sprintf("/collection/%d/submit", colB.ID), ""},
+		{"preview collection", http.MethodPost, fmt.Sprintf("/collection/%d/preview", colB.ID), ""},
+		{"add collection items", http.MethodPost, fmt.Sprintf("/collection/%d/items", colB.ID), `{"jobs":[]}`},
+		{"update collection item", http.MethodPut, fmt.Sprintf("/collection-item/%d", colItemB.ID), item},
+		{"delete collection item", http.MethodDelete, fmt.Sprintf("/collection-item/%d", colItemB.ID), ""},
+		{"organization collection", http.MethodGet, fmt.Sprintf("/collection/%d", orgColB.ID), ""},
+		{"get search", http.MethodGet, fmt.Sprintf("/search/%d", searchB.ID), ""},
+		{"update search item", http.MethodPut, fmt.Sprintf("/search-item/%d", searchItemB.ID), item},
+		{"refresh job status", http.MethodPost, fmt.Sprintf("/refresh-job-status/%d", searchB.ID), ""},
+		{"download by job name", http.MethodGet, "/download/20300101/" + *searchB.JobName, ""},
+		{"download search output", http.MethodGet, "/download-search-output/20300101/" + *searchB.JobName, ""},
+		{"download by run ID", http.MethodGet, fmt.Sprintf("/download-by-run-id/%d", *searchB.RunID), ""},
+		{"schedule collection", http.MethodPost, "/schedules", scheduleBody("collection_id", colB.ID)},
+		{"schedule search", http.MethodPost, "/schedules", scheduleBody("search_id", searchB.ID)},
+		{"delete schedule", http.MethodDelete, fmt.Sprintf("/schedules/%d", scheduleB.ID), ""},
+		{"organization schedules", http.MethodGet, fmt.Sprintf("/organizations/%d/schedules", orgB.ID), ""},
+		{"organization collections", http.MethodGet, fmt.Sprintf("/my-collections?organization_id=%d", orgB.ID), ""},
+		{"organization searches", http.MethodGet, fmt.Sprintf("/my-searches?organization_id=%d", orgB.ID), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := ts.do(tokenA, tt.method, tt.path, tt.body)
+			if rec.Code != http.StatusNotFound {
+				t.Errorf("%s %s = %d, want 404; body: %s", tt.method, tt.path, rec.Code, rec.Body)
+			}
+		})
+	}
+
+	t.Run("listings", func(t *testing.T) {
+		for _, path := range []string{"/my-collections", "/my-searches", "/schedules"} {
+			rec := ts.do(tokenA, http.MethodGet, path, "")
+			if rec.Code != http.StatusOK {
+				t.Errorf("GET %s = %d, want 200", path, rec.Code)
+			}
+			for _, leaked := range []string{colB.Name, orgColB.Name, *searchB.JobName, scheduleB.Name} {
+				if strings.Contains(rec.Body.String(), leaked) {
+					t.Errorf("GET %s lists %q of another user", path, leaked)
+				}
+			}
+		}
+	})
+
+	t.Run("records untouched", func(t *testing.T) {
+		var count int64
+		ts.db.Model(&models.Collection{}).Where("id = ?", colB.ID).Count(&count)
+		if count != 1 {
+			t.Error("the other user's collection was deleted")
+		}
+		ts.db.Model(&models.CollectionItem{}).Where("id = ? AND location = ?", colItemB.ID, colItemB.Location).Count(&count)
+		if count != 1 {
+			t.Error("the other user's collection item was changed or deleted")
+		}
+		var schedule models.Schedule
+		if err := ts.db.First(&schedule, scheduleB.ID).Error; err != nil || !schedule.IsActive {
+			t.Errorf("the other user's schedule was deactivated (err %v)", err)
+		}
+		ts.db.Model(&models.Search{}).Where("user_id = ? AND collection_name = ?", userB.Email, colB.Name).Count(&count)
+		if count != 0 {
+			t.Error("the other user's collection was submitted")
+		}
+	})
+}
+
+// TestOrganizationRoles checks that viewers can read an organization's
+// records but not change or run them, while managers can
+func TestOrganizationRoles(t *testing.T) {
+	ts := newTestServer(t)
+	owner, _ := ts.user("olivia")
+	viewer, viewerToken := ts.user("victor")
+	manager, managerToken := ts.user("mona")
+	org := ts.organization(owner, map[*models.User]string{viewer: models.OrgRoleViewer, manager: models.OrgRoleManager})
+	col, colItem := ts.collection(owner, &org.ID)
+	search, _ := ts.search(owner, &org.ID)
+
+	reads := []routeCase{
+		{"get collection", http.MethodGet, fmt.Sprintf("/collection/%d", col.ID), ""},
+		{"get search", http.MethodGet, fmt.Sprintf("/search/%d", search.ID), ""},
+		{"organization schedules", http.MethodGet, fmt.Sprintf("/organizations/%d/schedules", org.ID), ""},
+		{"organization collections", http.MethodGet, fmt.Sprintf("/my-collections?organization_id=%d", org.ID), ""},
+	}
+	writes := []routeCase{
+		{"update collection", http.MethodPut, fmt.Sprintf("/collection/%d", col.ID), `{"jobs":[]}`},
+		{"submit collection", http.MethodPost, fmt.Sprintf("/collection/%d/submit", col.ID), ""},
+		{"preview collection", http.MethodPost, fmt.Sprintf("/collection/%d/preview", col.ID), ""},
+		{"add collection items", http.MethodPost, fmt.Sprintf("/collection/%d/items", col.ID), `{"jobs":[]}`},
+		{"delete collection item", http.MethodDelete, fmt.Sprintf("/collection-item/%d", colItem.ID), ""},
+		{"delete collection", http.MethodDelete, fmt.Sprintf("/collection/%d", col.ID), ""},
+		{"schedule collection", http.MethodPost, "/schedules", scheduleBody("collection_id", col.ID)},
+	}
+
+	for _, tt := range reads {
+		t.Run("viewer "+tt.name, func(t *testing.T) {
+			if rec := ts.do(viewerToken, tt.method, tt.path, tt.body); rec.Code != http.StatusOK {
+				t.Errorf("%s %s = %d, want 200; body: %s", tt.method, tt.path, rec.Code, rec.Body)
+			}
+		})
+	}
+	for _, tt := range writes {
+		t.Run("viewer "+tt.name, func(t *testing.T) {
+			if rec := ts.do(viewerToken, tt.method, tt.path, tt.body); rec.Code != http.StatusForbidden {
+				t.Errorf("%s %s = %d, want 403; body: %s", tt.method, tt.path, rec.Code, rec.Body)
+			}
+		})
+	}
+
+	// Managers get past the access check. What happens after it, such as the
+	// preview's pricing, depends on reference data the test does not seed;
+	// the schedule a manager creates is removed with their user.
+	for _, tt := range []routeCase{reads[0], reads[1], writes[2], writes[6]} {
+		t.Run("manager "+tt.name, func(t *testing.T) {
+			rec := ts.do(managerToken, tt.method, tt.path, tt.body)
+			if rec.Code == http.StatusNotFound || rec.Code == http.StatusForbidden {
+				t.Errorf("%s %s = %d, want access; body: %s", tt.method, tt.path, rec.Code, rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package server
+
+import (
+	"errors"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+
+	"github.com/frontinsight/backend/internal/models"
+	"github.com/frontinsight/backend/internal/utils"
+)
+
+var (
+	errInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	errRefreshTokenReused  = errors.New("refresh token reuse detected")
+)
+
+// tokenPair is the credential set returned by login, email verification and refresh
+type tokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"` // access token lifetime in seconds
+}
+
+// startSession records a new signed-in device for the user and issues its
+// first token pair. Sessions on other devices are left untouched.
+func (s *Server) startSession(db *gorm.DB, user *models.User, c echo.Context) (*tokenPair, error) {
+	now := time.Now().UTC()
+	userAgent := c.Request().UserAgent()
+	session := models.Session{
+		UserID:     user.ID,
+		Device:     describeDevice(userAgent),
+		IPAddress:  s.getClientIP(c),
+		UserAgent:  userAgent,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(s.Cfg.RefreshTokenExpiry),
+	}
+	var pair *tokenPair
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&session).Error; err != nil {
+			return err
+		}
+		var err error
+		pair, err = s.issueTokens(tx, user, session.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// issueTokens creates a new refresh token for the session and a matching access token
+func (s *Server) issueTokens(db *gorm.DB, user *models.User, sessionID uint) (*tokenPair, error) {
+	rawRefresh, err := utils.GenerateToken(32)
+	if err != nil {
+		return nil, err
+	}
+	refresh := models.RefreshToken{
+		UserID:    user.ID,
+		SessionID: sessionID,
+		TokenHash: utils.HashToken(rawRefresh),
+		ExpiresAt: time.Now().UTC().Add(s.Cfg.RefreshTokenExpiry),
+	}
+	if err := db.Create(&refresh).Error; err != nil {
+		return nil, err
+	}
+
+	signer, err := s.keys.Signer()
+	if err != nil {
+		return nil, err
+	}
+	access, err := utils.GenerateAccessToken(user.ID, user.Email, sessionID, signer, s.Cfg.AccessTokenExpiry)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tokenPair{
+		AccessToken:  access,
+		RefreshToken: rawRefresh,
+		ExpiresIn:    int(s.Cfg.AccessTokenExpiry.Seconds()),
+	}, nil
+}
+
+// rotateRefreshToken exchanges a refresh token for a new token pair in the
+// same session and records the session as seen from ipAddress. Presenting a
+// token that was already used or revoked is treated as theft and revokes the
+// whole session.
+func (s *Server) rotateRefreshToken(rawRefresh, ipAddress string) (*models.User, *tokenPair, error) {
+	var current models.RefreshToken
+	if err := s.DB.Where("token_hash = ?", utils.HashToken(rawRefresh)).First(&current).Error; err != nil {
+		return nil, nil, errInvalidRefreshToken
+	}
+	if current.UsedAt != nil || current.RevokedAt != nil {
+		_ = s.revokeSession(s.DB, current.SessionID)
+		return nil, nil, errRefreshTokenReused
+	}
+	if current.ExpiresAt.Before(time.Now().UTC()) {
+		return nil, nil, errInvalidRefreshToken
+	}
+
+	var user models.User
+	var pair *tokenPair
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		// Mark the token used only if nobody else did first; a concurrent
+		// rotation of the same token counts as reuse
+		now := time.Now().UTC()
+		res := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", current.ID).
+			Update("used_at", now)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errRefreshTokenReused
+		}
+
+		// Keep the session alive; a revoked session cannot be refreshed
+		res = tx.Model(&models.Session{}).
+			Where("id = ? AND revoked_at IS NULL", current.SessionID).
+			Updates(map[string]any{
+				"last_seen_at": now,
+				"ip_address":   ipAddress,
+				"expires_at":   now.Add(s.Cfg.RefreshTokenExpiry),
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errInvalidRefreshToken
+		}
+
+		if err := tx.First(&user, current.UserID).Error; err != nil {
+			return errInvalidRefreshToken
+		}
+		var err error
+		pair, err = s.issueTokens(tx, &user, current.SessionID)
+		return err
+	})
+	if errors.Is(err, errRefreshTokenReused) {
+		_ = s.revokeSession(s.DB, current.SessionID)
+		return nil, nil, err
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return &user, pair, nil
+}
+
+// revokeSession signs out one session: its refresh tokens stop working and
+// its access tokens are rejected immediately
+func (s *Server) revokeSession(db *gorm.DB, sessionID uint) error {
+	if sessionID == 0 {
+		return nil
+	}
+	now := time.Now().UTC()
+	if err := db.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", now).Error; err != nil {
+		return err
+	}
+	if err := db.Model(&models.RefreshToken{}).
+		Where("session_id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", now).Error; err != nil {
+		return err
+	}
+	s.revocations.RevokeSession(sessionID)
+	return nil
+}
+
+// revokeOtherSessions signs out every session of the user except keepSessionID
+func (s *Server) revokeOtherSessions(db *gorm.DB, userID, keepSessionID uint) error {
+	var sessionIDs []uint
+	if err := db.Model(&models.Session{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, keepSessionID).
+		Pluck("id", &sessionIDs).Error; err != nil {
+		return err
+	}
+	for _, id := range sessionIDs {
+		if err := s.revokeSession(db, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// revokeAllSessions signs out every session of the user, including the current one
+func (s *Server) revokeAllSessions(db *gorm.DB, userID uint) error {
+	now := time.Now().UTC()
+	if err := db.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now).Error; err != nil {
+		return err
+	}
+	if err := db.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now).Error; err != nil {
+		return err
+	}
+	s.revocations.RevokeUser(userID)
+	return nil
+}
+
+// cleanupExpiredRefreshTokens removes refresh tokens and sessions that can no longer be used
+func (s *Server) cleanupExpiredRefreshTokens() {
+	now := time.Now().UTC()
+	_ = s.DB.Where("expires_at < ?", now).Delete(&models.RefreshToken{}).Error
+	_ = s.DB.Where("expires_at < ?", now).Delete(&models.Session{}).Error
+	s.revocations.Cleanup()
+}
+
+// issueMFAToken signs the token for the second step of a two-factor login
+func (s *Server) issueMFAToken(user *models.User) (string, error) {
+	signer, err := s.keys.Signer()
+	if err != nil {
+		return "", err
+	}
+	return utils.GenerateMFAToken(user.ID, user.Email, signer, mfaTokenExpiry)
+}
@@ -0,0 +1,23 @@
+package services
+
+import (
+	"github.com/frontinsight/backend/internal/events"
+)
+
+// bus receives live updates for users' open browser tabs; nil discards them
+var bus *events.Bus
+
+// SetEventBus registers the bus services publish to. Call it once at startup.
+func SetEventBus(b *events.Bus) {
+	bus = b
+}
+
+// publishWallet announces a wallet's new balance to the user whose search
+// changed it
+func publishWallet(userID string, w *wallet) {
+	bus.Publish(userID, events.WalletBalance, map[string]any{
+		"balance":         *w.balance,
+		"frozen_amount":   *w.frozen,
+		"organization_id": w.organizationID,
+	})
+}
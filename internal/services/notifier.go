@@ -0,0 +1,32 @@
+package services
+
+import (
+	"github.com/frontinsight/backend/internal/models"
+)
+
+// Notifier tells users about changes to their schedules and wallets. The
+// server registers one with SetNotifier; services call it after the change
+// is committed. Implementations log their own errors; a lost notification
+// must not fail the payment or run that caused it.
+type Notifier interface {
+	// ScheduleFailed reports a scheduled run that could not be submitted
+	ScheduleFailed(schedule models.Schedule, reason string)
+	// BalanceChanged reports a debit from the wallet a search of userID was
+	// charged to: the user's own, or the organization's when organizationID is set
+	BalanceChanged(userID string, organizationID *uint, before, after float64)
+	// RefundIssued reports money returned to a wallet for a search
+	RefundIssued(search models.Search, amount float64)
+}
+
+type noopNotifier struct{}
+
+func (noopNotifier) ScheduleFailed(models.Schedule, string)         {}
+func (noopNotifier) BalanceChanged(string, *uint, float64, float64) {}
+func (noopNotifier) RefundIssued(models.Search, float64)            {}
+
+var notifier Notifier = noopNotifier{}
+
+// SetNotifier registers the notifier services report to. Call it once at startup.
+func SetNotifier(n Notifier) {
+	notifier = n
+}
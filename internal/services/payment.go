@@ -9,85 +9,110 @@ import (
 	"github.com/frontinsight/backend/internal/models"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-// CheckBalanceAndFreeze checks if user has sufficient balance and freezes the amount
-// Returns error if balance is insufficient
-func CheckBalanceAndFreeze(userID string, amount float64, searchID uint, db *gorm.DB) error {
+// wallet is the balance a search is charged to: the organization's shared
+// wallet when the search belongs to one, otherwise the user's own
+type wallet struct {
+	record         any
+	balance        *float64
+	frozen         *float64
+	organizationID *uint
+}
+
+// loadWallet loads the organization wallet when organizationID is set,
+// otherwise the wallet of the user with this email. The row stays locked
+// until tx ends, so members of an organization sharing a wallet cannot
+// overwrite each other's changes; call it inside the transaction that saves.
+func loadWallet(tx *gorm.DB, userID string, organizationID *uint) (*wallet, error) {
+	tx = tx.Clauses(clause.Locking{Strength: "UPDATE"})
+	if organizationID != nil {
+		var org models.Organization
+		if err := tx.First(&org, *organizationID).Error; err != nil {
+			return nil, fmt.Errorf("organization not found: %v", err)
+		}
+		return &wallet{record: &org, balance: &org.Balance, frozen: &org.FrozenAmount, organizationID: organizationID}, nil
+	}
+	var user models.User
+	if err := tx.Where("email = ?", userID).First(&user).Error; err != nil {
+		return nil, fmt.Errorf("user not found: %v", err)
+	}
+	return &wallet{record: &user, balance: &user.Balance, frozen: &user.FrozenAmount}, nil
+}
+
+// save writes the balance and frozen amount back
+func (w *wallet) save(tx *gorm.DB) error {
+	return tx.Model(w.record).Updates(map[string]any{"balance": *w.balance, "frozen_amount": *w.frozen}).Error
+}
+
+// FreezeForSearch moves the search amount from the wallet's balance to its
+// frozen amount within tx, so a search, its freeze and its QL2 submission are
+// committed together. Searches that belong to an organization are charged to
+// the organization wallet. Call the returned function once tx has committed to
+// announce the new balance.
+func FreezeForSearch(tx *gorm.DB, userID string, amount float64, searchID uint) (func(), error) {
 	if amount <= 0 {
-		return fmt.Errorf("amount must be greater than zero")
+		return nil, fmt.Errorf("amount must be greater than zero")
 	}
 
-	// Get user
-	var user models.User
-	if err := db.Where("email = ?", userID).First(&user).Error; err != nil {
-		return fmt.Errorf("user not found: %v", err)
+	var search models.Search
+	if err := tx.First(&search, searchID).Error; err != nil {
+		return nil, fmt.Errorf("search not found: %v", err)
+	}
+
+	// Get the wallet to charge
+	w, err := loadWallet(tx, userID, search.OrganizationID)
+	if err != nil {
+		return nil, err
 	}
 
-	// Check if user has sufficient balance
+	// Check if wallet has sufficient balance
 	// Balance must be >= (current frozen_amount + new amount)
 	// requiredBalance := user.FrozenAmount + amount
 	requiredBalance := amount
-	if user.Balance < requiredBalance {
-		return fmt.Errorf("insufficient balance: required %.2f, available %.2f", requiredBalance, user.Balance)
+	if *w.balance < requiredBalance {
+		return nil, fmt.Errorf("insufficient balance: required %.2f, available %.2f", requiredBalance, *w.balance)
 	}
 
-	// Start transaction
-	tx := db.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
-
-	// Update user: add to frozen_amount, deduct from balance
-	user.FrozenAmount += amount
-	user.Balance -= amount
+	// Update wallet: add to frozen_amount, deduct from balance
+	*w.frozen += amount
+	*w.balance -= amount
 
-	if err := tx.Save(&user).Error; err != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to update user balance: %v", err)
+	if err := w.save(tx); err != nil {
+		return nil, fmt.Errorf("failed to update wallet balance: %v", err)
 	}
 
 	// Update search's frozen_amount
-	var search models.Search
-	if err := tx.First(&search, searchID).Error; err != nil {
-		tx.Rollback()
-		return fmt.Errorf("search not found: %v", err)
-	}
-
 	search.FrozenAmount = amount
 	if err := tx.Save(&search).Error; err != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to update search frozen_amount: %v", err)
+		return nil, fmt.Errorf("failed to update search frozen_amount: %v", err)
 	}
 
 	// Create transaction record
 	description := fmt.Sprintf("Frozen amount for search #%d", searchID)
 	transaction := models.Transaction{
-		UserID:        userID,
-		SearchID:      &searchID,
-		TxnType:       "freeze",
-		Amount:        amount,
-		BalanceBefore: user.Balance + amount, // Balance before freeze
-		BalanceAfter:  user.Balance,          // Balance after freeze
-		Description:   &description,
-		Status:        "completed",
-		CreatedAt:     time.Now(),
-		UpdatedAt:     time.Now(),
+		UserID:         userID,
+		SearchID:       &searchID,
+		OrganizationID: w.organizationID,
+		TxnType:        "freeze",
+		Amount:         amount,
+		BalanceBefore:  *w.balance + amount, // Balance before freeze
+		BalanceAfter:   *w.balance,          // Balance after freeze
+		Description:    &description,
+		Status:         "completed",
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
 	}
 
 	if err := tx.Create(&transaction).Error; err != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to create transaction record: %v", err)
-	}
-
-	// Commit transaction
-	if err := tx.Commit().Error; err != nil {
-		return fmt.Errorf("failed to commit transaction: %v", err)
+		return nil, fmt.Errorf("failed to create transaction record: %v", err)
 	}
 
-	return nil
+	return func() {
+		publishWallet(userID, w)
+		notifier.BalanceChanged(userID, w.organizationID, *w.balance+amount, *w.balance)
+	}, nil
 }
 
 // CalculateDeductedAmount calculates the deducted amount from run_billing_summary
@@ -144,8 +169,19 @@ func CalculateDeductedAmount(runID int64, db *gorm.DB, cfg config.AppConfig) (fl
 	return totalDeducted, nil
 }
 
+// lockUnsettledSearch locks the search row and refreshes its frozen amount.
+// It reports settled when another request released the money first.
+func lockUnsettledSearch(tx *gorm.DB, search *models.Search) (settled bool, err error) {
+	var current models.Search
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, search.ID).Error; err != nil {
+		return false, fmt.Errorf("failed to lock search: %v", err)
+	}
+	search.FrozenAmount = current.FrozenAmount
+	return current.FrozenAmount <= 0, nil
+}
+
 // ProcessSearchCompletion processes a completed search
-// Calculates deducted_amount, refunded_amount, and updates user balance
+// Calculates deducted_amount, refunded_amount, and updates the wallet balance
 func ProcessSearchCompletion(search *models.Search, db *gorm.DB, cfg config.AppConfig) error {
 	if search.FrozenAmount <= 0 {
 		// Nothing to process if no frozen amount
@@ -166,18 +202,6 @@ func ProcessSearchCompletion(search *models.Search, db *gorm.DB, cfg config.AppC
 		return ProcessSearchFailure(search, db)
 	}
 
-	// Calculate refunded amount
-	refundedAmount := search.FrozenAmount - deductedAmount
-
-	// Get user
-	var user models.User
-	if err := db.Where("email = ?", search.UserID).First(&user).Error; err != nil {
-		return fmt.Errorf("user not found: %v", err)
-	}
-
-	// Capture initial balance before processing
-	initialBalance := user.Balance
-
 	// Start transaction
 	tx := db.Begin()
 	defer func() {
@@ -186,18 +210,37 @@ func ProcessSearchCompletion(search *models.Search, db *gorm.DB, cfg config.AppC
 		}
 	}()
 
-	// Unfreeze: reduce user's frozen_amount by search's frozen_amount
-	user.FrozenAmount -= search.FrozenAmount
-	if user.FrozenAmount < 0 {
-		user.FrozenAmount = 0 // Prevent negative frozen amount
+	// Settle each search once, even when a webhook and a refresh race
+	if settled, err := lockUnsettledSearch(tx, search); err != nil || settled {
+		tx.Rollback()
+		return err
 	}
 
-	// Refund: add refunded_amount to user's balance
-	user.Balance += refundedAmount
+	// Calculate refunded amount
+	refundedAmount := search.FrozenAmount - deductedAmount
 
-	if err := tx.Save(&user).Error; err != nil {
+	// Get the wallet the search was charged to
+	w, err := loadWallet(tx, search.UserID, search.OrganizationID)
+	if err != nil {
 		tx.Rollback()
-		return fmt.Errorf("failed to update user balance: %v", err)
+		return err
+	}
+
+	// Capture initial balance before processing
+	initialBalance := *w.balance
+
+	// Unfreeze: reduce wallet's frozen_amount by search's frozen_amount
+	*w.frozen -= search.FrozenAmount
+	if *w.frozen < 0 {
+		*w.frozen = 0 // Prevent negative frozen amount
+	}
+
+	// Refund: add refunded_amount to wallet's balance
+	*w.balance += refundedAmount
+
+	if err := w.save(tx); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to update wallet balance: %v", err)
 	}
 
 	// Create transaction records
@@ -206,16 +249,17 @@ func ProcessSearchCompletion(search *models.Search, db *gorm.DB, cfg config.AppC
 	// Unfreeze transaction (balance doesn't change, only frozen_amount changes)
 	unfreezeDesc := fmt.Sprintf("Unfreeze amount for completed search #%d", searchID)
 	unfreezeTxn := models.Transaction{
-		UserID:        search.UserID,
-		SearchID:      &searchID,
-		TxnType:       "unfreeze",
-		Amount:        search.FrozenAmount,
-		BalanceBefore: initialBalance,
-		BalanceAfter:  initialBalance, // Balance doesn't change on unfreeze
-		Description:   &unfreezeDesc,
-		Status:        "completed",
-		CreatedAt:     time.Now(),
-		UpdatedAt:     time.Now(),
+		UserID:         search.UserID,
+		SearchID:       &searchID,
+		OrganizationID: search.OrganizationID,
+		TxnType:        "unfreeze",
+		Amount:         search.FrozenAmount,
+		BalanceBefore:  initialBalance,
+		BalanceAfter:   initialBalance, // Balance doesn't change on unfreeze
+		Description:    &unfreezeDesc,
+		Status:         "completed",
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
 	}
 	if err := tx.Create(&unfreezeTxn).Error; err != nil {
 		tx.Rollback()
@@ -226,16 +270,17 @@ func ProcessSearchCompletion(search *models.Search, db *gorm.DB, cfg config.AppC
 	if refundedAmount > 0 {
 		refundDesc := fmt.Sprintf("Refund for completed search #%d (deducted: %.2f)", searchID, deductedAmount)
 		refundTxn := models.Transaction{
-			UserID:        search.UserID,
-			SearchID:      &searchID,
-			TxnType:       "refund",
-			Amount:        refundedAmount,
-			BalanceBefore: initialBalance,
-			BalanceAfter:  user.Balance,
-			Description:   &refundDesc,
-			Status:        "completed",
-			CreatedAt:     time.Now(),
-			UpdatedAt:     time.Now(),
+			UserID:         search.UserID,
+			SearchID:       &searchID,
+			OrganizationID: search.OrganizationID,
+			TxnType:        "refund",
+			Amount:         refundedAmount,
+			BalanceBefore:  initialBalance,
+			BalanceAfter:   *w.balance,
+			Description:    &refundDesc,
+			Status:         "completed",
+			CreatedAt:      time.Now(),
+			UpdatedAt:      time.Now(),
 		}
 		if err := tx.Create(&refundTxn).Error; err != nil {
 			tx.Rollback()
@@ -247,16 +292,17 @@ func ProcessSearchCompletion(search *models.Search, db *gorm.DB, cfg config.AppC
 	if deductedAmount > 0 {
 		debitDesc := fmt.Sprintf("Deduction for completed search #%d", searchID)
 		debitTxn := models.Transaction{
-			UserID:        search.UserID,
-			SearchID:      &searchID,
-			TxnType:       "debit",
-			Amount:        deductedAmount,
-			BalanceBefore: user.Balance,
-			BalanceAfter:  user.Balance, // Balance already reflects the deduction (frozen - refunded = deducted)
-			Description:   &debitDesc,
-			Status:        "completed",
-			CreatedAt:     time.Now(),
-			UpdatedAt:     time.Now(),
+			UserID:         search.UserID,
+			SearchID:       &searchID,
+			OrganizationID: search.OrganizationID,
+			TxnType:        "debit",
+			Amount:         deductedAmount,
+			BalanceBefore:  *w.balance,
+			BalanceAfter:   *w.balance, // Balance already reflects the deduction (frozen - refunded = deducted)
+			Description:    &debitDesc,
+			Status:         "completed",
+			CreatedAt:      time.Now(),
+			UpdatedAt:      time.Now(),
 		}
 		if err := tx.Create(&debitTxn).Error; err != nil {
 			tx.Rollback()
@@ -276,6 +322,11 @@ func ProcessSearchCompletion(search *models.Search, db *gorm.DB, cfg config.AppC
 		return fmt.Errorf("failed to commit transaction: %v", err)
 	}
 
+	publishWallet(search.UserID, w)
+	if refundedAmount > 0 {
+		notifier.RefundIssued(*search, refundedAmount)
+	}
+
 	return nil
 }
 
@@ -287,15 +338,6 @@ func ProcessSearchFailure(search *models.Search, db *gorm.DB) error {
 		return nil
 	}
 
-	// Get user
-	var user models.User
-	if err := db.Where("email = ?", search.UserID).First(&user).Error; err != nil {
-		return fmt.Errorf("user not found: %v", err)
-	}
-
-	// Capture initial balance before processing
-	initialBalance := user.Balance
-
 	// Start transaction
 	tx := db.Begin()
 	defer func() {
@@ -304,18 +346,34 @@ func ProcessSearchFailure(search *models.Search, db *gorm.DB) error {
 		}
 	}()
 
-	// Unfreeze: reduce user's frozen_amount by search's frozen_amount
-	user.FrozenAmount -= search.FrozenAmount
-	if user.FrozenAmount < 0 {
-		user.FrozenAmount = 0 // Prevent negative frozen amount
+	// Settle each search once, even when a webhook and a refresh race
+	if settled, err := lockUnsettledSearch(tx, search); err != nil || settled {
+		tx.Rollback()
+		return err
 	}
 
-	// Refund: add full frozen_amount to user's balance
-	user.Balance += search.FrozenAmount
+	// Get the wallet the search was charged to
+	w, err := loadWallet(tx, search.UserID, search.OrganizationID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// Capture initial balance before processing
+	initialBalance := *w.balance
 
-	if err := tx.Save(&user).Error; err != nil {
+	// Unfreeze: reduce wallet's frozen_amount by search's frozen_amount
+	*w.frozen -= search.FrozenAmount
+	if *w.frozen < 0 {
+		*w.frozen = 0 // Prevent negative frozen amount
+	}
+
+	// Refund: add full frozen_amount to wallet's balance
+	*w.balance += search.FrozenAmount
+
+	if err := w.save(tx); err != nil {
 		tx.Rollback()
-		return fmt.Errorf("failed to update user balance: %v", err)
+		return fmt.Errorf("failed to update wallet balance: %v", err)
 	}
 
 	// Create transaction records
@@ -324,16 +382,17 @@ func ProcessSearchFailure(search *models.Search, db *gorm.DB) error {
 	// Unfreeze transaction (balance doesn't change, only frozen_amount changes)
 	unfreezeDesc := fmt.Sprintf("Unfreeze amount for failed/aborted search #%d", searchID)
 	unfreezeTxn := models.Transaction{
-		UserID:        search.UserID,
-		SearchID:      &searchID,
-		TxnType:       "unfreeze",
-		Amount:        search.FrozenAmount,
-		BalanceBefore: initialBalance,
-		BalanceAfter:  initialBalance, // Balance doesn't change on unfreeze
-		Description:   &unfreezeDesc,
-		Status:        "completed",
-		CreatedAt:     time.Now(),
-		UpdatedAt:     time.Now(),
+		UserID:         search.UserID,
+		SearchID:       &searchID,
+		OrganizationID: search.OrganizationID,
+		TxnType:        "unfreeze",
+		Amount:         search.FrozenAmount,
+		BalanceBefore:  initialBalance,
+		BalanceAfter:   initialBalance, // Balance doesn't change on unfreeze
+		Description:    &unfreezeDesc,
+		Status:         "completed",
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
 	}
 	if err := tx.Create(&unfreezeTxn).Error; err != nil {
 		tx.Rollback()
@@ -343,16 +402,17 @@ func ProcessSearchFailure(search *models.Search, db *gorm.DB) error {
 	// Refund transaction
 	refundDesc := fmt.Sprintf("Full refund for failed/aborted search #%d", searchID)
 	refundTxn := models.Transaction{
-		UserID:        search.UserID,
-		SearchID:      &searchID,
-		TxnType:       "refund",
-		Amount:        search.FrozenAmount,
-		BalanceBefore: initialBalance,
-		BalanceAfter:  user.Balance,
-		Description:   &refundDesc,
-		Status:        "completed",
-		CreatedAt:     time.Now(),
-		UpdatedAt:     time.Now(),
+		UserID:         search.UserID,
+		SearchID:       &searchID,
+		OrganizationID: search.OrganizationID,
+		TxnType:        "refund",
+		Amount:         search.FrozenAmount,
+		BalanceBefore:  initialBalance,
+		BalanceAfter:   *w.balance,
+		Description:    &refundDesc,
+		Status:         "completed",
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
 	}
 	if err := tx.Create(&refundTxn).Error; err != nil {
 		tx.Rollback()
@@ -371,5 +431,8 @@ func ProcessSearchFailure(search *models.Search, db *gorm.DB) error {
 		return fmt.Errorf("failed to commit transaction: %v", err)
 	}
 
+	publishWallet(search.UserID, w)
+	notifier.RefundIssued(*search, refundTxn.Amount)
+
 	return nil
 }
@@ -34,15 +34,30 @@ func (s *SchedulerService) CreateSchedule(userID, name, scheduleType string, sch
 		return nil, fmt.Errorf("failed to calculate next run time: %v", err)
 	}
 
+	// Schedules belong to the organization of the collection or search they run
+	var organizationID *uint
+	if collectionID != nil {
+		var collection models.Collection
+		if err := s.db.Select("organization_id").First(&collection, *collectionID).Error; err == nil {
+			organizationID = collection.OrganizationID
+		}
+	} else if searchID != nil {
+		var search models.Search
+		if err := s.db.Select("organization_id").First(&search, *searchID).Error; err == nil {
+			organizationID = search.OrganizationID
+		}
+	}
+
 	schedule := &models.Schedule{
-		UserID:       userID,
-		Name:         name,
-		ScheduleType: scheduleType,
-		ScheduleData: string(dataJSON),
-		IsActive:     true,
-		NextRunAt:    nextRunAt,
-		CollectionID: collectionID,
-		SearchID:     searchID,
+		UserID:         userID,
+		Name:           name,
+		ScheduleType:   scheduleType,
+		ScheduleData:   string(dataJSON),
+		IsActive:       true,
+		NextRunAt:      nextRunAt,
+		CollectionID:   collectionID,
+		SearchID:       searchID,
+		OrganizationID: organizationID,
 	}
 
 	if err := s.db.Create(schedule).Error; err != nil {
@@ -59,6 +74,13 @@ func (s *SchedulerService) GetSchedulesForUser(userID string) ([]models.Schedule
 	return schedules, err
 }
 
+// GetSchedulesForOrganization returns all active schedules of an organization
+func (s *SchedulerService) GetSchedulesForOrganization(organizationID uint) ([]models.Schedule, error) {
+	var schedules []models.Schedule
+	err := s.db.Where("organization_id = ? AND is_active = ?", organizationID, true).Order("next_run_at ASC").Find(&schedules).Error
+	return schedules, err
+}
+
 // GetDueSchedules returns schedules that are due to run
 // Optimized query: uses partial index and selects only necessary fields
 func (s *SchedulerService) GetDueSchedules() ([]models.Schedule, error) {
@@ -396,5 +418,12 @@ func parseTimeToMinutes(timeStr string) int {
 
 // DeleteSchedule deactivates a schedule
 func (s *SchedulerService) DeleteSchedule(scheduleID uint, userID string) error {
-	return s.db.Model(&models.Schedule{}).Where("id = ? AND user_id = ?", scheduleID, userID).Update("is_active", false).Error
+	res := s.db.Model(&models.Schedule{}).Where("id = ? AND user_id = ?", scheduleID, userID).Update("is_active", false)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
 }
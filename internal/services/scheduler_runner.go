@@ -7,7 +7,9 @@ import (
 	"time"
 
 	"github.com/frontinsight/backend/internal/config"
+	"github.com/frontinsight/backend/internal/events"
 	"github.com/frontinsight/backend/internal/models"
+	"github.com/frontinsight/backend/internal/ql2"
 	"github.com/lib/pq"
 	"gorm.io/gorm"
 )
@@ -15,11 +17,12 @@ import (
 type SchedulerRunner struct {
 	db               *gorm.DB
 	schedulerService *SchedulerService
-	submitCollection func(string, []models.JobData, string, ...models.SubmitOption) error
+	// submitCollection queues the QL2 job of a search in the transaction that creates it
+	submitCollection func(*gorm.DB, *models.Search, []models.JobData, ...models.SubmitOption) error
 	cfg              config.AppConfig
 }
 
-func NewSchedulerRunner(db *gorm.DB, schedulerService *SchedulerService, submitCollection func(string, []models.JobData, string, ...models.SubmitOption) error, cfg config.AppConfig) *SchedulerRunner {
+func NewSchedulerRunner(db *gorm.DB, schedulerService *SchedulerService, submitCollection func(*gorm.DB, *models.Search, []models.JobData, ...models.SubmitOption) error, cfg config.AppConfig) *SchedulerRunner {
 	return &SchedulerRunner{
 		db:               db,
 		schedulerService: schedulerService,
@@ -55,6 +58,11 @@ func (sr *SchedulerRunner) executeSchedule(schedule models.Schedule) {
 		log.Printf("Failed to record schedule run start: %v", err)
 		return
 	}
+	bus.Publish(schedule.UserID, events.ScheduleRun, map[string]any{
+		"schedule_id": schedule.ID,
+		"name":        schedule.Name,
+		"status":      "running",
+	})
 
 	var errorMsg *string
 	defer func() {
@@ -64,6 +72,15 @@ func (sr *SchedulerRunner) executeSchedule(schedule models.Schedule) {
 			status = "failed"
 		}
 		sr.schedulerService.RecordScheduleRun(schedule.ID, status, errorMsg)
+		bus.Publish(schedule.UserID, events.ScheduleRun, map[string]any{
+			"schedule_id": schedule.ID,
+			"name":        schedule.Name,
+			"status":      status,
+			"error":       errorMsg,
+		})
+		if errorMsg != nil {
+			notifier.ScheduleFailed(schedule, *errorMsg)
+		}
 
 		// Update schedule next run time
 		if err := sr.schedulerService.UpdateScheduleNextRun(schedule.ID); err != nil {
@@ -130,6 +147,11 @@ func (sr *SchedulerRunner) submitCollectionToQL2(collection *models.Collection,
 		jobs = append(jobs, job)
 	}
 
+	// Every job is charged, so refuse to run the collection without them
+	if _, skipped := ql2.BuildRows(jobs, userID, collection.Name); len(skipped) > 0 {
+		return &ql2.JobsError{Skipped: skipped}
+	}
+
 	// Generate job name: 'user-given name' + 'scheduled' + 'userIDStr' + timestamp
 	now := time.Now().UTC()
 	safeCollectionName := strings.ReplaceAll(collection.Name, "@", "_")
@@ -155,46 +177,56 @@ func (sr *SchedulerRunner) submitCollectionToQL2(collection *models.Collection,
 		Scheduled:      true,
 		Amount:         searchAmount,
 		FrozenAmount:   0.00, // Will be set when frozen
-	}
-	if err := sr.db.Create(&search).Error; err != nil {
-		return fmt.Errorf("failed to create search: %v", err)
+		OrganizationID: collection.OrganizationID,
 	}
 
-	// Create search items for each job
-	for _, job := range jobs {
-		checkIn, _ := time.Parse("2006-01-02", job.CheckInDate)
-		checkOut, _ := time.Parse("2006-01-02", job.CheckOutDate)
-		// Ensure POS values are plain strings, not JSON-encoded
-		posValues := make([]string, len(job.Website.POS))
-		for i, pos := range job.Website.POS {
-			posValues[i] = pos
+	// Create the search, freeze its amount and queue the QL2 job together
+	var announce func()
+	err = sr.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&search).Error; err != nil {
+			return fmt.Errorf("failed to create search: %v", err)
 		}
-		_ = sr.db.Create(&models.SearchItem{
-			SearchID:     search.ID,
-			Location:     job.Location,
-			CheckInDate:  checkIn,
-			CheckOutDate: checkOut,
-			Adults:       job.Adults,
-			StarRating:   job.StarRating,
-			Website:      job.Website.Name,
-			POS:          pq.StringArray(posValues),
-			Amount:       0.00, // Individual item amount not used
-		}).Error
-	}
 
-	// Check balance and freeze amount before submitting to QL2
-	if err := CheckBalanceAndFreeze(userID, searchAmount, search.ID, sr.db); err != nil {
-		// Delete the search if freeze fails
-		sr.db.Delete(&search)
-		return fmt.Errorf("failed to freeze amount: %v", err)
-	}
+		// Create search items for each job
+		for _, job := range jobs {
+			checkIn, _ := time.Parse("2006-01-02", job.CheckInDate)
+			checkOut, _ := time.Parse("2006-01-02", job.CheckOutDate)
+			// Ensure POS values are plain strings, not JSON-encoded
+			posValues := make([]string, len(job.Website.POS))
+			for i, pos := range job.Website.POS {
+				posValues[i] = pos
+			}
+			if err := tx.Create(&models.SearchItem{
+				SearchID:     search.ID,
+				Location:     job.Location,
+				CheckInDate:  checkIn,
+				CheckOutDate: checkOut,
+				Adults:       job.Adults,
+				StarRating:   job.StarRating,
+				Website:      job.Website.Name,
+				POS:          pq.StringArray(posValues),
+				Amount:       0.00, // Individual item amount not used
+			}).Error; err != nil {
+				return fmt.Errorf("failed to create search items: %v", err)
+			}
+		}
 
-	// Submit to QL2 using the actual submission function
-	err = sr.submitCollection(jobName, jobs, userID)
+		// Check balance and freeze amount before submitting to QL2
+		var err error
+		if announce, err = FreezeForSearch(tx, userID, searchAmount, search.ID); err != nil {
+			return fmt.Errorf("failed to freeze amount: %v", err)
+		}
+
+		// Queue the QL2 job; the outbox submits it once this commits
+		if err := sr.submitCollection(tx, &search, jobs); err != nil {
+			return fmt.Errorf("failed to submit collection to QL2: %v", err)
+		}
+		return nil
+	})
 	if err != nil {
-		// If QL2 submission fails, the frozen amount will be handled when search is marked as failed
-		return fmt.Errorf("failed to submit collection to QL2: %v", err)
+		return err
 	}
+	announce()
 
 	// Update collection status
 	collection.Status = "running"
@@ -208,21 +240,23 @@ func (sr *SchedulerRunner) submitSearchToQL2(search *models.Search) error {
 	log.Printf("Submitting search %d to QL2", search.ID)
 
 	// Convert search items to job data format
-	var jobs []map[string]interface{}
+	var jobs []models.JobData
 	for _, item := range search.Items {
-		job := map[string]interface{}{
-			"website":      models.WebsiteData{Name: item.Website, POS: []string(item.POS)},
-			"location":     item.Location,
-			"checkInDate":  item.CheckInDate.Format("2006-01-02"),
-			"checkOutDate": item.CheckOutDate.Format("2006-01-02"),
-			"adults":       item.Adults,
-			"starRating":   item.StarRating,
-		}
-		jobs = append(jobs, job)
+		jobs = append(jobs, models.JobData{
+			Website:      models.WebsiteData{Name: item.Website, POS: []string(item.POS)},
+			Location:     item.Location,
+			CheckInDate:  item.CheckInDate.Format("2006-01-02"),
+			CheckOutDate: item.CheckOutDate.Format("2006-01-02"),
+			Adults:       item.Adults,
+			StarRating:   item.StarRating,
+		})
 	}
 
 	// Create CSV data for QL2
-	csvData := sr.createCSVData(jobs)
+	csvData, err := sr.createCSVData(search, jobs)
+	if err != nil {
+		return fmt.Errorf("failed to build QL2 input for search %d: %v", search.ID, err)
+	}
 
 	// Here you would submit to QL2 - for now just log
 	log.Printf("Would submit to QL2: %d jobs from search %d", len(jobs), search.ID)
@@ -233,24 +267,18 @@ func (sr *SchedulerRunner) submitSearchToQL2(search *models.Search) error {
 	return sr.db.Save(search).Error
 }
 
-// createCSVData creates CSV data for QL2 submission
-func (sr *SchedulerRunner) createCSVData(jobs []map[string]interface{}) string {
-	// This is a simplified version - you would implement the full CSV creation logic
-	// similar to the existing submitCollectionToQL2 function
-	csvData := "SITECODE,COUNTRY,LOCATION,CHECKIN_DATE,CHECKOUT_DATE,HOTELNAME,SUPPLIER,RATENIGHTLY,RATEFINAL,CURRENCY,ROOM_INFORMATION,STARS,MESSAGE\n"
-
-	for _, job := range jobs {
-		website := job["website"].(models.WebsiteData)
-		csvData += fmt.Sprintf("%s,%s,%s,%s,%s,,,,,,,,,\n",
-			website.Name,
-			"", // Country would be extracted from location
-			job["location"],
-			job["checkInDate"],
-			job["checkOutDate"],
-		)
+// createCSVData creates the QL2 job body for a search's jobs, in the same
+// format as submitted collections
+func (sr *SchedulerRunner) createCSVData(search *models.Search, jobs []models.JobData) (string, error) {
+	label := ""
+	if search.CollectionName != nil {
+		label = *search.CollectionName
 	}
-
-	return csvData
+	rows, skipped := ql2.BuildRows(jobs, search.UserID, label)
+	if len(skipped) > 0 {
+		return "", &ql2.JobsError{Skipped: skipped}
+	}
+	return ql2.EncodeInput(rows)
 }
 
 // StartScheduler starts the scheduler background process
@@ -7,16 +7,52 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// Token uses carried in the token_use claim
+const (
+	TokenUseAccess = "access" // API access token
+	TokenUseMFA    = "mfa"    // password verified, waiting for the second factor
+	TokenUseStream = "stream" // opens one live event stream
+)
+
+// Signer signs tokens with one key. KeyID is sent in the kid header so
+// verifiers can pick the matching key after a rotation.
+type Signer struct {
+	KeyID  string
+	Method jwt.SigningMethod
+	Key    any // []byte for HS256, crypto.Signer for RS256 and EdDSA
+}
+
+func (sg Signer) sign(claims Claims) (string, error) {
+	token := jwt.NewWithClaims(sg.Method, claims)
+	if sg.KeyID != "" {
+		token.Header["kid"] = sg.KeyID
+	}
+	return token.SignedString(sg.Key)
+}
+
+// KeyLookup returns the verification key and algorithm for a token's kid
+type KeyLookup func(kid string) (key any, alg string, err error)
+
+// SupportedAlgorithms are the signing algorithms tokens may use
+var SupportedAlgorithms = []string{"HS256", "RS256", "EdDSA"}
+
 type Claims struct {
-	UserID uint   `json:"user_id"`
-	Email  string `json:"email"`
+	UserID    uint   `json:"user_id"`
+	Email     string `json:"email"`
+	TokenUse  string `json:"token_use,omitempty"`
+	SessionID uint   `json:"sid,omitempty"`
+	// ImpersonatorID is the admin acting as the user; zero on the user's own tokens
+	ImpersonatorID uint `json:"impersonator_id,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateJWT(userID uint, email, secret string, expiry time.Duration) (string, error) {
+// GenerateAccessToken issues a short-lived access token bound to a session
+func GenerateAccessToken(userID uint, email string, sessionID uint, signer Signer, expiry time.Duration) (string, error) {
 	claims := Claims{
-		UserID: userID,
-		Email:  email,
+		UserID:    userID,
+		Email:     email,
+		TokenUse:  TokenUseAccess,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiry)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -25,17 +61,23 @@ func GenerateJWT(userID uint, email, secret string, expiry time.Duration) (strin
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	return signer.sign(claims)
 }
 
-func ValidateJWT(tokenString, secret string) (*Claims, error) {
+// ValidateJWT verifies a token with the key its kid header names
+func ValidateJWT(tokenString string, lookup KeyLookup) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		kid, _ := token.Header["kid"].(string)
+		key, alg, err := lookup(kid)
+		if err != nil {
+			return nil, err
+		}
+		// The key decides the algorithm, never the token
+		if token.Method.Alg() != alg {
 			return nil, errors.New("unexpected signing method")
 		}
-		return []byte(secret), nil
-	})
+		return key, nil
+	}, jwt.WithValidMethods(SupportedAlgorithms))
 
 	if err != nil {
 		return nil, err
@@ -47,3 +89,60 @@ func ValidateJWT(tokenString, secret string) (*Claims, error) {
 
 	return nil, errors.New("invalid token")
 }
+
+// GenerateMFAToken issues a short-lived token proving the password step of a
+// two-factor login. It cannot be used as an access token.
+func GenerateMFAToken(userID uint, email string, signer Signer, expiry time.Duration) (string, error) {
+	claims := Claims{
+		UserID:   userID,
+		Email:    email,
+		TokenUse: TokenUseMFA,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiry)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+			Issuer:    "frontinsight",
+		},
+	}
+
+	return signer.sign(claims)
+}
+
+// GenerateImpersonationToken issues an access token that lets an admin act as
+// the user. It names the admin, is not bound to a session and cannot be refreshed.
+func GenerateImpersonationToken(userID uint, email string, impersonatorID uint, signer Signer, expiry time.Duration) (string, error) {
+	claims := Claims{
+		UserID:         userID,
+		Email:          email,
+		TokenUse:       TokenUseAccess,
+		ImpersonatorID: impersonatorID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiry)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+			Issuer:    "frontinsight",
+		},
+	}
+
+	return signer.sign(claims)
+}
+
+// GenerateStreamToken issues a short-lived token for opening a live event
+// stream. Browsers cannot send headers with EventSource, so it travels in the
+// URL; it cannot be used as an access token.
+func GenerateStreamToken(userID uint, email string, sessionID uint, signer Signer, expiry time.Duration) (string, error) {
+	claims := Claims{
+		UserID:    userID,
+		Email:     email,
+		TokenUse:  TokenUseStream,
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiry)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+			Issuer:    "frontinsight",
+		},
+	}
+
+	return signer.sign(claims)
+}
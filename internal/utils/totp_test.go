@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret is the RFC 6238 SHA-1 test key "12345678901234567890" in base32
+var rfcSecret = totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+// TestTOTPCodeRFC6238 checks the RFC 6238 appendix B SHA-1 vectors, cut to
+// six digits
+func TestTOTPCodeRFC6238(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tt := range tests {
+		got, err := TOTPCode(rfcSecret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatalf("TOTPCode: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("TOTPCode at T=%d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyTOTPSkew(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	tests := []struct {
+		steps int
+		ok    bool
+	}{
+		{-2, false},
+		{-1, true},
+		{0, true},
+		{1, true},
+		{2, false},
+	}
+	for _, tt := range tests {
+		at := now.Add(time.Duration(tt.steps*totpPeriod) * time.Second)
+		code, err := TOTPCode(rfcSecret, at)
+		if err != nil {
+			t.Fatalf("TOTPCode: %v", err)
+		}
+		step, ok := VerifyTOTP(rfcSecret, code, now)
+		if ok != tt.ok {
+			t.Errorf("code from %+d steps accepted = %v, want %v", tt.steps, ok, tt.ok)
+		}
+		if ok && step != at.Unix()/totpPeriod {
+			t.Errorf("code from %+d steps matched step %d, want %d", tt.steps, step, at.Unix()/totpPeriod)
+		}
+	}
+}
+
+func TestVerifyTOTPInput(t *testing.T) {
+	now := time.Unix(59, 0)
+	if _, ok := VerifyTOTP(rfcSecret, " 287 082 ", now); !ok {
+		t.Error("code with spaces rejected")
+	}
+	for _, code := range []string{"", "28708", "2870820", "94287082"} {
+		if _, ok := VerifyTOTP(rfcSecret, code, now); ok {
+			t.Errorf("VerifyTOTP accepted %q", code)
+		}
+	}
+	if _, ok := VerifyTOTP("not base32!", "287082", now); ok {
+		t.Error("VerifyTOTP accepted an invalid secret")
+	}
+}
@@ -0,0 +1,221 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+
+	"github.com/frontinsight/backend/internal/models"
+	"github.com/frontinsight/backend/internal/outbox"
+	"github.com/frontinsight/backend/internal/utils"
+)
+
+const (
+	// requestTimeout bounds one attempt, including reading the response
+	requestTimeout = 10 * time.Second
+	// maxAttempts spreads retries over about a day before giving up
+	maxAttempts = 10
+	// maxResponseBody is how much of the endpoint's response is kept for the delivery log
+	maxResponseBody = 4 << 10
+)
+
+var outboxConfig = outbox.Config{
+	Name:             "webhook deliveries",
+	PendingStatus:    models.WebhookStatusPending,
+	FinishedStatuses: []string{models.WebhookStatusDelivered, models.WebhookStatusFailed},
+	PollInterval:     15 * time.Second,
+	BatchSize:        20,
+	Lease:            2 * time.Minute,
+	Retention:        30 * 24 * time.Hour,
+}
+
+// Dispatcher queues events for the endpoints subscribed to them and delivers
+// them in the background with an outbox.Worker
+type Dispatcher struct {
+	*outbox.Worker[models.WebhookDelivery]
+	db     *gorm.DB
+	client *http.Client
+}
+
+// NewDispatcher creates the dispatcher; Start runs its worker. allowInsecure
+// permits plain http and private addresses, for local development.
+func NewDispatcher(db *gorm.DB, allowInsecure bool) *Dispatcher {
+	d := &Dispatcher{
+		db:     db,
+		client: NewClient(requestTimeout, allowInsecure),
+	}
+	d.Worker = outbox.New(db, outboxConfig, d.deliver)
+	return d
+}
+
+// Enqueue queues an event for the active endpoints subscribed to it: the
+// user's own endpoints and, when organizationID is set, the organization's.
+// Pass a transaction as db to queue the event only if the transaction commits.
+func (d *Dispatcher) Enqueue(db *gorm.DB, userID uint, organizationID *uint, event string, data any) error {
+	query := db.Where("active AND ? = ANY(events)", event)
+	if organizationID != nil {
+		query = query.Where("((organization_id IS NULL AND user_id = ?) OR organization_id = ?)", userID, *organizationID)
+	} else {
+		query = query.Where("organization_id IS NULL AND user_id = ?", userID)
+	}
+	var endpoints []models.WebhookEndpoint
+	if err := query.Find(&endpoints).Error; err != nil {
+		return fmt.Errorf("find webhook endpoints: %w", err)
+	}
+	if len(endpoints) == 0 {
+		return nil
+	}
+
+	eventID, body, err := newPayload(event, data)
+	if err != nil {
+		return err
+	}
+	for _, endpoint := range endpoints {
+		if _, err := d.queue(db, endpoint.ID, eventID, event, body); err != nil {
+			return err
+		}
+	}
+	d.Wake()
+	return nil
+}
+
+// Ping queues a ping event for one endpoint, to test that it receives and
+// verifies deliveries
+func (d *Dispatcher) Ping(db *gorm.DB, endpoint *models.WebhookEndpoint) (*models.WebhookDelivery, error) {
+	eventID, body, err := newPayload(models.WebhookPing, map[string]any{"endpoint_id": endpoint.ID})
+	if err != nil {
+		return nil, err
+	}
+	delivery, err := d.queue(db, endpoint.ID, eventID, models.WebhookPing, body)
+	if err != nil {
+		return nil, err
+	}
+	d.Wake()
+	return delivery, nil
+}
+
+// Redeliver queues the payload of an earlier delivery again, with the same
+// event ID
+func (d *Dispatcher) Redeliver(db *gorm.DB, original *models.WebhookDelivery) (*models.WebhookDelivery, error) {
+	delivery, err := d.queue(db, original.EndpointID, original.EventID, original.Event, original.Payload)
+	if err != nil {
+		return nil, err
+	}
+	d.Wake()
+	return delivery, nil
+}
+
+func newPayload(event string, data any) (string, []byte, error) {
+	token, err := utils.GenerateToken(16)
+	if err != nil {
+		return "", nil, err
+	}
+	eventID := "evt_" + token
+	body, err := json.Marshal(Payload{ID: eventID, Event: event, CreatedAt: time.Now().UTC(), Data: data})
+	if err != nil {
+		return "", nil, fmt.Errorf("encode webhook payload: %w", err)
+	}
+	return eventID, body, nil
+}
+
+func (d *Dispatcher) queue(db *gorm.DB, endpointID uint, eventID, event string, body []byte) (*models.WebhookDelivery, error) {
+	delivery := models.WebhookDelivery{
+		EndpointID:    endpointID,
+		EventID:       eventID,
+		Event:         event,
+		Payload:       body,
+		Status:        models.WebhookStatusPending,
+		NextAttemptAt: time.Now().UTC(),
+	}
+	if err := db.Create(&delivery).Error; err != nil {
+		return nil, fmt.Errorf("queue webhook delivery: %w", err)
+	}
+	return &delivery, nil
+}
+
+// deliver makes one attempt at a claimed delivery and records the outcome
+func (d *Dispatcher) deliver(delivery *models.WebhookDelivery) {
+	updates := map[string]any{}
+	var endpoint models.WebhookEndpoint
+	if err := d.db.First(&endpoint, delivery.EndpointID).Error; err != nil || !endpoint.Active {
+		// Disabled or deleted endpoints are not retried
+		updates["status"] = models.WebhookStatusFailed
+		updates["last_error"] = "endpoint is disabled"
+		d.record(delivery.ID, updates)
+		return
+	}
+
+	status, body, elapsed, sendErr := d.send(&endpoint, delivery)
+	updates["duration_ms"] = elapsed.Milliseconds()
+	updates["response_status"] = nil
+	updates["response_body"] = nil
+	if status != 0 {
+		updates["response_status"] = status
+		updates["response_body"] = body
+		if sendErr == nil && (status < 200 || status > 299) {
+			sendErr = fmt.Errorf("endpoint responded with HTTP %d", status)
+		}
+	}
+
+	now := time.Now().UTC()
+	switch {
+	case sendErr == nil:
+		updates["status"] = models.WebhookStatusDelivered
+		updates["delivered_at"] = now
+		updates["last_error"] = nil
+	case delivery.Attempts >= maxAttempts:
+		fmt.Printf("ERROR: Giving up on webhook delivery %d to endpoint %d after %d attempts: %v\n", delivery.ID, endpoint.ID, delivery.Attempts, sendErr)
+		updates["status"] = models.WebhookStatusFailed
+		updates["last_error"] = sendErr.Error()
+	default:
+		updates["next_attempt_at"] = now.Add(retryBackoff(delivery.Attempts))
+		updates["last_error"] = sendErr.Error()
+	}
+	d.record(delivery.ID, updates)
+}
+
+func (d *Dispatcher) record(id uint, updates map[string]any) {
+	if err := d.db.Model(&models.WebhookDelivery{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+		fmt.Printf("ERROR: Failed to record webhook delivery %d: %v\n", id, err)
+	}
+}
+
+// send posts the signed payload and returns the response status and the
+// start of its body. status is 0 when no response was received.
+func (d *Dispatcher) send(endpoint *models.WebhookEndpoint, delivery *models.WebhookDelivery) (int, string, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, "", 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "FrontInsight-Webhooks/1.0")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, fmt.Sprint(delivery.ID))
+	req.Header.Set(HeaderSignature, Sign(endpoint.Secret, time.Now().Unix(), delivery.Payload))
+
+	start := time.Now()
+	res, err := d.client.Do(req)
+	if err != nil {
+		return 0, "", time.Since(start), err
+	}
+	defer res.Body.Close()
+	raw, _ := io.ReadAll(io.LimitReader(res.Body, maxResponseBody))
+	// Postgres text cannot hold NUL or invalid UTF-8
+	text := strings.ReplaceAll(strings.ToValidUTF8(string(raw), string(utf8.RuneError)), "\x00", "")
+	return res.StatusCode, text, time.Since(start), nil
+}
+
+// retryBackoff doubles the wait after each failed attempt: 1m, 2m, 4m, ... up to six hours
+func retryBackoff(attempts int) time.Duration {
+	return outbox.Backoff(attempts, time.Minute, 6*time.Hour)
+}
@@ -0,0 +1,125 @@
+// Package webhooks delivers signed event payloads to endpoints registered by
+// customers. Events are queued in the webhook_deliveries table and sent by a
+// background worker that retries failed attempts with backoff.
+//
+// Every request carries these headers:
+//
+//	X-FrontInsight-Event: search.completed
+//	X-FrontInsight-Delivery: 42
+//	X-FrontInsight-Signature: t=1700000000,v1=<hex HMAC-SHA256>
+//
+// The signature is the HMAC-SHA256, keyed with the endpoint secret, of the
+// timestamp, a dot and the raw request body. Receivers recompute it, compare
+// in constant time and reject timestamps more than a few minutes old.
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Request headers
+const (
+	HeaderEvent     = "X-FrontInsight-Event"
+	HeaderDelivery  = "X-FrontInsight-Delivery"
+	HeaderSignature = "X-FrontInsight-Signature"
+)
+
+// SecretPrefix starts every endpoint secret so it is recognisable in config files
+const SecretPrefix = "whsec_"
+
+// Payload is the JSON body sent to endpoints
+type Payload struct {
+	ID        string    `json:"id"` // same for redeliveries of the event
+	Event     string    `json:"event"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// Sign returns the signature header value for a body sent at timestamp
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return fmt.Sprintf("t=%d,v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil)))
+}
+
+var errForbiddenAddress = errors.New("webhook URLs must not point to private or local addresses")
+
+// ValidateURL checks that an endpoint URL can be delivered to. Plain http
+// and private addresses are only accepted when allowInsecure is set, for
+// local development.
+func ValidateURL(raw string, allowInsecure bool) error {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return errors.New("webhook URL must be an absolute URL")
+	}
+	switch {
+	case u.Scheme == "https":
+	case u.Scheme == "http" && allowInsecure:
+	default:
+		return errors.New("webhook URL must use https")
+	}
+	if u.User != nil {
+		return errors.New("webhook URL must not contain credentials")
+	}
+	if len(raw) > 2000 {
+		return errors.New("webhook URL is too long")
+	}
+	if allowInsecure {
+		return nil
+	}
+	host := u.Hostname()
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") || strings.HasSuffix(host, ".internal") {
+		return errForbiddenAddress
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !publicAddr(addr) {
+		return errForbiddenAddress
+	}
+	return nil
+}
+
+// publicAddr reports whether deliveries may connect to the address
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !addr.IsLoopback() && !addr.IsLinkLocalUnicast()
+}
+
+// NewClient returns the HTTP client deliveries are sent with. Unless
+// allowInsecure is set it refuses to connect to private and local addresses,
+// checked after DNS resolution so a public name cannot point inside our
+// network. Redirects are not followed.
+func NewClient(timeout time.Duration, allowInsecure bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if !allowInsecure {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil || !publicAddr(addrPort.Addr()) {
+				return errForbiddenAddress
+			}
+			return nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}